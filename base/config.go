@@ -3,10 +3,21 @@ package base
 import "flag"
 
 var (
-	devModule = flag.Bool("dev", false, "开发模式")
+	devModule         = flag.Bool("dev", false, "开发模式")
+	checkConfigModule = flag.Bool("check-config", false, "只校验配置,不启动服务")
 )
 
 //IsDevModule 是否是开发模式
 func IsDevModule() bool {
 	return *devModule
 }
+
+//IsCheckConfigModule 是否是只校验配置的模式,此模式下不会调用 Service.Init
+func IsCheckConfigModule() bool {
+	return *checkConfigModule
+}
+
+//ConfigCheckService Service 可选实现的接口,-check-config 模式下代替 Init 校验 Service 自己的配置,不能连接外部资源
+type ConfigCheckService interface {
+	CheckConfig(configPath string) Error
+}
@@ -0,0 +1,195 @@
+package base
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/coffeehc/logger"
+	"gopkg.in/yaml.v2"
+)
+
+//ConfigSource 配置来源,将配置直接解析到 config 上,已有的配置项会被覆盖
+type ConfigSource interface {
+	Apply(config interface{}) Error
+}
+
+//envKeySeparator 环境变量中层级的分隔符,如 SERVICE_CONFIG__HTTP_CONFIG__SERVER_ADDR
+const envKeySeparator = "__"
+
+var (
+	configOverrides = &configOverrideFlag{}
+	interpolateExp  = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::([^}]*))?\}`)
+)
+
+func init() {
+	flag.Var(configOverrides, "set", "覆盖配置项,格式为 path.to.key=value,可以指定多次")
+}
+
+type configOverrideFlag []string
+
+func (f *configOverrideFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *configOverrideFlag) Set(value string) error {
+	if !strings.Contains(value, "=") {
+		return fmt.Errorf("%s 不是 path.to.key=value 的格式", value)
+	}
+	*f = append(*f, value)
+	return nil
+}
+
+//LoadConfigFromSources 按照 sources 的顺序将配置解析到 config,后面的覆盖前面的
+func LoadConfigFromSources(config interface{}, sources ...ConfigSource) Error {
+	for _, source := range sources {
+		err := source.Apply(config)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//NewFileConfigSource 从 yaml 文件加载配置,支持 ${VAR:default} 形式的环境变量插值
+func NewFileConfigSource(configPath string) ConfigSource {
+	return &fileConfigSource{configPath: configPath}
+}
+
+type fileConfigSource struct {
+	configPath string
+}
+
+func (s *fileConfigSource) Apply(config interface{}) Error {
+	logger.Debug("load config file %s", s.configPath)
+	data, err := ioutil.ReadFile(s.configPath)
+	if err != nil {
+		return NewError(Error_System, errScopeLoadConfig, err.Error())
+	}
+	err = yaml.Unmarshal([]byte(InterpolateEnv(string(data))), config)
+	if err != nil {
+		return NewError(Error_System, errScopeLoadConfig, err.Error())
+	}
+	return nil
+}
+
+//InterpolateEnv 替换 content 中的 ${VAR:default},环境变量不存在时使用默认值
+func InterpolateEnv(content string) string {
+	return interpolateExp.ReplaceAllStringFunc(content, func(expr string) string {
+		matches := interpolateExp.FindStringSubmatch(expr)
+		if value, ok := os.LookupEnv(matches[1]); ok {
+			return value
+		}
+		if !strings.Contains(expr, ":") {
+			logger.Warn("环境变量%s不存在,使用空值", matches[1])
+		}
+		return matches[2]
+	})
+}
+
+//NewEnvConfigSource 使用环境变量覆盖配置,变量名为 yaml tag 的路径转换为大写并使用双下划线连接
+func NewEnvConfigSource() ConfigSource {
+	return &envConfigSource{}
+}
+
+type envConfigSource struct{}
+
+func (s *envConfigSource) Apply(config interface{}) Error {
+	for _, path := range collectYamlPaths(reflect.TypeOf(config), nil, 0) {
+		envKey := strings.ToUpper(strings.Join(path, envKeySeparator))
+		value, ok := os.LookupEnv(envKey)
+		if !ok {
+			continue
+		}
+		logger.Debug("使用环境变量%s覆盖配置", envKey)
+		err := SetConfigValue(config, path, value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//NewFlagConfigSource 使用命令行参数 -set path.to.key=value 覆盖配置
+func NewFlagConfigSource() ConfigSource {
+	return &flagConfigSource{overrides: configOverrides}
+}
+
+type flagConfigSource struct {
+	overrides *configOverrideFlag
+}
+
+func (s *flagConfigSource) Apply(config interface{}) Error {
+	for _, override := range *s.overrides {
+		kv := strings.SplitN(override, "=", 2)
+		err := SetConfigValue(config, strings.Split(kv[0], "."), kv[1])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//SetConfigValue 将 value 按照 yaml 解析到 config 中 path 对应的配置项,value 保留原始的文本,
+// 如 1.0 与 010 设置到 string 类型的配置项时仍为 "1.0" 与 "010"
+func SetConfigValue(config interface{}, path []string, value string) Error {
+	if len(path) == 0 {
+		return NewError(Error_System, errScopeLoadConfig, "没有指定配置项的路径")
+	}
+	buf := new(bytes.Buffer)
+	for i, key := range path {
+		buf.WriteString(strings.Repeat("  ", i))
+		buf.WriteString(strconv.Quote(key))
+		buf.WriteString(":\n")
+	}
+	indent := strings.Repeat("  ", len(path))
+	for _, line := range strings.Split(value, "\n") {
+		buf.WriteString(indent)
+		buf.WriteString(line)
+		buf.WriteString("\n")
+	}
+	err := yaml.Unmarshal(buf.Bytes(), config)
+	if err != nil {
+		return NewError(Error_System, errScopeLoadConfig, fmt.Sprintf("%s 的值 %s 解析失败:%s", strings.Join(path, "."), value, err))
+	}
+	return nil
+}
+
+//collectYamlPaths 根据 yaml tag 收集所有配置项的路径,最多展开 depth 为8的层级
+func collectYamlPaths(t reflect.Type, prefix []string, depth int) [][]string {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct || depth > 8 {
+		return nil
+	}
+	paths := make([][]string, 0)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag := field.Tag.Get("yaml")
+		name := strings.Split(tag, ",")[0]
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(tag, ",inline") {
+			paths = append(paths, collectYamlPaths(field.Type, prefix, depth+1)...)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		path := append(append(make([]string, 0, len(prefix)+1), prefix...), name)
+		paths = append(paths, path)
+		paths = append(paths, collectYamlPaths(field.Type, path, depth+1)...)
+	}
+	return paths
+}
@@ -0,0 +1,103 @@
+package base
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type ConfigSourceSuite struct {
+}
+
+var _ = Suite(&ConfigSourceSuite{})
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type testHTTPConfig struct {
+	ServerAddr  string        `yaml:"server_addr"`
+	ReadTimeout time.Duration `yaml:"read_timeout"`
+}
+
+type testServiceConfig struct {
+	ServiceInfo      *SimpleServiceInfo `yaml:"service_info"`
+	EnableAccessInfo bool               `yaml:"enableAccessInfo"`
+	HTTPConfig       *testHTTPConfig    `yaml:"http_config"`
+}
+
+type testConfig struct {
+	ServiceConfig *testServiceConfig `yaml:"service_config"`
+}
+
+const testConfigContent = `
+service_config:
+  service_info:
+    service_name: ${TEST_SERVICE_NAME:demo}
+    tag: ${TEST_SERVICE_TAG:dev}
+  http_config:
+    server_addr: 127.0.0.1:8888
+    read_timeout: 3s
+`
+
+func (suite *ConfigSourceSuite) TestLayeredConfig(c *C) {
+	file, err := ioutil.TempFile("", "config")
+	c.Assert(err, IsNil)
+	defer os.Remove(file.Name())
+	file.WriteString(testConfigContent)
+	file.Close()
+	os.Setenv("TEST_SERVICE_TAG", "prod")
+	os.Setenv("SERVICE_CONFIG__HTTP_CONFIG__SERVER_ADDR", "0.0.0.0:9999")
+	os.Setenv("SERVICE_CONFIG__ENABLEACCESSINFO", "true")
+	defer os.Unsetenv("TEST_SERVICE_TAG")
+	defer os.Unsetenv("SERVICE_CONFIG__HTTP_CONFIG__SERVER_ADDR")
+	defer os.Unsetenv("SERVICE_CONFIG__ENABLEACCESSINFO")
+	overrides := &configOverrideFlag{}
+	c.Assert(overrides.Set("service_config.http_config.read_timeout=5s"), IsNil)
+	c.Assert(overrides.Set("service_config.http_config.server_addr"), NotNil)
+
+	config := new(testConfig)
+	baseErr := LoadConfigFromSources(config, NewFileConfigSource(file.Name()), NewEnvConfigSource(), &flagConfigSource{overrides: overrides})
+	c.Assert(baseErr, IsNil)
+	c.Assert(config.ServiceConfig.ServiceInfo.ServiceName, Equals, "demo")
+	c.Assert(config.ServiceConfig.ServiceInfo.Tag, Equals, "prod")
+	c.Assert(config.ServiceConfig.EnableAccessInfo, Equals, true)
+	c.Assert(config.ServiceConfig.HTTPConfig.ServerAddr, Equals, "0.0.0.0:9999")
+	c.Assert(config.ServiceConfig.HTTPConfig.ReadTimeout, Equals, 5*time.Second)
+}
+
+const testScalarConfigContent = `
+service_config:
+  service_info:
+    service_name: demo
+    version: 1.0
+    tag: 010
+`
+
+func (suite *ConfigSourceSuite) TestScalarText(c *C) {
+	file, err := ioutil.TempFile("", "config")
+	c.Assert(err, IsNil)
+	defer os.Remove(file.Name())
+	file.WriteString(testScalarConfigContent)
+	file.Close()
+
+	config := new(testConfig)
+	c.Assert(LoadConfigFromSources(config, NewFileConfigSource(file.Name())), IsNil)
+	c.Assert(config.ServiceConfig.ServiceInfo.Version, Equals, "1.0")
+	c.Assert(config.ServiceConfig.ServiceInfo.Tag, Equals, "010")
+
+	os.Setenv("SERVICE_CONFIG__SERVICE_INFO__VERSION", "2.10")
+	defer os.Unsetenv("SERVICE_CONFIG__SERVICE_INFO__VERSION")
+	overrides := &configOverrideFlag{}
+	c.Assert(overrides.Set("service_config.http_config.read_timeout=5s"), IsNil)
+	config = new(testConfig)
+	c.Assert(LoadConfigFromSources(config, NewFileConfigSource(file.Name()), NewEnvConfigSource(), &flagConfigSource{overrides: overrides}), IsNil)
+	c.Assert(config.ServiceConfig.ServiceInfo.Version, Equals, "2.10")
+	c.Assert(config.ServiceConfig.ServiceInfo.Tag, Equals, "010")
+	c.Assert(config.ServiceConfig.ServiceInfo.ServiceName, Equals, "demo")
+	c.Assert(config.ServiceConfig.HTTPConfig.ReadTimeout, Equals, 5*time.Second)
+	c.Assert(SetConfigValue(config, []string{"service_config", "http_config", "read_timeout"}, "[1"), NotNil)
+}
@@ -0,0 +1,59 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+//HeaderRequestTimeout Rest 调用传递剩余时间的 Header,值为毫秒数
+const HeaderRequestTimeout = "X-Request-Timeout"
+
+const (
+	errScopeDeadline      = "deadline"
+	defaultDeadlineMargin = time.Millisecond * 10
+)
+
+//DeadlineConfig 把上游的 deadline 传递给下游调用的配置
+type DeadlineConfig struct {
+	//Margin 传递给下游的剩余时间需要减去的安全余量,留给本服务处理下游的结果,默认10ms
+	Margin time.Duration `yaml:"margin" validate:"min=0s"`
+	//MinTimeout 减去余量之后剩余时间小于该值时不再调用下游,直接返回 Error_System_DeadlineTooShort,默认0
+	MinTimeout time.Duration `yaml:"min_timeout" validate:"min=0s"`
+}
+
+//Remaining 计算传递给下游的剩余时间,cxt 没有 deadline 时 ok 为 false,
+// 剩余时间用完或者小于 MinTimeout 时返回 Error_System_DeadlineTooShort
+func (config *DeadlineConfig) Remaining(cxt context.Context) (timeout time.Duration, ok bool, err Error) {
+	deadline, ok := cxt.Deadline()
+	if !ok {
+		return 0, false, nil
+	}
+	margin := config.Margin
+	if margin <= 0 {
+		margin = defaultDeadlineMargin
+	}
+	timeout = deadline.Sub(time.Now()) - margin
+	if timeout <= 0 || timeout < config.MinTimeout {
+		return 0, true, NewError(Error_System_DeadlineTooShort, errScopeDeadline, fmt.Sprintf("剩余时间 %s 不足以完成调用", timeout+margin))
+	}
+	return timeout, true, nil
+}
+
+//FormatRequestTimeout 格式化 HeaderRequestTimeout 的值
+func FormatRequestTimeout(timeout time.Duration) string {
+	return strconv.FormatInt(int64(timeout/time.Millisecond), 10)
+}
+
+//ParseRequestTimeout 解析 HeaderRequestTimeout 的值,没有设置或者格式错误时 ok 为 false
+func ParseRequestTimeout(value string) (timeout time.Duration, ok bool) {
+	if value == "" {
+		return 0, false
+	}
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(ms) * time.Millisecond, true
+}
@@ -0,0 +1,36 @@
+package base
+
+import (
+	"context"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type DeadlineSuite struct {
+}
+
+var _ = Suite(&DeadlineSuite{})
+
+func (suite *DeadlineSuite) TestDeadlineRemaining(c *C) {
+	config := &DeadlineConfig{Margin: time.Millisecond * 100, MinTimeout: time.Millisecond * 50}
+	_, ok, err := config.Remaining(context.Background())
+	c.Assert(ok, Equals, false)
+	c.Assert(err, IsNil)
+
+	cxt, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	timeout, ok, err := config.Remaining(cxt)
+	c.Assert(ok, Equals, true)
+	c.Assert(err, IsNil)
+	c.Assert(timeout <= time.Millisecond*900 && timeout > time.Millisecond*800, Equals, true)
+
+	shortCxt, shortCancel := context.WithTimeout(context.Background(), time.Millisecond*120)
+	defer shortCancel()
+	_, _, err = config.Remaining(shortCxt)
+	c.Assert(IsDeadlineTooShortError(err), Equals, true)
+
+	timeout, ok = ParseRequestTimeout(FormatRequestTimeout(time.Millisecond * 1500))
+	c.Assert(ok, Equals, true)
+	c.Assert(timeout, Equals, time.Millisecond*1500)
+}
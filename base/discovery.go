@@ -7,3 +7,21 @@ type ServiceDiscoveryRegister interface {
 	//注册服务
 	RegService(cxt context.Context, info ServiceInfo, serviceAddr string) (deregister func(), err Error)
 }
+
+//HealthCheck 注册中心使用的健康检查定义
+type HealthCheck struct {
+	//HTTPPath 基于 http 的检查路径,如 consul 的 HTTP Check
+	HTTPPath string
+	//Checker 进程内的检查,如 etcd 根据其结果决定是否保留注册信息
+	Checker func() bool
+}
+
+//HealthCheckRegister ServiceDiscoveryRegister 可选实现的接口,用于指定注册时使用的健康检查
+type HealthCheckRegister interface {
+	SetHealthCheck(healthCheck *HealthCheck)
+}
+
+//MetadataRegister ServiceDiscoveryRegister 可选实现的接口,用于注册节点的元数据,如负载均衡使用的 weight
+type MetadataRegister interface {
+	SetMetadata(metadata map[string]string)
+}
@@ -0,0 +1,87 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+const errScopeDynamicConfig = "dynamic config"
+
+var errorType = reflect.TypeOf((*Error)(nil)).Elem()
+
+//DynamicConfigSource 可以监听变化的配置来源,如 consul KV,etcd
+type DynamicConfigSource interface {
+	ConfigSource
+	//Watch 在后台监听配置的变化,变化时调用 onChange,cxt 结束后停止监听
+	Watch(cxt context.Context, onChange func()) Error
+}
+
+//ConfigValidator 配置对象可选实现的接口,Validate 在 tag 校验之后调用,用于 tag 无法表达的校验
+type ConfigValidator interface {
+	Validate() Error
+}
+
+//ConfigChangeListener 接收配置变更的通知
+type ConfigChangeListener interface {
+	//OnConfigChange old 在第一次加载时为 nil,返回错误表示拒绝新的配置,将继续使用 old
+	OnConfigChange(old, new interface{}) Error
+}
+
+//DynamicConfigService Service 可选实现的接口,用于在不重启服务的情况下修改开关,限流等配置
+type DynamicConfigService interface {
+	ConfigChangeListener
+	//GetDynamicConfigSources 动态配置的来源,后面的覆盖前面的
+	GetDynamicConfigSources() ([]DynamicConfigSource, Error)
+	//NewDynamicConfig 创建一个新的配置对象,每次配置变化都会解析到一个新的对象中
+	NewDynamicConfig() interface{}
+}
+
+//ConfigChangeFunc 把 func(old, new *Config) Error 形式的函数适配为 ConfigChangeListener,避免在 OnConfigChange 中做类型断言
+// 第一次加载时 old 为 nil,新配置的类型与函数参数不一致时返回错误
+func ConfigChangeFunc(fn interface{}) (ConfigChangeListener, Error) {
+	fnValue := reflect.ValueOf(fn)
+	if !fnValue.IsValid() {
+		return nil, NewError(Error_System, errScopeDynamicConfig, "fn 为 nil")
+	}
+	fnType := fnValue.Type()
+	if fnType.Kind() != reflect.Func || fnType.NumIn() != 2 || fnType.NumOut() != 1 || fnType.In(0) != fnType.In(1) || fnType.Out(0) != errorType {
+		return nil, NewError(Error_System, errScopeDynamicConfig, fmt.Sprintf("%s 不是 func(old, new T) base.Error 形式的函数", fnType))
+	}
+	if fnValue.IsNil() {
+		return nil, NewError(Error_System, errScopeDynamicConfig, "fn 为 nil")
+	}
+	return &configChangeFunc{fn: fnValue, configType: fnType.In(0)}, nil
+}
+
+type configChangeFunc struct {
+	fn         reflect.Value
+	configType reflect.Type
+}
+
+func (f *configChangeFunc) OnConfigChange(old, new interface{}) Error {
+	oldValue, err := f.toValue(old)
+	if err != nil {
+		return err
+	}
+	newValue, err := f.toValue(new)
+	if err != nil {
+		return err
+	}
+	out := f.fn.Call([]reflect.Value{oldValue, newValue})[0]
+	if out.IsNil() {
+		return nil
+	}
+	return out.Interface().(Error)
+}
+
+func (f *configChangeFunc) toValue(config interface{}) (reflect.Value, Error) {
+	if config == nil {
+		return reflect.Zero(f.configType), nil
+	}
+	value := reflect.ValueOf(config)
+	if !value.Type().AssignableTo(f.configType) {
+		return value, NewError(Error_System, errScopeDynamicConfig, fmt.Sprintf("配置类型 %s 与监听函数的参数类型 %s 不一致", value.Type(), f.configType))
+	}
+	return value, nil
+}
@@ -1,5 +1,11 @@
 package base
 
+import (
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+)
+
 const (
 	_baseError = 0x10000000
 
@@ -17,10 +23,41 @@ const (
 	Error_System_Redis = Error_System | 0x3
 	//RPC错误,包含编解码
 	Error_System_RPC = Error_System | 0x4
+	//Error_System_CircuitOpen 熔断器打开,调用被直接拒绝
+	Error_System_CircuitOpen = Error_System | 0x5
+	//Error_System_DeadlineTooShort 剩余时间不足以完成调用,请求被直接拒绝
+	Error_System_DeadlineTooShort = Error_System | 0x6
+	//Error_System_RateLimited 超过服务端的限流或者并发限制,请求没有被处理,可以重试其他节点
+	Error_System_RateLimited = Error_System | 0x7
 )
 
+var (
+	systemErrors  = registerErrorNamespace(Error_System>>errorNamespaceShift&errorNamespaceMask, "system", "系统级别的错误", http.StatusInternalServerError, codes.Internal)
+	messageErrors = registerErrorNamespace(Error_Message>>errorNamespaceShift&errorNamespaceMask, "message", "业务相关的错误", http.StatusBadRequest, codes.FailedPrecondition)
+)
+
+func init() {
+	systemErrors.Code(Error_System_Internal&errorDetailMask, "internal", "内部错误", 0, 0)
+	systemErrors.Code(Error_System_DB&errorDetailMask, "db", "数据库错误", 0, 0)
+	systemErrors.Code(Error_System_Redis&errorDetailMask, "redis", "Redis 错误", 0, 0)
+	systemErrors.Code(Error_System_RPC&errorDetailMask, "rpc", "调用其他服务失败,包含编解码错误", http.StatusBadGateway, codes.Unknown)
+	systemErrors.Code(Error_System_CircuitOpen&errorDetailMask, "circuit_open", "熔断器打开,调用被直接拒绝", http.StatusServiceUnavailable, codes.Unavailable)
+	systemErrors.Code(Error_System_DeadlineTooShort&errorDetailMask, "deadline_too_short", "剩余时间不足以完成调用", http.StatusGatewayTimeout, codes.DeadlineExceeded)
+	systemErrors.Code(Error_System_RateLimited&errorDetailMask, "rate_limited", "超过服务端的限流或者并发限制", http.StatusTooManyRequests, codes.ResourceExhausted)
+	messageErrors.Code(Error_Message_NotFount&errorDetailMask, "not_found", "资源不存在", http.StatusNotFound, codes.NotFound)
+}
+
+//equalError srcCode 是否是 targetCode 或者属于 targetCode 表示的分类,
+// targetCode 为 _baseError 时匹配所有基础错误码,序号为0时匹配命名空间中的所有错误码,否则只匹配相同的错误码
 func equalError(srcCode, targetCode int32) bool {
-	return srcCode&targetCode == targetCode
+	switch {
+	case targetCode == _baseError:
+		return srcCode&^(errorNamespaceMask<<errorNamespaceShift|errorDetailMask) == _baseError
+	case targetCode&errorDetailMask == 0:
+		return srcCode&^errorDetailMask == targetCode
+	default:
+		return srcCode == targetCode
+	}
 }
 
 func IsBaseErrorCode(code int32) bool {
@@ -82,3 +119,27 @@ func IsNotFountError(err error) bool {
 	}
 	return false
 }
+
+//IsCircuitOpenError 是否是熔断器打开导致的快速失败
+func IsCircuitOpenError(err error) bool {
+	if e, ok := err.(Error); ok {
+		return e.GetCode() == Error_System_CircuitOpen
+	}
+	return false
+}
+
+//IsDeadlineTooShortError 是否是剩余时间不足导致的快速失败
+func IsDeadlineTooShortError(err error) bool {
+	if e, ok := err.(Error); ok {
+		return e.GetCode() == Error_System_DeadlineTooShort
+	}
+	return false
+}
+
+//IsRateLimitedError 是否是被服务端限流拒绝
+func IsRateLimitedError(err error) bool {
+	if e, ok := err.(Error); ok {
+		return e.GetCode() == Error_System_RateLimited
+	}
+	return false
+}
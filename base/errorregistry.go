@@ -0,0 +1,194 @@
+package base
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	errorNamespaceShift = 16
+	errorDetailMask     = 0xFFFF
+	errorNamespaceMask  = 0xFFF
+	//minServiceErrorNamespace 小于该值的命名空间保留给框架使用
+	minServiceErrorNamespace = 0x100
+)
+
+//ErrorNamespace 错误码的命名空间,命名空间 ID 为 0x100~0xFFF,其中的错误码为 _baseError|ID<<16|序号,
+// HTTPStatus 与 GRPCCode 是命名空间中没有单独声明映射的错误码的默认映射,0表示没有映射
+type ErrorNamespace struct {
+	ID          int32      `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	HTTPStatus  int        `json:"http_status,omitempty"`
+	GRPCCode    codes.Code `json:"grpc_code,omitempty"`
+}
+
+//BaseCode 命名空间的基础错误码,也是命名空间中最小的错误码
+func (ns *ErrorNamespace) BaseCode() int32 {
+	return _baseError | ns.ID<<errorNamespaceShift
+}
+
+//Code 在命名空间中声明错误码并返回,seq 为1~0xFFFF,httpStatus 与 grpcCode 为0时使用命名空间的映射,
+// 重复的错误码或者名称会在启动时由 CheckErrorCodes 报告
+func (ns *ErrorNamespace) Code(seq int32, name string, description string, httpStatus int, grpcCode codes.Code) int32 {
+	code := ns.BaseCode() | seq&errorDetailMask
+	if seq <= 0 || seq > errorDetailMask {
+		errorRegistry.conflict("错误码 %s.%s 的序号 0x%x 超出范围", ns.Name, name, seq)
+		return code
+	}
+	errorRegistry.addCode(&ErrorCodeInfo{
+		Code:        code,
+		Name:        name,
+		Namespace:   ns.Name,
+		Description: description,
+		HTTPStatus:  httpStatus,
+		GRPCCode:    grpcCode,
+	})
+	return code
+}
+
+//ErrorCodeInfo 已声明的错误码
+type ErrorCodeInfo struct {
+	Code        int32      `json:"code"`
+	Name        string     `json:"name"`
+	Namespace   string     `json:"namespace"`
+	Description string     `json:"description"`
+	HTTPStatus  int        `json:"http_status,omitempty"`
+	GRPCCode    codes.Code `json:"grpc_code,omitempty"`
+}
+
+//ErrorCatalogNamespace /errors 返回的命名空间及其中的错误码
+type ErrorCatalogNamespace struct {
+	ErrorNamespace
+	Codes []*ErrorCodeInfo `json:"codes"`
+}
+
+type registry struct {
+	mutex      *sync.RWMutex
+	namespaces map[int32]*ErrorNamespace
+	codes      map[int32]*ErrorCodeInfo
+	names      map[string]int32
+	conflicts  []string
+}
+
+var errorRegistry = &registry{
+	mutex:      new(sync.RWMutex),
+	namespaces: make(map[int32]*ErrorNamespace),
+	codes:      make(map[int32]*ErrorCodeInfo),
+	names:      make(map[string]int32),
+}
+
+func (r *registry) conflict(format string, args ...interface{}) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.conflicts = append(r.conflicts, fmt.Sprintf(format, args...))
+}
+
+func (r *registry) addNamespace(ns *ErrorNamespace) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if exist, ok := r.namespaces[ns.ID]; ok {
+		r.conflicts = append(r.conflicts, fmt.Sprintf("错误码命名空间 0x%x 被 %s 与 %s 重复声明", ns.ID, exist.Name, ns.Name))
+		return
+	}
+	for _, exist := range r.namespaces {
+		if exist.Name == ns.Name {
+			r.conflicts = append(r.conflicts, fmt.Sprintf("错误码命名空间名称 %s 重复,ID 为 0x%x 与 0x%x", ns.Name, exist.ID, ns.ID))
+			return
+		}
+	}
+	r.namespaces[ns.ID] = ns
+}
+
+func (r *registry) addCode(info *ErrorCodeInfo) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	fullName := info.Namespace + "." + info.Name
+	if exist, ok := r.codes[info.Code]; ok {
+		r.conflicts = append(r.conflicts, fmt.Sprintf("错误码 0x%x 被 %s.%s 与 %s 重复声明", info.Code, exist.Namespace, exist.Name, fullName))
+		return
+	}
+	if exist, ok := r.names[fullName]; ok {
+		r.conflicts = append(r.conflicts, fmt.Sprintf("错误码名称 %s 重复,错误码为 0x%x 与 0x%x", fullName, exist, info.Code))
+		return
+	}
+	r.codes[info.Code] = info
+	r.names[fullName] = info.Code
+}
+
+func registerErrorNamespace(id int32, name string, description string, httpStatus int, grpcCode codes.Code) *ErrorNamespace {
+	ns := &ErrorNamespace{ID: id, Name: name, Description: description, HTTPStatus: httpStatus, GRPCCode: grpcCode}
+	errorRegistry.addNamespace(ns)
+	return ns
+}
+
+//RegisterErrorNamespace 声明服务自己的错误码命名空间,id 为 0x100~0xFFF,一般在包级别的变量中声明,
+// 冲突的 id 或者名称会在启动时由 CheckErrorCodes 报告
+func RegisterErrorNamespace(id int32, name string, description string, httpStatus int, grpcCode codes.Code) *ErrorNamespace {
+	if id < minServiceErrorNamespace || id > errorNamespaceMask {
+		errorRegistry.conflict("错误码命名空间 %s 的 ID 0x%x 超出范围 0x%x~0x%x", name, id, minServiceErrorNamespace, errorNamespaceMask)
+		return &ErrorNamespace{ID: id & errorNamespaceMask, Name: name, Description: description, HTTPStatus: httpStatus, GRPCCode: grpcCode}
+	}
+	return registerErrorNamespace(id, name, description, httpStatus, grpcCode)
+}
+
+//CheckErrorCodes 检查声明的命名空间与错误码是否有冲突,Launch 时调用,有冲突时服务不能启动
+func CheckErrorCodes() Error {
+	errorRegistry.mutex.RLock()
+	defer errorRegistry.mutex.RUnlock()
+	if len(errorRegistry.conflicts) == 0 {
+		return nil
+	}
+	return NewError(Error_System, "error registry", strings.Join(errorRegistry.conflicts, "; "))
+}
+
+//LookupErrorCode 获取已声明的错误码
+func LookupErrorCode(code int32) (*ErrorCodeInfo, bool) {
+	errorRegistry.mutex.RLock()
+	defer errorRegistry.mutex.RUnlock()
+	info, ok := errorRegistry.codes[code]
+	return info, ok
+}
+
+//LookupErrorNamespace 获取错误码所在的命名空间
+func LookupErrorNamespace(code int32) (*ErrorNamespace, bool) {
+	if !IsBaseErrorCode(code) {
+		return nil, false
+	}
+	errorRegistry.mutex.RLock()
+	defer errorRegistry.mutex.RUnlock()
+	ns, ok := errorRegistry.namespaces[code>>errorNamespaceShift&errorNamespaceMask]
+	return ns, ok
+}
+
+//ErrorCatalog 按 ID 与错误码排序的所有命名空间与错误码
+func ErrorCatalog() []*ErrorCatalogNamespace {
+	errorRegistry.mutex.RLock()
+	defer errorRegistry.mutex.RUnlock()
+	catalog := make([]*ErrorCatalogNamespace, 0, len(errorRegistry.namespaces))
+	index := make(map[string]*ErrorCatalogNamespace, len(errorRegistry.namespaces))
+	for _, ns := range errorRegistry.namespaces {
+		entry := &ErrorCatalogNamespace{ErrorNamespace: *ns, Codes: make([]*ErrorCodeInfo, 0)}
+		catalog = append(catalog, entry)
+		index[ns.Name] = entry
+	}
+	for _, info := range errorRegistry.codes {
+		if entry, ok := index[info.Namespace]; ok {
+			entry.Codes = append(entry.Codes, info)
+		}
+	}
+	sort.Slice(catalog, func(i, j int) bool {
+		return catalog[i].ID < catalog[j].ID
+	})
+	for _, entry := range catalog {
+		entryCodes := entry.Codes
+		sort.Slice(entryCodes, func(i, j int) bool {
+			return entryCodes[i].Code < entryCodes[j].Code
+		})
+	}
+	return catalog
+}
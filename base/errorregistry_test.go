@@ -0,0 +1,57 @@
+package base
+
+import (
+	"net/http"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	. "gopkg.in/check.v1"
+)
+
+type ErrorRegistrySuite struct {
+}
+
+var _ = Suite(&ErrorRegistrySuite{})
+
+func (suite *ErrorRegistrySuite) TestEqualError(c *C) {
+	c.Assert(IsDBError(NewError(Error_System_Redis, "", "")), Equals, false)
+	c.Assert(IsDBError(NewError(Error_System_DB, "", "")), Equals, true)
+	c.Assert(IsInternalError(NewError(Error_System_Redis, "", "")), Equals, false)
+	c.Assert(IsSystemError(NewError(Error_System_Redis, "", "")), Equals, true)
+	c.Assert(IsMessageError(NewError(Error_System_Redis, "", "")), Equals, false)
+	c.Assert(IsBaseErrorCode(Error_Message_NotFount), Equals, true)
+	c.Assert(IsBaseErrorCode(0x30000000), Equals, false)
+}
+
+func (suite *ErrorRegistrySuite) TestErrorRegistry(c *C) {
+	origin := errorRegistry
+	errorRegistry = &registry{
+		mutex:      new(sync.RWMutex),
+		namespaces: make(map[int32]*ErrorNamespace),
+		codes:      make(map[int32]*ErrorCodeInfo),
+		names:      make(map[string]int32),
+	}
+	defer func() {
+		errorRegistry = origin
+	}()
+	users := RegisterErrorNamespace(0x100, "user", "用户服务", http.StatusBadRequest, codes.InvalidArgument)
+	notFound := users.Code(0x1, "not_found", "用户不存在", http.StatusNotFound, codes.NotFound)
+	locked := users.Code(0x2, "locked", "用户被锁定", 0, 0)
+	c.Assert(notFound, Equals, int32(0x11000001))
+	c.Assert(CheckErrorCodes(), IsNil)
+	info, ok := LookupErrorCode(notFound)
+	c.Assert(ok, Equals, true)
+	c.Assert(info.HTTPStatus, Equals, http.StatusNotFound)
+	ns, ok := LookupErrorNamespace(locked)
+	c.Assert(ok, Equals, true)
+	c.Assert(ns.Name, Equals, "user")
+	catalog := ErrorCatalog()
+	c.Assert(len(catalog), Equals, 1)
+	c.Assert(len(catalog[0].Codes), Equals, 2)
+
+	users.Code(0x1, "missing", "重复的错误码", 0, 0)
+	RegisterErrorNamespace(0x100, "account", "重复的命名空间", 0, 0)
+	RegisterErrorNamespace(0x1, "system2", "保留的命名空间", 0, 0)
+	c.Assert(CheckErrorCodes(), NotNil)
+	c.Assert(len(errorRegistry.conflicts), Equals, 3)
+}
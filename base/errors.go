@@ -1,6 +1,10 @@
 package base
 
 import (
+	"errors"
+	"runtime/debug"
+	"strings"
+
 	"github.com/pquerna/ffjson/ffjson"
 )
 
@@ -11,11 +15,16 @@ type Error interface {
 	GetScopes() string
 }
 
-//BaseError Error 接口的实现,可 json 序列化
+//BaseError Error 接口的实现,可 json 序列化,Cause 为被封装的错误,Stack 只在开发模式下记录
 type baseError struct {
-	Scope   string `json:"scope"`
-	Code    int32  `json:"code"`
-	Message string `json:"message"`
+	Scope     string            `json:"scope"`
+	Code      int32             `json:"code"`
+	Message   string            `json:"message"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Localized map[string]string `json:"localized,omitempty"`
+	Stack     string            `json:"stack,omitempty"`
+	Cause     *baseError        `json:"cause,omitempty"`
+	cause     error
 }
 
 func (err *baseError) Error() string {
@@ -29,6 +38,64 @@ func (err *baseError) GetScopes() string {
 	return err.Scope
 }
 
+//Unwrap 返回被封装的错误,支持 errors.Is 与 errors.As
+func (err *baseError) Unwrap() error {
+	if err.cause != nil {
+		return err.cause
+	}
+	if err.Cause != nil {
+		return err.Cause
+	}
+	return nil
+}
+
+//Is 错误码相同的 Error 视为同一个错误
+func (err *baseError) Is(target error) bool {
+	if e, ok := target.(Error); ok {
+		return e.GetCode() == err.Code
+	}
+	return false
+}
+
+func (err *baseError) clone() *baseError {
+	e := *err
+	e.Metadata = make(map[string]string, len(err.Metadata)+1)
+	for k, v := range err.Metadata {
+		e.Metadata[k] = v
+	}
+	e.Localized = make(map[string]string, len(err.Localized)+1)
+	for k, v := range err.Localized {
+		e.Localized[k] = v
+	}
+	return &e
+}
+
+//toBaseError 将 err 转换为可以序列化的 baseError,保留 err 的错误链
+func toBaseError(err error) *baseError {
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(*baseError); ok {
+		return e
+	}
+	e := &baseError{Message: err.Error(), cause: err}
+	if _err, ok := err.(Error); ok {
+		e.Scope = _err.GetScopes()
+		e.Code = _err.GetCode()
+	}
+	if next := errors.Unwrap(err); next != nil {
+		e.Cause = toBaseError(next)
+	}
+	return e
+}
+
+func captureStack() string {
+	if !IsDevModule() {
+		return ""
+	}
+	return string(debug.Stack())
+}
+
 //ParseErrorFromJSON 从 Jons数据解析出 Error 对象
 func ParseErrorFromJSON(data []byte) Error {
 	err := &baseError{}
@@ -40,7 +107,7 @@ func ParseErrorFromJSON(data []byte) Error {
 }
 
 func ErrorToJson(err Error) string {
-	data, _ := ffjson.Marshal(err)
+	data, _ := ffjson.Marshal(toBaseError(err))
 	return string(data)
 }
 
@@ -50,17 +117,79 @@ func NewError(debugCode int32, scope string, errMsg string) Error {
 		Scope:   scope,
 		Code:    debugCode,
 		Message: errMsg,
+		Stack:   captureStack(),
 	}
 }
 
-//NewErrorWrapper 创建一个对普通的 error的封装
+//NewErrorWrapper 创建一个对普通的 error的封装,err 已经是 Error 时直接返回,否则作为 Cause 保留
 func NewErrorWrapper(code int32, scope string, err error) Error {
 	if _err, ok := err.(Error); ok {
 		//scope = fmt.Sprintf("%s-%s", scope, _err.GetScopes())
 		//code = code | _err.GetCode()
 		return _err
 	}
-	return &baseError{Scope: scope, Code: code, Message: err.Error()}
+	return &baseError{Scope: scope, Code: code, Message: err.Error(), Stack: captureStack(), cause: err, Cause: toBaseError(errors.Unwrap(err))}
+}
+
+//WrapError 创建一个新的 Error,cause 作为原因保留在错误链中,cause 可以是 Error
+func WrapError(code int32, scope string, errMsg string, cause error) Error {
+	e := &baseError{Scope: scope, Code: code, Message: errMsg, Stack: captureStack()}
+	if cause != nil {
+		e.cause = cause
+		e.Cause = toBaseError(cause)
+	}
+	return e
+}
+
+//WithErrorMetadata 返回添加了元数据的 Error,不修改 err
+func WithErrorMetadata(err Error, key string, value string) Error {
+	e := toBaseError(err).clone()
+	e.Metadata[key] = value
+	return e
+}
+
+//WithLocalizedMessage 返回添加了 locale 语言消息的 Error,不修改 err,locale 如 zh-CN,en
+func WithLocalizedMessage(err Error, locale string, message string) Error {
+	e := toBaseError(err).clone()
+	e.Localized[locale] = message
+	return e
+}
+
+//GetErrorMetadata 返回错误链中第一个 Error 的元数据
+func GetErrorMetadata(err error) map[string]string {
+	var e *baseError
+	if errors.As(err, &e) {
+		return e.Metadata
+	}
+	return nil
+}
+
+//GetErrorStack 返回错误链中第一个 Error 记录的调用栈,只在开发模式下记录
+func GetErrorStack(err error) string {
+	var e *baseError
+	if errors.As(err, &e) {
+		return e.Stack
+	}
+	return ""
+}
+
+//LocalizeError 返回 locale 语言的错误消息,没有时依次尝试语言的前缀(如 zh-CN 的 zh),都没有时返回 err.Error()
+func LocalizeError(err error, locale string) string {
+	var e *baseError
+	if !errors.As(err, &e) {
+		return err.Error()
+	}
+	for locale != "" {
+		if message, ok := e.Localized[locale]; ok {
+			return message
+		}
+		index := strings.LastIndexAny(locale, "-_")
+		if index < 0 {
+			break
+		}
+		locale = locale[:index]
+	}
+	return e.Message
 }
 
 func PanicError(err error) {
@@ -0,0 +1,34 @@
+package base
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	. "gopkg.in/check.v1"
+)
+
+type ErrorsSuite struct {
+}
+
+var _ = Suite(&ErrorsSuite{})
+
+func (suite *ErrorsSuite) TestErrorDetails(c *C) {
+	cause := fmt.Errorf("read config: %w", io.EOF)
+	err := WrapError(Error_System_Internal, "test", "load failed", NewErrorWrapper(Error_System, "io", cause))
+	err = WithErrorMetadata(err, "file", "app.yml")
+	err = WithLocalizedMessage(err, "zh", "加载失败")
+	c.Assert(errors.Is(err, io.EOF), Equals, true)
+	c.Assert(errors.Is(err, NewError(Error_System_Internal, "", "")), Equals, true)
+	c.Assert(GetErrorMetadata(err)["file"], Equals, "app.yml")
+	c.Assert(LocalizeError(err, "zh-CN"), Equals, "加载失败")
+	c.Assert(LocalizeError(err, "en"), Equals, "load failed")
+
+	parsed := ParseErrorFromJSON([]byte(ErrorToJson(err)))
+	c.Assert(parsed.GetCode(), Equals, int32(Error_System_Internal))
+	c.Assert(parsed.GetScopes(), Equals, "test")
+	c.Assert(GetErrorMetadata(parsed)["file"], Equals, "app.yml")
+	c.Assert(LocalizeError(parsed, "zh"), Equals, "加载失败")
+	c.Assert(errors.Is(parsed, NewError(Error_System, "", "")), Equals, true)
+	c.Assert(errors.Unwrap(errors.Unwrap(parsed)).Error(), Equals, "EOF")
+}
@@ -0,0 +1,152 @@
+package grpcbase
+
+import (
+	"encoding/json"
+
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/golang/protobuf/ptypes"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+//GRPCCode 错误码对应的 grpc 状态码,依次使用错误码注册时声明的映射与命名空间的映射,
+// 不是基础错误码而是 grpc 状态码时直接使用,都没有时为 Unknown
+func GRPCCode(code int32) codes.Code {
+	if info, ok := base.LookupErrorCode(code); ok && info.GRPCCode != codes.OK {
+		return info.GRPCCode
+	}
+	if ns, ok := base.LookupErrorNamespace(code); ok && ns.GRPCCode != codes.OK {
+		return ns.GRPCCode
+	}
+	if code > int32(codes.OK) && code <= int32(codes.Unauthenticated) {
+		return codes.Code(code)
+	}
+	return codes.Unknown
+}
+
+//errorDetailTypeKey 与 errorDetailType 标记 Details 中的 Struct 是由 ErrorToStatus 生成的 Error,
+// 避免将其他服务放在 Details 中恰好带有 code 字段的 Struct 当成 Error 解析
+const (
+	errorDetailTypeKey = "@type"
+	errorDetailType    = "microserviceboot.base.Error"
+)
+
+//ErrorToStatus 将 Error 转换为 google.rpc.Status,Code 为 GRPCCode 映射的标准 grpc 状态码,
+// Details 中包含一个 google.protobuf.Struct,内容与 Error 的 json 序列化相同,包括错误码,cause,metadata,localized 与 stack,
+// 并带有值为 microserviceboot.base.Error 的 @type 字段作为标记
+func ErrorToStatus(err base.Error) *spb.Status {
+	s := &spb.Status{Code: int32(GRPCCode(err.GetCode())), Message: err.Error()}
+	var fields map[string]interface{}
+	if e := json.Unmarshal([]byte(base.ErrorToJson(err)), &fields); e != nil {
+		return s
+	}
+	fields[errorDetailTypeKey] = errorDetailType
+	detail, e := ptypes.MarshalAny(toStruct(fields))
+	if e != nil {
+		return s
+	}
+	s.Details = append(s.Details, detail)
+	return s
+}
+
+//ErrorFromStatus 从 ErrorToStatus 生成的 Status 中解析出原来的 Error,Details 中没有带 @type 标记的 Error 时返回 false
+func ErrorFromStatus(s *spb.Status) (base.Error, bool) {
+	for _, detail := range s.GetDetails() {
+		if !ptypes.Is(detail, &structpb.Struct{}) {
+			continue
+		}
+		fields := &structpb.Struct{}
+		if ptypes.UnmarshalAny(detail, fields) != nil {
+			continue
+		}
+		if fields.GetFields()[errorDetailTypeKey].GetStringValue() != errorDetailType {
+			continue
+		}
+		values := fromStruct(fields)
+		delete(values, errorDetailTypeKey)
+		data, e := json.Marshal(values)
+		if e != nil {
+			continue
+		}
+		if err := base.ParseErrorFromJSON(data); err != nil {
+			return err, true
+		}
+	}
+	return nil, false
+}
+
+//ErrorCodes 返回 err 的 grpc 状态码与错误码,err 是 base.Error 时状态码由 GRPCCode 映射,
+// err 是 grpc 的错误时错误码从 Details 中解析,没有时为0
+func ErrorCodes(err error) (codes.Code, int32) {
+	if baseErr, ok := err.(base.Error); ok {
+		return GRPCCode(baseErr.GetCode()), baseErr.GetCode()
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		return codes.Unknown, 0
+	}
+	if baseErr, ok := ErrorFromStatus(s.Proto()); ok {
+		return s.Code(), baseErr.GetCode()
+	}
+	return s.Code(), 0
+}
+
+func toStruct(fields map[string]interface{}) *structpb.Struct {
+	s := &structpb.Struct{Fields: make(map[string]*structpb.Value, len(fields))}
+	for k, v := range fields {
+		s.Fields[k] = toValue(v)
+	}
+	return s
+}
+
+func toValue(v interface{}) *structpb.Value {
+	switch v := v.(type) {
+	case string:
+		return &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: v}}
+	case float64:
+		return &structpb.Value{Kind: &structpb.Value_NumberValue{NumberValue: v}}
+	case bool:
+		return &structpb.Value{Kind: &structpb.Value_BoolValue{BoolValue: v}}
+	case map[string]interface{}:
+		return &structpb.Value{Kind: &structpb.Value_StructValue{StructValue: toStruct(v)}}
+	case []interface{}:
+		list := &structpb.ListValue{}
+		for _, item := range v {
+			list.Values = append(list.Values, toValue(item))
+		}
+		return &structpb.Value{Kind: &structpb.Value_ListValue{ListValue: list}}
+	default:
+		return &structpb.Value{Kind: &structpb.Value_NullValue{}}
+	}
+}
+
+func fromStruct(s *structpb.Struct) map[string]interface{} {
+	fields := make(map[string]interface{}, len(s.GetFields()))
+	for k, v := range s.GetFields() {
+		fields[k] = fromValue(v)
+	}
+	return fields
+}
+
+func fromValue(v *structpb.Value) interface{} {
+	switch kind := v.GetKind().(type) {
+	case *structpb.Value_StringValue:
+		return kind.StringValue
+	case *structpb.Value_NumberValue:
+		return kind.NumberValue
+	case *structpb.Value_BoolValue:
+		return kind.BoolValue
+	case *structpb.Value_StructValue:
+		return fromStruct(kind.StructValue)
+	case *structpb.Value_ListValue:
+		values := make([]interface{}, 0, len(kind.ListValue.GetValues()))
+		for _, item := range kind.ListValue.GetValues() {
+			values = append(values, fromValue(item))
+		}
+		return values
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,51 @@
+package grpcbase
+
+import (
+	"testing"
+
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type ErrorsSuite struct{}
+
+var _ = Suite(&ErrorsSuite{})
+
+func (suite *ErrorsSuite) TestErrorStatus(c *C) {
+	err := base.WrapError(base.Error_Message_NotFount, "user", "user not found", base.NewError(base.Error_System_DB, "db", "no rows"))
+	err = base.WithErrorMetadata(err, "id", "42")
+	s := ErrorToStatus(err)
+	c.Assert(s.GetCode(), Equals, int32(codes.NotFound))
+	c.Assert(len(s.GetDetails()), Equals, 1)
+	grpcCode, errorCode := ErrorCodes(status.ErrorProto(s))
+	c.Assert(grpcCode, Equals, codes.NotFound)
+	c.Assert(errorCode, Equals, int32(base.Error_Message_NotFount))
+	c.Assert(GRPCCode(base.Error_System_DB), Equals, codes.Internal)
+	c.Assert(GRPCCode(base.Error_System_RateLimited), Equals, codes.ResourceExhausted)
+	c.Assert(GRPCCode(int32(codes.PermissionDenied)), Equals, codes.PermissionDenied)
+
+	decoded, ok := ErrorFromStatus(s)
+	c.Assert(ok, Equals, true)
+	c.Assert(decoded.GetScopes(), Equals, "user")
+	c.Assert(decoded.Error(), Equals, "user not found")
+	c.Assert(base.GetErrorMetadata(decoded)["id"], Equals, "42")
+	c.Assert(base.IsDBError(decoded.(interface{ Unwrap() error }).Unwrap()), Equals, true)
+
+	_, ok = ErrorFromStatus(&spb.Status{Code: 2, Message: "unknown"})
+	c.Assert(ok, Equals, false)
+	//没有 @type 标记的 Struct 即使带有 code 字段也不是 Error
+	foreign, e := ptypes.MarshalAny(toStruct(map[string]interface{}{"code": float64(base.Error_System_DB), "message": "other"}))
+	c.Assert(e, IsNil)
+	_, ok = ErrorFromStatus(&spb.Status{Code: 2, Message: "other", Details: []*any.Any{foreign}})
+	c.Assert(ok, Equals, false)
+	grpcCode, errorCode = ErrorCodes(status.ErrorProto(&spb.Status{Code: int32(codes.Unavailable), Details: []*any.Any{foreign}}))
+	c.Assert(grpcCode, Equals, codes.Unavailable)
+	c.Assert(errorCode, Equals, int32(0))
+}
@@ -0,0 +1,11 @@
+package grpcbase
+
+//HealthReporter 修改 grpc.health.v1 中服务的状态,service 为空表示整个服务
+type HealthReporter interface {
+	SetServingStatus(service string, serving bool)
+}
+
+//HealthReporterAware GRPCService 可选实现的接口,用于获取 HealthReporter 自行切换服务状态
+type HealthReporterAware interface {
+	SetHealthReporter(reporter HealthReporter)
+}
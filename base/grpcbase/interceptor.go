@@ -0,0 +1,27 @@
+package grpcbase
+
+import (
+	"github.com/coffeehc/microserviceboot/base"
+	"google.golang.org/grpc"
+)
+
+//UnaryServerInterceptorChain 按名称管理的有序UnaryServerInterceptor链,修改需要在服务启动前完成
+type UnaryServerInterceptorChain interface {
+	AppendInterceptor(name string, interceptor grpc.UnaryServerInterceptor) base.Error
+	InsertBefore(target string, name string, interceptor grpc.UnaryServerInterceptor) base.Error
+	InsertAfter(target string, name string, interceptor grpc.UnaryServerInterceptor) base.Error
+	Remove(name string) base.Error
+}
+
+//StreamServerInterceptorChain 按名称管理的有序StreamServerInterceptor链,修改需要在服务启动前完成
+type StreamServerInterceptorChain interface {
+	AppendInterceptor(name string, interceptor grpc.StreamServerInterceptor) base.Error
+	InsertBefore(target string, name string, interceptor grpc.StreamServerInterceptor) base.Error
+	InsertAfter(target string, name string, interceptor grpc.StreamServerInterceptor) base.Error
+	Remove(name string) base.Error
+}
+
+//InterceptorService GRPCService 可选实现的接口,用于配置服务自己的拦截器链
+type InterceptorService interface {
+	InitInterceptors(unaryChain UnaryServerInterceptorChain, streamChain StreamServerInterceptorChain) base.Error
+}
@@ -0,0 +1,34 @@
+package base
+
+import (
+	"context"
+	"time"
+)
+
+//HealthIndicator 健康检查项,返回 nil 表示健康
+type HealthIndicator interface {
+	Check(cxt context.Context) error
+}
+
+//HealthIndicatorFunc 函数形式的 HealthIndicator
+type HealthIndicatorFunc func(cxt context.Context) error
+
+//Check implement HealthIndicator interface
+func (f HealthIndicatorFunc) Check(cxt context.Context) error {
+	return f(cxt)
+}
+
+//HealthRegistry 健康检查项的注册中心,timeout 小于等于0时使用默认的超时时间
+type HealthRegistry interface {
+	//RegisterLiveness 注册存活检查项,失败说明进程需要重启,对应 /health/live
+	RegisterLiveness(name string, indicator HealthIndicator, timeout time.Duration) Error
+	//RegisterReadiness 注册就绪检查项,失败说明暂时不能接收流量,对应 /health/ready
+	RegisterReadiness(name string, indicator HealthIndicator, timeout time.Duration) Error
+	//Unregister 移除检查项
+	Unregister(name string)
+}
+
+//HealthIndicatorService Service 可选实现的接口,用于注册服务自己的健康检查项,如 DB,Redis,下游服务等
+type HealthIndicatorService interface {
+	InitHealthIndicators(registry HealthRegistry) Error
+}
@@ -1,6 +1,10 @@
 package restbase
 
-import "github.com/coffeehc/httpx"
+import (
+	"fmt"
+
+	"github.com/coffeehc/httpx"
+)
 
 //EndpointMeta endpoint meta define
 type EndpointMeta struct {
@@ -14,3 +18,11 @@ type Endpoint struct {
 	Metadata    EndpointMeta
 	HandlerFunc httpx.RequestHandler
 }
+
+//StatusClass 状态码的分类,如 2xx,5xx,用于监控的 label
+func StatusClass(statusCode int) string {
+	if statusCode < 100 || statusCode > 599 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%dxx", statusCode/100)
+}
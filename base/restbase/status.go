@@ -0,0 +1,109 @@
+package restbase
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/coffeehc/microserviceboot/base"
+)
+
+//StatusRule 错误码范围到 http 状态码的映射
+type StatusRule struct {
+	//From 错误码范围的起始,包含
+	From int32 `yaml:"from"`
+	//To 错误码范围的结束,包含,小于 From 时只匹配 From
+	To int32 `yaml:"to"`
+	//Status http 状态码
+	Status int `yaml:"status" validate:"min=100,max=599"`
+}
+
+func (rule StatusRule) to() int32 {
+	if rule.To < rule.From {
+		return rule.From
+	}
+	return rule.To
+}
+
+func (rule StatusRule) match(code int32) bool {
+	return code >= rule.From && code <= rule.to()
+}
+
+//StatusTable 错误码到 http 状态码的映射表,范围最小的规则优先,范围相同时后添加的优先,没有匹配时为500
+type StatusTable struct {
+	mutex *sync.RWMutex
+	rules []StatusRule
+}
+
+//NewStatusTable 创建映射表
+func NewStatusTable(rules ...StatusRule) *StatusTable {
+	table := &StatusTable{mutex: new(sync.RWMutex)}
+	table.Add(rules...)
+	return table
+}
+
+//Add 添加规则
+func (table *StatusTable) Add(rules ...StatusRule) {
+	table.mutex.Lock()
+	defer table.mutex.Unlock()
+	table.rules = append(table.rules, rules...)
+}
+
+//Status 获取错误码对应的 http 状态码
+func (table *StatusTable) Status(code int32) int {
+	if status, ok := table.lookup(code); ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+func (table *StatusTable) lookup(code int32) (int, bool) {
+	table.mutex.RLock()
+	defer table.mutex.RUnlock()
+	status := 0
+	var width int64 = -1
+	for _, rule := range table.rules {
+		if !rule.match(code) {
+			continue
+		}
+		ruleWidth := int64(rule.to()) - int64(rule.From)
+		if width < 0 || ruleWidth <= width {
+			status = rule.Status
+			width = ruleWidth
+		}
+	}
+	return status, width >= 0
+}
+
+//configuredStatusTable 通过配置添加的规则,优先于错误码注册时声明的映射
+var configuredStatusTable = NewStatusTable()
+
+//AddStatusRules 添加映射规则,优先于错误码注册时声明的映射
+func AddStatusRules(rules ...StatusRule) {
+	configuredStatusTable.Add(rules...)
+}
+
+//HTTPStatus 获取错误码对应的 http 状态码,依次使用 AddStatusRules 添加的规则,错误码注册时声明的映射,
+// 错误码所在命名空间的映射,都没有时为500
+func HTTPStatus(code int32) int {
+	if status, ok := configuredStatusTable.lookup(code); ok {
+		return status
+	}
+	if info, ok := base.LookupErrorCode(code); ok && info.HTTPStatus > 0 {
+		return info.HTTPStatus
+	}
+	if ns, ok := base.LookupErrorNamespace(code); ok && ns.HTTPStatus > 0 {
+		return ns.HTTPStatus
+	}
+	return http.StatusInternalServerError
+}
+
+//ErrorCodeFromStatus 没有错误内容的 http 响应对应的错误码,404为 Error_Message_NotFount,其他4xx为 Error_Message,其他为 Error_System_RPC
+func ErrorCodeFromStatus(status int) int32 {
+	switch {
+	case status == http.StatusNotFound:
+		return base.Error_Message_NotFount
+	case status >= 400 && status < 500:
+		return base.Error_Message
+	}
+	return base.Error_System_RPC
+}
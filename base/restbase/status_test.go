@@ -0,0 +1,32 @@
+package restbase
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/coffeehc/microserviceboot/base"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type StatusSuite struct{}
+
+var _ = Suite(&StatusSuite{})
+
+func (suite *StatusSuite) TestStatusTable(c *C) {
+	c.Assert(HTTPStatus(base.Error_Message_NotFount), Equals, http.StatusNotFound)
+	c.Assert(HTTPStatus(base.Error_Message|0x10), Equals, http.StatusBadRequest)
+	c.Assert(HTTPStatus(base.Error_System_RPC), Equals, http.StatusBadGateway)
+	c.Assert(HTTPStatus(base.Error_System_DB), Equals, http.StatusInternalServerError)
+	c.Assert(HTTPStatus(1), Equals, http.StatusInternalServerError)
+
+	table := NewStatusTable(StatusRule{From: 100, To: 200, Status: http.StatusBadRequest}, StatusRule{From: 150, To: 160, Status: http.StatusConflict})
+	c.Assert(table.Status(155), Equals, http.StatusConflict)
+	c.Assert(table.Status(120), Equals, http.StatusBadRequest)
+	table.Add(StatusRule{From: 100, To: 200, Status: http.StatusForbidden})
+	c.Assert(table.Status(120), Equals, http.StatusForbidden)
+
+	c.Assert(ErrorCodeFromStatus(http.StatusNotFound), Equals, int32(base.Error_Message_NotFount))
+	c.Assert(ErrorCodeFromStatus(http.StatusServiceUnavailable), Equals, int32(base.Error_System_RPC))
+}
@@ -14,6 +14,24 @@ type Service interface {
 	GetServiceDiscoveryRegister() (ServiceDiscoveryRegister, Error)
 }
 
+//CleanFuncRegister 注册服务关闭时执行的清理函数,MicroService 实现了此接口
+type CleanFuncRegister interface {
+	AddCleanFunc(func())
+}
+
+type cleanFuncRegisterKey struct{}
+
+//WithCleanFuncRegister 将 CleanFuncRegister 放入 cxt,MicroService 会在调用 Service.Init 前设置
+func WithCleanFuncRegister(cxt context.Context, register CleanFuncRegister) context.Context {
+	return context.WithValue(cxt, cleanFuncRegisterKey{}, register)
+}
+
+//GetCleanFuncRegister 从 cxt 中获取 CleanFuncRegister,用于让客户端等资源随服务一起关闭
+func GetCleanFuncRegister(cxt context.Context) (CleanFuncRegister, bool) {
+	register, ok := cxt.Value(cleanFuncRegisterKey{}).(CleanFuncRegister)
+	return register, ok
+}
+
 // ServiceInfo 接口定义
 type ServiceInfo interface {
 	//获取 Api 定义的内容
@@ -32,12 +50,12 @@ type ServiceInfo interface {
 
 //SimpleServiceInfo 简单的 ServiceInfo 配置
 type SimpleServiceInfo struct {
-	ServiceName string `yaml:"service_name" json:"service_name"`
-	Version     string `yaml:"version" json:"version"`
+	ServiceName string `yaml:"service_name" json:"service_name" validate:"required"`
+	Version     string `yaml:"version" json:"version" validate:"required"`
 	Descriptor  string `yaml:"descriptor" json:"descriptor"`
 	APIDefine   string `yaml:"api_define" json:"api_define"`
-	Tag         string `yaml:"tag" json:"tag"`
-	Scheme      string `yaml:"scheme" json:"scheme"`
+	Tag         string `yaml:"tag" json:"tag" validate:"required"`
+	Scheme      string `yaml:"scheme" json:"scheme" validate:"omitempty,oneof=http https"`
 }
 
 //GetAPIDefine implement ServiceInfo interface
@@ -2,31 +2,23 @@ package base
 
 import (
 	"fmt"
-	"io/ioutil"
 	"net"
 	"os"
 
 	"context"
 
-	"github.com/coffeehc/logger"
 	xcontext "golang.org/x/net/context"
-	"gopkg.in/yaml.v2"
 )
 
 const errScopeLoadConfig = "loadConfig"
 
-//LoadConfig load the config from config path
+//LoadConfig load the config from config path,依次叠加 配置文件->环境变量->命令行参数 -set,加载后根据 validate tag 校验
 func LoadConfig(configPath string, config interface{}) Error {
-	logger.Debug("load config file %s", configPath)
-	data, err := ioutil.ReadFile(configPath)
+	err := LoadConfigFromSources(config, NewFileConfigSource(configPath), NewEnvConfigSource(), NewFlagConfigSource())
 	if err != nil {
-		return NewError(Error_System, errScopeLoadConfig, err.Error())
+		return err
 	}
-	err = yaml.Unmarshal(data, config)
-	if err != nil {
-		return NewError(Error_System, errScopeLoadConfig, err.Error())
-	}
-	return nil
+	return ValidateConfig(config)
 }
 
 const envIPInterfaceName = "NET_INTERFACE"
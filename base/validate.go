@@ -0,0 +1,259 @@
+package base
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const errScopeValidateConfig = "validate config"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+//FieldViolation 单个配置项的校验错误,Field 为 yaml 中的路径
+type FieldViolation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+//ValidationError 配置校验的错误,包含所有不合法的配置项
+type ValidationError struct {
+	Scope      string            `json:"scope"`
+	Code       int32             `json:"code"`
+	Violations []*FieldViolation `json:"violations"`
+}
+
+func (err *ValidationError) Error() string {
+	messages := make([]string, 0, len(err.Violations))
+	for _, violation := range err.Violations {
+		messages = append(messages, fmt.Sprintf("%s: %s", violation.Field, violation.Message))
+	}
+	return fmt.Sprintf("配置校验失败(%d处): %s", len(err.Violations), strings.Join(messages, "; "))
+}
+
+//GetCode implement Error interface
+func (err *ValidationError) GetCode() int32 {
+	return err.Code
+}
+
+//GetScopes implement Error interface
+func (err *ValidationError) GetScopes() string {
+	return err.Scope
+}
+
+//ValidateConfig 根据 validate tag 校验配置,所有的错误一起返回,没有错误返回 nil
+// 支持的规则: required,omitempty,min=,max=,oneof=a b,addr(host:port),duration
+// min/max 对数字比较大小,对 time.Duration 使用 1s 这样的格式,对 string/slice/map 比较长度
+// 实现了 ConfigValidator 的对象在规则校验之后调用其 Validate,Validate 中只需要做 tag 无法表达的校验
+func ValidateConfig(config interface{}) Error {
+	violations := make([]*FieldViolation, 0)
+	validateValue(reflect.ValueOf(config), "", &violations, 0)
+	if len(violations) == 0 {
+		return nil
+	}
+	return NewValidationError(violations...)
+}
+
+//NewValidationError 构建配置校验的错误,用于在 ConfigValidator 中返回
+func NewValidationError(violations ...*FieldViolation) *ValidationError {
+	return &ValidationError{
+		Scope:      errScopeValidateConfig,
+		Code:       Error_System,
+		Violations: violations,
+	}
+}
+
+func validateValue(v reflect.Value, path string, violations *[]*FieldViolation, depth int) {
+	if !v.IsValid() || depth > 8 {
+		return
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			fieldPath := joinFieldPath(path, yamlFieldName(field))
+			fieldValue := v.Field(i)
+			if rules := field.Tag.Get("validate"); rules != "" {
+				for _, message := range checkRules(fieldValue, rules) {
+					*violations = append(*violations, &FieldViolation{Field: fieldPath, Message: message})
+				}
+			}
+			validateValue(fieldValue, fieldPath, violations, depth+1)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), violations, depth+1)
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			validateValue(v.MapIndex(key), joinFieldPath(path, fmt.Sprint(key.Interface())), violations, depth+1)
+		}
+	}
+	if v.CanAddr() {
+		v = v.Addr()
+	}
+	if validator, ok := v.Interface().(ConfigValidator); ok {
+		err := validator.Validate()
+		if err == nil {
+			return
+		}
+		if validationError, ok := err.(*ValidationError); ok {
+			for _, violation := range validationError.Violations {
+				*violations = append(*violations, &FieldViolation{Field: joinFieldPath(path, violation.Field), Message: violation.Message})
+			}
+			return
+		}
+		*violations = append(*violations, &FieldViolation{Field: path, Message: err.Error()})
+	}
+}
+
+func checkRules(v reflect.Value, rules string) []string {
+	messages := make([]string, 0)
+	isZero := isZeroValue(v)
+	for _, rule := range strings.Split(rules, ",") {
+		name, param := rule, ""
+		if index := strings.Index(rule, "="); index != -1 {
+			name, param = rule[:index], rule[index+1:]
+		}
+		switch name {
+		case "omitempty":
+			if isZero {
+				return messages
+			}
+		case "required":
+			if isZero {
+				return append(messages, "不能为空")
+			}
+		case "min", "max":
+			if message := checkRange(v, name, param); message != "" {
+				messages = append(messages, message)
+			}
+		case "oneof":
+			value := fmt.Sprint(indirect(v).Interface())
+			if !containsString(strings.Fields(param), value) {
+				messages = append(messages, fmt.Sprintf("%q 不在 [%s] 中", value, param))
+			}
+		case "addr":
+			if message := checkAddr(indirect(v).String()); message != "" {
+				messages = append(messages, message)
+			}
+		case "duration":
+			if _, err := time.ParseDuration(indirect(v).String()); err != nil {
+				messages = append(messages, fmt.Sprintf("%q 不是合法的时间格式", indirect(v).String()))
+			}
+		default:
+			messages = append(messages, fmt.Sprintf("未知的校验规则 %s", name))
+		}
+	}
+	return messages
+}
+
+func checkRange(v reflect.Value, name string, param string) string {
+	v = indirect(v)
+	var value, limit float64
+	var display string
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(param)
+		if err != nil {
+			return fmt.Sprintf("校验规则 %s=%s 不是合法的时间格式", name, param)
+		}
+		value, limit, display = float64(v.Int()), float64(d), time.Duration(v.Int()).String()
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		value, display = float64(v.Int()), strconv.FormatInt(v.Int(), 10)
+	case v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64:
+		value, display = float64(v.Uint()), strconv.FormatUint(v.Uint(), 10)
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		value, display = v.Float(), strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case v.Kind() == reflect.String || v.Kind() == reflect.Slice || v.Kind() == reflect.Map:
+		value, display = float64(v.Len()), fmt.Sprintf("长度 %d", v.Len())
+	default:
+		return fmt.Sprintf("%s 不支持 %s 类型", name, v.Type())
+	}
+	if v.Type() != durationType {
+		var err error
+		limit, err = strconv.ParseFloat(param, 64)
+		if err != nil {
+			return fmt.Sprintf("校验规则 %s=%s 不是合法的数字", name, param)
+		}
+	}
+	if name == "min" && value < limit {
+		return fmt.Sprintf("%s 小于最小值 %s", display, param)
+	}
+	if name == "max" && value > limit {
+		return fmt.Sprintf("%s 大于最大值 %s", display, param)
+	}
+	return ""
+}
+
+func checkAddr(addr string) string {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Sprintf("%q 不是合法的 host:port 地址", addr)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil || p < 0 || p > 65535 {
+		return fmt.Sprintf("%q 的端口不合法", addr)
+	}
+	return ""
+}
+
+func isZeroValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Slice, reflect.Map, reflect.String:
+		return v.Len() == 0
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			if v.Kind() == reflect.Interface {
+				return reflect.ValueOf("")
+			}
+			return reflect.Zero(v.Type().Elem())
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func yamlFieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if name == "" || name == "-" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+func joinFieldPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
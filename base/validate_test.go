@@ -0,0 +1,53 @@
+package base
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type ValidateSuite struct {
+}
+
+var _ = Suite(&ValidateSuite{})
+
+type testLimitConfig struct {
+	ServerAddr string        `yaml:"server_addr" validate:"required,addr"`
+	Timeout    time.Duration `yaml:"timeout" validate:"min=1s,max=1m"`
+	Workers    int           `yaml:"workers" validate:"min=1"`
+}
+
+type testValidateConfig struct {
+	ServiceInfo *SimpleServiceInfo `yaml:"service_info" validate:"required"`
+	Limit       *testLimitConfig   `yaml:"limit"`
+}
+
+func (suite *ValidateSuite) TestValidateConfig(c *C) {
+	config := &testValidateConfig{
+		ServiceInfo: &SimpleServiceInfo{ServiceName: "demo", Scheme: "ftp"},
+		Limit: &testLimitConfig{
+			ServerAddr: "127.0.0.1",
+			Timeout:    time.Hour,
+		},
+	}
+	err := ValidateConfig(config)
+	c.Assert(err, NotNil)
+	violations := err.(*ValidationError).Violations
+	fields := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		fields = append(fields, violation.Field)
+	}
+	c.Assert(fields, DeepEquals, []string{
+		"service_info.version",
+		"service_info.tag",
+		"service_info.scheme",
+		"limit.server_addr",
+		"limit.timeout",
+		"limit.workers",
+	})
+
+	config.ServiceInfo = &SimpleServiceInfo{ServiceName: "demo", Version: "1.0", Tag: "dev"}
+	config.Limit = &testLimitConfig{ServerAddr: "0.0.0.0:8888", Timeout: time.Second, Workers: 2}
+	c.Assert(ValidateConfig(config), IsNil)
+	c.Assert(ValidateConfig(&testValidateConfig{}), NotNil)
+}
@@ -7,10 +7,18 @@ import (
 	"golang.org/x/net/context"
 )
 
+//NewConsulBalancer 构建基于 consul 服务发现的 Balancer,使用 cxt 中 loadbalancer.WithPolicy 与 WithZoneAware 指定的策略
 func NewConsulBalancer(cxt context.Context, consulClient *api.Client, serviceInfo base.ServiceInfo) (loadbalancer.Balancer, base.Error) {
 	consulRecolver, err := newConsulResolver(consulClient, serviceInfo.GetServiceName(), serviceInfo.GetServiceTag())
 	if err != nil {
 		return nil, err
 	}
-	return loadbalancer.RoundRobin(consulRecolver), nil
+	return loadbalancer.NewContextBalancer(cxt, consulRecolver)
+}
+
+//NewConsulBalancerBuilder 构建基于 consul 服务发现的 loadbalancer.BalancerBuilder
+func NewConsulBalancerBuilder(consulClient *api.Client) loadbalancer.BalancerBuilder {
+	return loadbalancer.BalancerBuilderFunc(func(cxt context.Context, serviceInfo base.ServiceInfo) (loadbalancer.Balancer, base.Error) {
+		return NewConsulBalancer(cxt, consulClient, serviceInfo)
+	})
 }
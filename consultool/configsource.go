@@ -0,0 +1,113 @@
+package consultool
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/coffeehc/logger"
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/hashicorp/consul/api"
+	"gopkg.in/yaml.v2"
+)
+
+const errScopeConsulConfig = "consul config"
+
+const watchWaitTime = time.Second * 30
+
+//NewConsulKVConfigSource 构建一个从 consul KV 读取配置的 base.DynamicConfigSource,key 对应的值为 yaml 格式
+func NewConsulKVConfigSource(consulClient *api.Client, key string) (base.DynamicConfigSource, base.Error) {
+	if consulClient == nil {
+		return nil, base.NewError(base.Error_System, errScopeConsulConfig, "没有指定 consulClient")
+	}
+	if key == "" {
+		return nil, base.NewError(base.Error_System, errScopeConsulConfig, "没有指定配置的 key")
+	}
+	return &consulKVConfigSource{
+		client: consulClient,
+		key:    key,
+		mutex:  new(sync.Mutex),
+	}, nil
+}
+
+type consulKVConfigSource struct {
+	client    *api.Client
+	key       string
+	lastIndex uint64
+	mutex     *sync.Mutex
+}
+
+func (s *consulKVConfigSource) Apply(config interface{}) base.Error {
+	kvPair, meta, err := s.client.KV().Get(s.key, nil)
+	if err != nil {
+		return base.NewError(base.Error_System, errScopeConsulConfig, err.Error())
+	}
+	s.mutex.Lock()
+	s.lastIndex = meta.LastIndex
+	s.mutex.Unlock()
+	if kvPair == nil {
+		logger.Warn("consul 中没有配置 %s", s.key)
+		return nil
+	}
+	err = yaml.Unmarshal(kvPair.Value, config)
+	if err != nil {
+		return base.NewError(base.Error_System, errScopeConsulConfig, err.Error())
+	}
+	return nil
+}
+
+//Watch 使用 consul 的 blocking query 监听 key 的变化
+func (s *consulKVConfigSource) Watch(cxt context.Context, onChange func()) base.Error {
+	s.mutex.Lock()
+	waitIndex := s.lastIndex
+	s.mutex.Unlock()
+	go func() {
+		for {
+			select {
+			case <-cxt.Done():
+				return
+			default:
+			}
+			meta, err := s.waitChange(cxt, waitIndex)
+			if cxt.Err() != nil {
+				return
+			}
+			if err != nil {
+				logger.Error("监听 consul 配置 %s 失败:%s", s.key, err)
+				time.Sleep(time.Second * 3)
+				continue
+			}
+			if meta.LastIndex < waitIndex {
+				//index 被重置,重新开始监听
+				waitIndex = 0
+				continue
+			}
+			if meta.LastIndex != waitIndex {
+				waitIndex = meta.LastIndex
+				onChange()
+			}
+		}
+	}()
+	return nil
+}
+
+type waitResult struct {
+	meta *api.QueryMeta
+	err  error
+}
+
+//waitChange 执行 blocking query,cxt 结束时立即返回
+// consul api 不支持 context,WaitTime 限制了 cxt 结束后请求最多还会在后台阻塞多久
+func (s *consulKVConfigSource) waitChange(cxt context.Context, waitIndex uint64) (*api.QueryMeta, error) {
+	result := make(chan waitResult, 1)
+	go func() {
+		_, meta, err := s.client.KV().Get(s.key, &api.QueryOptions{WaitIndex: waitIndex, WaitTime: watchWaitTime})
+		result <- waitResult{meta: meta, err: err}
+	}()
+	select {
+	case <-cxt.Done():
+		return nil, cxt.Err()
+	case r := <-result:
+		return r.meta, r.err
+	}
+}
@@ -8,13 +8,16 @@ import (
 
 	"github.com/coffeehc/logger"
 	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/loadbalancer"
 	"github.com/hashicorp/consul/api"
 )
 
 const errScopeConsulRegister = "consul register"
 
 type consulServiceRegister struct {
-	client *api.Client
+	client    *api.Client
+	checkPath string
+	metadata  map[string]string
 }
 
 //NewConsulServiceRegister 构建一个 base.ServiceDiscoveryRegister的基于 consul 的实现实例
@@ -23,10 +26,23 @@ func NewConsulServiceRegister(consulClient *api.Client) (base.ServiceDiscoveryRe
 		return nil, base.NewError(base.Error_System, errScopeConsulRegister, "没有指定 consulClient")
 	}
 	return &consulServiceRegister{
-		client: consulClient,
+		client:    consulClient,
+		checkPath: "/health/ready",
 	}, nil
 }
 
+//SetHealthCheck 实现 base.HealthCheckRegister,使用 healthCheck 中的 HTTPPath 作为 consul 的 HTTP Check
+func (csr *consulServiceRegister) SetHealthCheck(healthCheck *base.HealthCheck) {
+	if healthCheck != nil && healthCheck.HTTPPath != "" {
+		csr.checkPath = healthCheck.HTTPPath
+	}
+}
+
+//SetMetadata 实现 base.MetadataRegister,元数据以 key=value 的格式作为 tag 注册
+func (csr *consulServiceRegister) SetMetadata(metadata map[string]string) {
+	csr.metadata = metadata
+}
+
 func (csr *consulServiceRegister) RegService(cxt context.Context, serviceInfo base.ServiceInfo, serviceAddr string) (func(), base.Error) {
 	if serviceAddr == "" {
 		return nil, base.NewError(base.Error_System, errScopeConsulRegister, "serverAddr is nil")
@@ -53,13 +69,13 @@ func (csr *consulServiceRegister) RegService(cxt context.Context, serviceInfo ba
 	registration := &api.AgentServiceRegistration{
 		ID:                serviceAddr,
 		Name:              serviceInfo.GetServiceName(),
-		Tags:              []string{serviceInfo.GetServiceTag()},
+		Tags:              append([]string{serviceInfo.GetServiceTag()}, loadbalancer.MetadataToTags(csr.metadata)...),
 		Port:              p,
 		Address:           addr, //http 获取节点的情况下,或出现问题
 		EnableTagOverride: true,
 		Checks: api.AgentServiceChecks([]*api.AgentServiceCheck{
 			{
-				HTTP:          fmt.Sprintf("%s://%s/health", serviceInfo.GetScheme(), serviceAddr),
+				HTTP:          fmt.Sprintf("%s://%s%s", serviceInfo.GetScheme(), serviceAddr, csr.checkPath),
 				Interval:      "10s",
 				Status:        "passing",
 				TLSSkipVerify: true,
@@ -4,7 +4,6 @@ import (
 	"math/rand"
 	"net"
 	"strconv"
-	"sync"
 	"time"
 
 	"github.com/coffeehc/logger"
@@ -20,10 +19,8 @@ type _ConsulResolver struct {
 	tag         string
 	passingOnly bool
 
-	quitc       chan struct{}
-	quitUpdate  chan struct{}
-	updatesc    chan []*naming.Update
-	updateMutex *sync.Mutex
+	quitc    chan struct{}
+	updatesc chan []*naming.Update
 }
 
 // NewConsulResolver initializes and returns a new ConsulResolver.
@@ -37,13 +34,11 @@ func newConsulResolver(client *api.Client, service, tag string) (naming.Resolver
 		tag:         tag,
 		passingOnly: true,
 		quitc:       make(chan struct{}),
-		quitUpdate:  make(chan struct{}),
 		updatesc:    make(chan []*naming.Update, 1),
-		updateMutex: new(sync.Mutex),
 	}
 
 	// Retrieve instances immediately
-	instancesCh := make(chan []string)
+	instancesCh := make(chan map[string]loadbalancer.NodeMetadata)
 	go func() {
 		sleep := int64(time.Second * 10)
 		for {
@@ -92,19 +87,17 @@ func (r *_ConsulResolver) Close() {
 
 // updater is a background process started in NewConsulResolver. It takes
 // a list of previously resolved instances (in the format of host:port, e.g.
-// 192.168.0.1:1234) and the last index returned from Consul.
-func (r *_ConsulResolver) updater(instances []string, lastIndex uint64) {
+// 192.168.0.1:1234) with their metadata and the last index returned from Consul.
+func (r *_ConsulResolver) updater(instances map[string]loadbalancer.NodeMetadata, lastIndex uint64) {
 	var err error
 	var oldInstances = instances
-	var newInstances []string
+	var newInstances map[string]loadbalancer.NodeMetadata
 
 	// TODO Cache the updates for a while, so that we don't overwhelm Consul.
 	sleep := int64(time.Second * 10)
 	for {
 		select {
 		case <-r.quitc:
-			break
-		case <-r.quitUpdate:
 			return
 		default:
 			func() {
@@ -132,7 +125,7 @@ func (r *_ConsulResolver) updater(instances []string, lastIndex uint64) {
 
 // getInstances retrieves the new set of instances registered for the
 // service from Consul.
-func (r *_ConsulResolver) getInstances(lastIndex uint64) ([]string, uint64, error) {
+func (r *_ConsulResolver) getInstances(lastIndex uint64) (map[string]loadbalancer.NodeMetadata, uint64, error) {
 	services, meta, err := r.c.Health().Service(r.service, r.tag, r.passingOnly, &api.QueryOptions{
 		WaitIndex: lastIndex,
 		WaitTime:  time.Second,
@@ -143,50 +136,41 @@ func (r *_ConsulResolver) getInstances(lastIndex uint64) ([]string, uint64, erro
 	if len(services) == 0 {
 		return nil, lastIndex, base.NewError(base.Error_System, "consul resolver", "service is no address available")
 	}
-	var instances []string
+	instances := make(map[string]loadbalancer.NodeMetadata, len(services))
 	for _, service := range services {
 		s := service.Service.Address
 		if len(s) == 0 {
 			s = service.Node.Address
 		}
 		addr := net.JoinHostPort(s, strconv.Itoa(service.Service.Port))
-		instances = append(instances, addr)
+		//consul 的服务没有元数据,注册时以 key=value 的 tag 保存,没有指定 zone/region 时使用 consul 节点的 node_meta
+		metadata := loadbalancer.TagsToMetadata(service.Service.Tags)
+		if service.Node != nil {
+			for _, key := range []string{loadbalancer.MetadataZone, loadbalancer.MetadataRegion} {
+				if _, ok := metadata[key]; !ok && service.Node.Meta[key] != "" {
+					metadata[key] = service.Node.Meta[key]
+				}
+			}
+		}
+		instances[addr] = loadbalancer.ParseNodeMetadata(metadata)
 	}
 	return instances, meta.LastIndex, nil
 }
 
 // makeUpdates calculates the difference between and old and a new set of
 // instances and turns it into an array of naming.Updates.
-func (r *_ConsulResolver) makeUpdates(oldInstances, newInstances []string) []*naming.Update {
-	oldAddr := make(map[string]struct{}, len(oldInstances))
-	for _, instance := range oldInstances {
-		oldAddr[instance] = struct{}{}
-	}
-	newAddr := make(map[string]struct{}, len(newInstances))
-	for _, instance := range newInstances {
-		newAddr[instance] = struct{}{}
-	}
+// An instance whose metadata changed is deleted with the old metadata and added again.
+func (r *_ConsulResolver) makeUpdates(oldInstances, newInstances map[string]loadbalancer.NodeMetadata) []*naming.Update {
 	var updates []*naming.Update
-	for addr := range newAddr {
-		if _, ok := oldAddr[addr]; !ok {
-			updates = append(updates, &naming.Update{Op: naming.Add, Addr: addr})
+	for addr, metadata := range oldInstances {
+		if newMetadata, ok := newInstances[addr]; !ok || newMetadata != metadata {
+			updates = append(updates, &naming.Update{Op: naming.Delete, Addr: addr, Metadata: metadata})
 		}
 	}
-	for addr := range oldAddr {
-		if _, ok := newAddr[addr]; !ok {
-			updates = append(updates, &naming.Update{Op: naming.Delete, Addr: addr})
+	for addr, metadata := range newInstances {
+		if oldMetadata, ok := oldInstances[addr]; !ok || oldMetadata != metadata {
+			updates = append(updates, &naming.Update{Op: naming.Add, Addr: addr, Metadata: metadata})
 		}
 	}
 	return updates
 }
-
-func (sr *_ConsulResolver) Delete(addr loadbalancer.Address) {
-	sr.updateMutex.Lock()
-	defer sr.updateMutex.Unlock()
-	logger.Warn("delete addr [%s]", addr.Addr)
-	sr.updatesc <- []*naming.Update{&naming.Update{Op: naming.Delete, Addr: addr.Addr}}
-	sr.quitUpdate <- struct{}{}
-	time.Sleep(time.Second * 10)
-	instances := make([]string, 0)
-	go sr.updater(instances, 0)
-}
@@ -0,0 +1,75 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/loadbalancer"
+	xcontext "golang.org/x/net/context"
+)
+
+const errScopeDiscovery = "discovery"
+
+//Backend 服务发现的后端,同时提供服务端的注册与客户端的 Balancer
+type Backend interface {
+	//GetServiceDiscoveryRegister 获取服务注册,不需要注册时返回 nil
+	GetServiceDiscoveryRegister() (base.ServiceDiscoveryRegister, base.Error)
+	//NewBalancer 为 serviceInfo 对应的服务创建 Balancer
+	NewBalancer(cxt context.Context, serviceInfo base.ServiceInfo) (loadbalancer.Balancer, base.Error)
+	//Close 关闭 backend 使用的连接
+	Close()
+}
+
+//BackendFactory 根据配置创建 Backend
+type BackendFactory func(config *Config) (Backend, base.Error)
+
+var (
+	backendMutex     = new(sync.RWMutex)
+	backendFactories = map[string]BackendFactory{
+		BackendConsul: newConsulBackend,
+		BackendEtcd:   newEtcdBackend,
+		BackendStatic: newStaticBackend,
+		BackendNone:   newNoneBackend,
+	}
+)
+
+//RegisterBackend 注册自定义的 Backend,name 对应配置中的 backend
+func RegisterBackend(name string, factory BackendFactory) base.Error {
+	backendMutex.Lock()
+	defer backendMutex.Unlock()
+	if _, ok := backendFactories[name]; ok {
+		return base.NewError(base.Error_System, errScopeDiscovery, fmt.Sprintf("backend %s 已经存在", name))
+	}
+	backendFactories[name] = factory
+	return nil
+}
+
+func hasBackend(name string) bool {
+	backendMutex.RLock()
+	defer backendMutex.RUnlock()
+	_, ok := backendFactories[name]
+	return ok
+}
+
+//NewBackend 根据配置中的 backend 创建对应的 Backend
+func NewBackend(config *Config) (Backend, base.Error) {
+	if config == nil || config.Backend == "" {
+		return nil, base.NewError(base.Error_System, errScopeDiscovery, "没有指定 backend")
+	}
+	backendMutex.RLock()
+	factory, ok := backendFactories[config.Backend]
+	backendMutex.RUnlock()
+	if !ok {
+		return nil, base.NewError(base.Error_System, errScopeDiscovery, fmt.Sprintf("backend %s 没有注册", config.Backend))
+	}
+	return factory(config)
+}
+
+//NewBalancerBuilder 将 Backend 转换为客户端使用的 loadbalancer.BalancerBuilder
+func NewBalancerBuilder(backend Backend) loadbalancer.BalancerBuilder {
+	return loadbalancer.BalancerBuilderFunc(func(cxt xcontext.Context, serviceInfo base.ServiceInfo) (loadbalancer.Balancer, base.Error) {
+		return backend.NewBalancer(cxt, serviceInfo)
+	})
+}
@@ -0,0 +1,119 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/consultool"
+	"github.com/coffeehc/microserviceboot/etcdtool"
+	"github.com/coffeehc/microserviceboot/loadbalancer"
+	"github.com/coreos/etcd/clientv3"
+	"github.com/hashicorp/consul/api"
+)
+
+type consulBackend struct {
+	client *api.Client
+	config *Config
+}
+
+func newConsulBackend(config *Config) (Backend, base.Error) {
+	client, err := consultool.NewClient(config.Consul)
+	if err != nil {
+		return nil, err
+	}
+	return &consulBackend{client: client, config: config}, nil
+}
+
+func (b *consulBackend) GetServiceDiscoveryRegister() (base.ServiceDiscoveryRegister, base.Error) {
+	return consultool.NewConsulServiceRegister(b.client)
+}
+
+func (b *consulBackend) NewBalancer(cxt context.Context, serviceInfo base.ServiceInfo) (loadbalancer.Balancer, base.Error) {
+	return consultool.NewConsulBalancer(withBalancerDefaults(cxt, b.config), b.client, serviceInfo)
+}
+
+func (b *consulBackend) Close() {}
+
+type etcdBackend struct {
+	client *clientv3.Client
+	config *Config
+}
+
+func newEtcdBackend(config *Config) (Backend, base.Error) {
+	etcdConfig := config.Etcd
+	if etcdConfig == nil {
+		//endpoints 可以通过环境变量指定
+		etcdConfig = &etcdtool.Config{}
+	}
+	client, err := etcdtool.NewClient(etcdConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &etcdBackend{client: client, config: config}, nil
+}
+
+func (b *etcdBackend) GetServiceDiscoveryRegister() (base.ServiceDiscoveryRegister, base.Error) {
+	return etcdtool.NewEtcdServiceRegister(b.client)
+}
+
+func (b *etcdBackend) NewBalancer(cxt context.Context, serviceInfo base.ServiceInfo) (loadbalancer.Balancer, base.Error) {
+	return etcdtool.NewEtcdBalancer(withBalancerDefaults(cxt, b.config), b.client, serviceInfo)
+}
+
+func (b *etcdBackend) Close() {
+	b.client.Close()
+}
+
+type staticBackend struct {
+	addrs  map[string][]string
+	config *Config
+}
+
+func newStaticBackend(config *Config) (Backend, base.Error) {
+	return &staticBackend{addrs: config.Static, config: config}, nil
+}
+
+func (b *staticBackend) GetServiceDiscoveryRegister() (base.ServiceDiscoveryRegister, base.Error) {
+	return nil, nil
+}
+
+func (b *staticBackend) NewBalancer(cxt context.Context, serviceInfo base.ServiceInfo) (loadbalancer.Balancer, base.Error) {
+	addrs := b.addrs[serviceInfo.GetServiceName()]
+	if len(addrs) == 0 {
+		return nil, base.NewError(base.Error_System, errScopeDiscovery, fmt.Sprintf("static 中没有配置服务[%s]的地址", serviceInfo.GetServiceName()))
+	}
+	return loadbalancer.NewAddrArrayContextBalancer(withBalancerDefaults(cxt, b.config), addrs, serviceInfo.GetScheme() == "https")
+}
+
+func (b *staticBackend) Close() {}
+
+type noneBackend struct{}
+
+func newNoneBackend(config *Config) (Backend, base.Error) {
+	return &noneBackend{}, nil
+}
+
+func (b *noneBackend) GetServiceDiscoveryRegister() (base.ServiceDiscoveryRegister, base.Error) {
+	return nil, nil
+}
+
+func (b *noneBackend) NewBalancer(cxt context.Context, serviceInfo base.ServiceInfo) (loadbalancer.Balancer, base.Error) {
+	return nil, base.NewError(base.Error_System, errScopeDiscovery, fmt.Sprintf("backend 为 none,无法发现服务[%s]", serviceInfo.GetServiceName()))
+}
+
+func (b *noneBackend) Close() {}
+
+//withBalancerDefaults cxt 中没有指定的负载均衡配置使用配置中的 policy,zone_aware 与 outlier_detection
+func withBalancerDefaults(cxt context.Context, config *Config) context.Context {
+	if config.Policy != "" && loadbalancer.GetPolicy(cxt) == "" {
+		cxt = loadbalancer.WithPolicy(cxt, config.Policy)
+	}
+	if config.ZoneAware != nil && loadbalancer.GetZoneAware(cxt) == nil {
+		cxt = loadbalancer.WithZoneAware(cxt, config.ZoneAware)
+	}
+	if config.OutlierDetection != nil && loadbalancer.GetOutlierDetection(cxt) == nil {
+		cxt = loadbalancer.WithOutlierDetection(cxt, config.OutlierDetection)
+	}
+	return cxt
+}
@@ -0,0 +1,74 @@
+package discovery
+
+import (
+	"fmt"
+
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/consultool"
+	"github.com/coffeehc/microserviceboot/etcdtool"
+	"github.com/coffeehc/microserviceboot/loadbalancer"
+)
+
+const (
+	//BackendConsul 使用 consul 注册与发现服务
+	BackendConsul = "consul"
+	//BackendEtcd 使用 etcd 注册与发现服务
+	BackendEtcd = "etcd"
+	//BackendStatic 不注册服务,客户端使用配置中的固定地址
+	BackendStatic = "static"
+	//BackendNone 不注册服务,也不提供服务发现
+	BackendNone = "none"
+)
+
+//Config 服务发现的配置,对应 service_config 中的 discovery
+type Config struct {
+	Backend string                   `yaml:"backend" validate:"required"`
+	Consul  *consultool.ConsulConfig `yaml:"consul"`
+	Etcd    *etcdtool.Config         `yaml:"etcd"`
+	//Static 服务名对应的地址列表,用于 static
+	Static map[string][]string `yaml:"static"`
+	//Policy 客户端默认的负载均衡策略,如 weighted_round_robin,可以通过 loadbalancer.WithPolicy 为单个客户端指定
+	Policy string `yaml:"policy"`
+	//ZoneAware 客户端同区域优先的配置,可以通过 loadbalancer.WithZoneAware 为单个客户端指定
+	ZoneAware *loadbalancer.ZoneAwareConfig `yaml:"zone_aware"`
+	//OutlierDetection 客户端被动健康检查的配置,可以通过 loadbalancer.WithOutlierDetection 为单个客户端指定
+	OutlierDetection *loadbalancer.OutlierConfig `yaml:"outlier_detection"`
+	//Options 自定义 backend 使用的配置
+	Options map[string]interface{} `yaml:"options"`
+}
+
+//Validate 实现 base.ConfigValidator,检查 backend 与 policy 是否已经注册
+func (config *Config) Validate() base.Error {
+	violations := make([]*base.FieldViolation, 0)
+	if config.Backend != "" && !hasBackend(config.Backend) {
+		violations = append(violations, &base.FieldViolation{Field: "backend", Message: fmt.Sprintf("%q 没有注册", config.Backend)})
+	}
+	if config.Policy != "" && !loadbalancer.HasPolicy(config.Policy) {
+		violations = append(violations, &base.FieldViolation{Field: "policy", Message: fmt.Sprintf("不支持的负载均衡策略 %q", config.Policy)})
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return &base.ValidationError{
+		Scope:      errScopeDiscovery,
+		Code:       base.Error_System,
+		Violations: violations,
+	}
+}
+
+//LoadConfig 从服务的配置文件中加载 service_config.discovery,客户端可以使用与服务注册相同的配置
+func LoadConfig(configPath string) (*Config, base.Error) {
+	i := &struct {
+		ServiceConfig *struct {
+			Discovery *Config `yaml:"discovery"`
+		} `yaml:"service_config"`
+	}{}
+	err := base.LoadConfig(configPath, i)
+	if err != nil {
+		return nil, err
+	}
+	if i.ServiceConfig == nil || i.ServiceConfig.Discovery == nil {
+		return nil, base.NewError(base.Error_System, errScopeDiscovery, "没有配置 service_config.discovery")
+	}
+	return i.ServiceConfig.Discovery, nil
+}
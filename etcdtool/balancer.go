@@ -6,12 +6,21 @@ import (
 	"github.com/coffeehc/microserviceboot/base"
 	"github.com/coffeehc/microserviceboot/loadbalancer"
 	"github.com/coreos/etcd/clientv3"
+	xcontext "golang.org/x/net/context"
 )
 
+//NewEtcdBalancer 构建基于 etcd 服务发现的 Balancer,使用 cxt 中 loadbalancer.WithPolicy 与 WithZoneAware 指定的策略
 func NewEtcdBalancer(cxt context.Context, client *clientv3.Client, serviceInfo base.ServiceInfo) (loadbalancer.Balancer, base.Error) {
 	etcdRecolver, err := newEtcdResolver(client, serviceInfo.GetServiceName(), serviceInfo.GetServiceTag())
 	if err != nil {
 		return nil, err
 	}
-	return loadbalancer.RoundRobin(etcdRecolver), nil
+	return loadbalancer.NewContextBalancer(cxt, etcdRecolver)
+}
+
+//NewEtcdBalancerBuilder 构建基于 etcd 服务发现的 loadbalancer.BalancerBuilder
+func NewEtcdBalancerBuilder(client *clientv3.Client) loadbalancer.BalancerBuilder {
+	return loadbalancer.BalancerBuilderFunc(func(cxt xcontext.Context, serviceInfo base.ServiceInfo) (loadbalancer.Balancer, base.Error) {
+		return NewEtcdBalancer(cxt, client, serviceInfo)
+	})
 }
@@ -0,0 +1,96 @@
+package etcdtool
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coffeehc/logger"
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coreos/etcd/clientv3"
+)
+
+const errScopeEtcdConfig = "etcd config"
+
+//NewEtcdConfigSource 构建一个从 etcd 前缀读取配置的 base.DynamicConfigSource
+// 如 prefix 为 /config/demo/ 时,key /config/demo/service/limit 对应的配置项为 service.limit,值为 yaml 格式
+func NewEtcdConfigSource(client *clientv3.Client, prefix string) (base.DynamicConfigSource, base.Error) {
+	if client == nil {
+		return nil, base.NewError(base.Error_System, errScopeEtcdConfig, "没有指定 etcd client")
+	}
+	if prefix == "" {
+		return nil, base.NewError(base.Error_System, errScopeEtcdConfig, "没有指定配置的前缀")
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix = prefix + "/"
+	}
+	return &etcdConfigSource{
+		client: client,
+		prefix: prefix,
+		mutex:  new(sync.Mutex),
+	}, nil
+}
+
+type etcdConfigSource struct {
+	client   *clientv3.Client
+	prefix   string
+	revision int64
+	mutex    *sync.Mutex
+}
+
+func (s *etcdConfigSource) Apply(config interface{}) base.Error {
+	cxt, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	response, err := s.client.Get(cxt, s.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return base.NewError(base.Error_System, errScopeEtcdConfig, err.Error())
+	}
+	s.mutex.Lock()
+	s.revision = response.Header.Revision
+	s.mutex.Unlock()
+	for _, kv := range response.Kvs {
+		key := strings.Trim(strings.TrimPrefix(string(kv.Key), s.prefix), "/")
+		if key == "" {
+			continue
+		}
+		baseErr := base.SetConfigValue(config, strings.Split(key, "/"), string(kv.Value))
+		if baseErr != nil {
+			return baseErr
+		}
+	}
+	return nil
+}
+
+//Watch 监听前缀下所有 key 的变化
+func (s *etcdConfigSource) Watch(cxt context.Context, onChange func()) base.Error {
+	s.mutex.Lock()
+	revision := s.revision
+	s.mutex.Unlock()
+	go func() {
+		for {
+			watchChan := s.client.Watch(cxt, s.prefix, clientv3.WithPrefix(), clientv3.WithRev(revision+1))
+			for response := range watchChan {
+				if response.CompactRevision != 0 {
+					//历史版本已经被压缩,从压缩的版本开始监听并重新加载一次全量配置
+					revision = response.CompactRevision
+					onChange()
+					continue
+				}
+				if err := response.Err(); err != nil {
+					logger.Error("监听 etcd 配置 %s 失败:%s", s.prefix, err)
+					continue
+				}
+				revision = response.Header.Revision
+				if len(response.Events) > 0 {
+					onChange()
+				}
+			}
+			if cxt.Err() != nil {
+				return
+			}
+			time.Sleep(time.Second * 3)
+		}
+	}()
+	return nil
+}
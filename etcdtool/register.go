@@ -7,29 +7,59 @@ import (
 
 	"net"
 
+	"sync"
 	"time"
 
 	"github.com/coffeehc/logger"
 	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/loadbalancer"
 	"github.com/coreos/etcd/clientv3"
 	"github.com/pquerna/ffjson/ffjson"
 )
 
 type ServiceRegisterInfo struct {
 	ServiceInfo *base.SimpleServiceInfo `json:"info"`
+	//Metadata 节点的元数据,如负载均衡使用的 weight
+	Metadata map[string]string `json:"metadata,omitempty"`
+	//Zone 节点所在的可用区
+	Zone string `json:"zone,omitempty"`
+	//Region 节点所在的区域
+	Region string `json:"region,omitempty"`
 }
 
 var timeout = time.Second * 5
 
+//healthCheckInterval watchHealth 执行 healthChecker 的间隔
+var healthCheckInterval = timeout / 2
+
 func NewEtcdServiceRegister(client *clientv3.Client) (base.ServiceDiscoveryRegister, base.Error) {
 	return &etcdServiceRegister{
 		client: client,
+		mutex:  new(sync.Mutex),
 	}, nil
 }
 
 type etcdServiceRegister struct {
-	client     *clientv3.Client
-	serviceKey string
+	client        *clientv3.Client
+	serviceKey    string
+	serviceValue  string
+	leaseID       clientv3.LeaseID
+	healthChecker func() bool
+	unhealthy     bool
+	metadata      map[string]string
+	mutex         *sync.Mutex
+}
+
+//SetHealthCheck 实现 base.HealthCheckRegister,Checker 不健康时从 etcd 中摘除注册信息,恢复后重新注册
+func (reg *etcdServiceRegister) SetHealthCheck(healthCheck *base.HealthCheck) {
+	if healthCheck != nil {
+		reg.healthChecker = healthCheck.Checker
+	}
+}
+
+//SetMetadata 实现 base.MetadataRegister,元数据保存在注册信息中
+func (reg *etcdServiceRegister) SetMetadata(metadata map[string]string) {
+	reg.metadata = metadata
 }
 
 func (reg *etcdServiceRegister) RegService(cxt context.Context, info base.ServiceInfo, serviceAddr string) (deregister func(), err base.Error) {
@@ -41,8 +71,16 @@ func (reg *etcdServiceRegister) RegService(cxt context.Context, info base.Servic
 	if err != nil {
 		return nil, err
 	}
+	checkCxt, checkCancel := context.WithCancel(cxt)
+	if reg.healthChecker != nil {
+		go reg.watchHealth(checkCxt)
+	}
 	return func() {
-		_, _err := reg.client.KV.Delete(cxt, reg.serviceKey)
+		checkCancel()
+		reg.mutex.Lock()
+		serviceKey := reg.serviceKey
+		reg.mutex.Unlock()
+		_, _err := reg.client.KV.Delete(cxt, serviceKey)
 		if _err != nil {
 			logger.Error("反注册服务失败," + _err.Error())
 		}
@@ -64,7 +102,6 @@ func (reg *etcdServiceRegister) register(cxt context.Context, info base.ServiceI
 	}
 	serviceKey := fmt.Sprintf("%s%s", buildServiceKeyPrefix(info.GetServiceName(), info.GetServiceTag()), serverAddr)
 	logger.Debug("serviceKey is %s", serviceKey)
-	reg.serviceKey = serviceKey
 	leaseGrantResponse, err := reg.client.Lease.Grant(cxt, int64(timeout/time.Second))
 	if err != nil {
 		if reTry {
@@ -74,8 +111,21 @@ func (reg *etcdServiceRegister) register(cxt context.Context, info base.ServiceI
 		}
 		return base.NewError(base.Error_System, "etcd", "创建租约失败")
 	}
-	value, _ := ffjson.Marshal(&ServiceRegisterInfo{ServiceInfo: info.(*base.SimpleServiceInfo)})
-	_, err = reg.client.Put(cxt, serviceKey, string(value), clientv3.WithLease(leaseGrantResponse.ID))
+	value, _ := ffjson.Marshal(&ServiceRegisterInfo{
+		ServiceInfo: info.(*base.SimpleServiceInfo),
+		Metadata:    reg.metadata,
+		Zone:        reg.metadata[loadbalancer.MetadataZone],
+		Region:      reg.metadata[loadbalancer.MetadataRegion],
+	})
+	reg.mutex.Lock()
+	reg.serviceKey = serviceKey
+	reg.serviceValue = string(value)
+	reg.leaseID = leaseGrantResponse.ID
+	unhealthy := reg.unhealthy
+	reg.mutex.Unlock()
+	if !unhealthy {
+		_, err = reg.client.Put(cxt, serviceKey, string(value), clientv3.WithLease(leaseGrantResponse.ID))
+	}
 	if err != nil {
 		if reTry {
 			time.Sleep(time.Second * 3)
@@ -133,3 +183,39 @@ func (reg *etcdServiceRegister) keepAlive(cxt context.Context, leaseId clientv3.
 	}(leaseKeepAliveResponseChe)
 	return nil
 }
+
+//watchHealth 定期执行 healthChecker,状态变化时摘除或者恢复注册信息
+func (reg *etcdServiceRegister) watchHealth(cxt context.Context) {
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-cxt.Done():
+			return
+		case <-ticker.C:
+			healthy := reg.healthChecker()
+			reg.mutex.Lock()
+			if healthy != reg.unhealthy {
+				reg.mutex.Unlock()
+				continue
+			}
+			serviceKey, serviceValue, leaseID := reg.serviceKey, reg.serviceValue, reg.leaseID
+			reg.unhealthy = !healthy
+			reg.mutex.Unlock()
+			var err error
+			if healthy {
+				logger.Info("服务恢复健康,重新注册%s", serviceKey)
+				_, err = reg.client.Put(cxt, serviceKey, serviceValue, clientv3.WithLease(leaseID))
+			} else {
+				logger.Warn("服务不健康,摘除注册信息%s", serviceKey)
+				_, err = reg.client.KV.Delete(cxt, serviceKey)
+			}
+			if err != nil {
+				logger.Error("更新注册信息失败,%s", err)
+				reg.mutex.Lock()
+				reg.unhealthy = healthy
+				reg.mutex.Unlock()
+			}
+		}
+	}
+}
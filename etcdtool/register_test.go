@@ -0,0 +1,84 @@
+package etcdtool
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"golang.org/x/net/context"
+	. "gopkg.in/check.v1"
+)
+
+type RegisterHealthSuite struct {
+}
+
+var _ = Suite(&RegisterHealthSuite{})
+
+//testKV 记录 Put 与 Delete 操作,failPuts 为需要失败的 Put 次数
+type testKV struct {
+	clientv3.KV
+	mutex    *sync.Mutex
+	ops      chan string
+	failPuts int
+}
+
+func (kv *testKV) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	kv.mutex.Lock()
+	defer kv.mutex.Unlock()
+	if kv.failPuts > 0 {
+		kv.failPuts--
+		kv.ops <- "put failed " + key
+		return nil, errors.New("put failed")
+	}
+	kv.ops <- "put " + key + "=" + val
+	return &clientv3.PutResponse{}, nil
+}
+
+func (kv *testKV) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	kv.ops <- "delete " + key
+	return &clientv3.DeleteResponse{}, nil
+}
+
+func nextOp(c *C, ops chan string) string {
+	select {
+	case op := <-ops:
+		return op
+	case <-time.After(time.Second):
+		c.Fatal("没有等到注册信息的修改")
+		return ""
+	}
+}
+
+func (t *RegisterHealthSuite) TestWatchHealth(c *C) {
+	interval := healthCheckInterval
+	healthCheckInterval = time.Millisecond * 5
+	defer func() {
+		healthCheckInterval = interval
+	}()
+	kv := &testKV{mutex: new(sync.Mutex), ops: make(chan string, 10), failPuts: 1}
+	var healthy int32 = 1
+	reg := &etcdServiceRegister{
+		client:       &clientv3.Client{KV: kv},
+		serviceKey:   "service/127.0.0.1:8888",
+		serviceValue: "info",
+		leaseID:      1,
+		mutex:        new(sync.Mutex),
+		healthChecker: func() bool {
+			return atomic.LoadInt32(&healthy) == 1
+		},
+	}
+	cxt, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reg.watchHealth(cxt)
+
+	atomic.StoreInt32(&healthy, 0)
+	c.Assert(nextOp(c, kv.ops), Equals, "delete service/127.0.0.1:8888")
+	atomic.StoreInt32(&healthy, 1)
+	//重新注册失败时在下一次检查时重试
+	c.Assert(nextOp(c, kv.ops), Equals, "put failed service/127.0.0.1:8888")
+	c.Assert(nextOp(c, kv.ops), Equals, "put service/127.0.0.1:8888=info")
+	time.Sleep(time.Millisecond * 20)
+	c.Assert(len(kv.ops), Equals, 0)
+}
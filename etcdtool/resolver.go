@@ -2,7 +2,6 @@ package etcdtool
 
 import (
 	"context"
-	"sync"
 	"time"
 
 	"github.com/coffeehc/logger"
@@ -20,10 +19,10 @@ type _EtcdResolver struct {
 	tag            string
 	registerPrefix string
 
-	quitc       chan struct{}
-	quitUpdate  chan struct{}
-	updatesc    chan []*naming.Update
-	updateMutex *sync.Mutex
+	quitc    chan struct{}
+	updatesc chan []*naming.Update
+	//metadatas 已发现节点的元数据,Delete 时需要与 Add 的 Metadata 一致,只在 updater 中使用
+	metadatas map[string]loadbalancer.NodeMetadata
 }
 
 func newEtcdResolver(client *clientv3.Client, service, tag string) (naming.Resolver, base.Error) {
@@ -33,9 +32,8 @@ func newEtcdResolver(client *clientv3.Client, service, tag string) (naming.Resol
 		tag:            tag,
 		registerPrefix: buildServiceKeyPrefix(service, tag),
 		quitc:          make(chan struct{}),
-		quitUpdate:     make(chan struct{}),
 		updatesc:       make(chan []*naming.Update, 1),
-		updateMutex:    new(sync.Mutex),
+		metadatas:      make(map[string]loadbalancer.NodeMetadata),
 	}
 
 	// Retrieve instances immediately
@@ -61,7 +59,7 @@ func (r *_EtcdResolver) Close() {
 }
 
 func (r *_EtcdResolver) updater() {
-	instancesCh := make(chan []string)
+	instancesCh := make(chan map[string]loadbalancer.NodeMetadata)
 	go func() {
 		for {
 			instances, err := r.getInstances()
@@ -70,16 +68,16 @@ func (r *_EtcdResolver) updater() {
 				time.Sleep(time.Second)
 				continue
 			}
-			logger.Debug("初始化instance is %q", instances)
+			logger.Debug("初始化instance is %v", instances)
 			instancesCh <- instances
 			return
 		}
 	}()
 	instances := <-instancesCh
 	updates := make([]*naming.Update, 0)
-	for _, instance := range instances {
+	for instance, metadata := range instances {
 		if instance != "" {
-			updates = append(updates, &naming.Update{Op: naming.Add, Addr: instance})
+			updates = append(updates, r.addUpdates(instance, metadata)...)
 		}
 	}
 	r.updatesc <- updates
@@ -93,9 +91,6 @@ func (r *_EtcdResolver) updater() {
 				return
 			}
 			break
-		case <-r.quitUpdate:
-			logger.Warn("quitUpdate")
-			return
 		case response, ok := <-watchChan:
 			if !ok {
 				logger.Debug("re wartch")
@@ -106,12 +101,14 @@ func (r *_EtcdResolver) updater() {
 			for _, event := range response.Events {
 				switch event.Type {
 				case clientv3.EventTypePut:
-					addr := r.getServiceAddr(event.Kv)
+					addr, metadata := r.getServiceAddr(event.Kv)
 					if addr != "" {
-						updates = append(updates, &naming.Update{Op: naming.Add, Addr: addr})
+						updates = append(updates, r.addUpdates(addr, metadata)...)
 					}
 				case clientv3.EventTypeDelete:
-					updates = append(updates, &naming.Update{Op: naming.Delete, Addr: string(event.Kv.Key[len(r.registerPrefix):])})
+					addr := string(event.Kv.Key[len(r.registerPrefix):])
+					updates = append(updates, &naming.Update{Op: naming.Delete, Addr: addr, Metadata: r.metadatas[addr]})
+					delete(r.metadatas, addr)
 				default:
 					logger.Warn("无法识别的事件,%#v", event)
 				}
@@ -124,36 +121,52 @@ func (r *_EtcdResolver) updater() {
 	}
 }
 
-func (r *_EtcdResolver) getInstances() ([]string, error) {
+//addUpdates 新增节点,已经存在的节点元数据变化时先删除旧的节点
+func (r *_EtcdResolver) addUpdates(addr string, metadata loadbalancer.NodeMetadata) []*naming.Update {
+	updates := make([]*naming.Update, 0, 2)
+	if old, ok := r.metadatas[addr]; ok {
+		if old == metadata {
+			return updates
+		}
+		updates = append(updates, &naming.Update{Op: naming.Delete, Addr: addr, Metadata: old})
+	}
+	r.metadatas[addr] = metadata
+	return append(updates, &naming.Update{Op: naming.Add, Addr: addr, Metadata: metadata})
+}
+
+func (r *_EtcdResolver) getInstances() (map[string]loadbalancer.NodeMetadata, error) {
 	response, err := r.client.KV.Get(context.Background(), r.registerPrefix, clientv3.WithPrefix())
 	if err != nil {
 		return nil, err
 	}
-	address := []string{}
+	address := make(map[string]loadbalancer.NodeMetadata)
 	for _, kv := range response.Kvs {
-		addr := r.getServiceAddr(kv)
+		addr, metadata := r.getServiceAddr(kv)
 		if addr != "" {
-			address = append(address, string(kv.Key[len(r.registerPrefix):]))
+			address[addr] = metadata
 		}
 	}
 	return address, nil
 }
 
-func (r *_EtcdResolver) getServiceAddr(kv *mvccpb.KeyValue) string {
+func (r *_EtcdResolver) getServiceAddr(kv *mvccpb.KeyValue) (string, loadbalancer.NodeMetadata) {
 	logger.Debug("value is %s", kv.Value)
 	info := &ServiceRegisterInfo{}
 	err := ffjson.Unmarshal(kv.Value, info)
 	if err != nil {
 		logger.Error("Unmarshal er is %s", err)
-		return ""
+		return "", loadbalancer.NodeMetadata{}
 	}
 	logger.Debug("info is %#v", info)
 	if info.ServiceInfo.Tag == r.tag {
-		return string(kv.Key[len(r.registerPrefix):])
+		metadata := loadbalancer.ParseNodeMetadata(info.Metadata)
+		if metadata.Zone == "" {
+			metadata.Zone = info.Zone
+		}
+		if metadata.Region == "" {
+			metadata.Region = info.Region
+		}
+		return string(kv.Key[len(r.registerPrefix):]), metadata
 	}
-	return ""
-}
-
-func (sr *_EtcdResolver) Delete(addr loadbalancer.Address) {
-	time.Sleep(time.Second)
+	return "", loadbalancer.NodeMetadata{}
 }
@@ -201,9 +201,6 @@ func (rr *roundRobin) down(addr Address, err error) {
 	defer rr.mu.Unlock()
 	for _, a := range rr.addrs {
 		if addr == a.addr {
-			if nodeDown, ok := rr.r.(NodeDown); ok {
-				nodeDown.Delete(addr)
-			}
 			a.connected = false
 			break
 		}
@@ -310,6 +307,9 @@ func (rr *roundRobin) Notify() <-chan []Address {
 func (rr *roundRobin) Close() error {
 	rr.mu.Lock()
 	defer rr.mu.Unlock()
+	if rr.done {
+		return nil
+	}
 	rr.done = true
 	if rr.w != nil {
 		rr.w.Close()
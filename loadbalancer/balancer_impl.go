@@ -1,40 +1,35 @@
 package loadbalancer
 
 import (
-	"crypto/tls"
-	"net"
-	"time"
-
 	"github.com/coffeehc/microserviceboot/base"
+	"golang.org/x/net/context"
 	"google.golang.org/grpc/naming"
 )
 
-var defaultTlsConfig = &tls.Config{
-	InsecureSkipVerify: true,
+//NewAddrArrayBalancer 使用固定的地址创建轮询的 Balancer,不可用的地址由被动健康检查暂时摘除,ssl 保留兼容,已不再使用
+func NewAddrArrayBalancer(addrs []string, ssl bool) (Balancer, base.Error) {
+	return NewAddrArrayContextBalancer(context.Background(), addrs, ssl)
 }
 
-func NewAddrArrayBalancer(addrs []string, ssl bool) (Balancer, base.Error) {
-	r, err := newAddrArrayResolver(addrs, ssl)
+//NewAddrArrayContextBalancer 使用固定的地址创建 Balancer,策略由 cxt 中的 WithPolicy,WithZoneAware 与 WithOutlierDetection 指定
+func NewAddrArrayContextBalancer(cxt context.Context, addrs []string, ssl bool) (Balancer, base.Error) {
+	r, err := newAddrArrayResolver(addrs)
 	if err != nil {
 		return nil, err
 	}
-	return RoundRobin(r), nil
+	return NewContextBalancer(cxt, r)
 }
 
 type addrArrayResolver struct {
-	updatesc    chan []*naming.Update
-	addrMonitor map[string]struct{}
-	ssl         bool
+	updatesc chan []*naming.Update
 }
 
-func newAddrArrayResolver(addrs []string, ssl bool) (*addrArrayResolver, base.Error) {
+func newAddrArrayResolver(addrs []string) (*addrArrayResolver, base.Error) {
 	if addrs == nil || len(addrs) == 0 {
 		return nil, base.NewError(-1, errScopeBalance, "addrs is nil")
 	}
 	resolver := &addrArrayResolver{
-		updatesc:    make(chan []*naming.Update, 1),
-		addrMonitor: make(map[string]struct{}, len(addrs)),
-		ssl:         ssl,
+		updatesc: make(chan []*naming.Update, 1),
 	}
 	go func() {
 		updates := make([]*naming.Update, len(addrs))
@@ -46,35 +41,9 @@ func newAddrArrayResolver(addrs []string, ssl bool) (*addrArrayResolver, base.Er
 		}
 		resolver.updatesc <- updates
 	}()
-	go resolver.monitorAddr()
 	return resolver, nil
 }
 
-func (sr *addrArrayResolver) monitorAddr() {
-	timeout := time.Second * 3
-	timer := time.NewTimer(timeout)
-	for {
-		select {
-		case <-timer.C:
-			for addr, _ := range sr.addrMonitor {
-				var conn net.Conn
-				var err error
-				if sr.ssl {
-					conn, err = tls.Dial("tcp", addr, defaultTlsConfig)
-				} else {
-					conn, err = net.Dial("tcp", addr)
-				}
-				if err == nil && conn != nil {
-					conn.Close()
-					delete(sr.addrMonitor, addr)
-					sr.updatesc <- []*naming.Update{&naming.Update{Op: naming.Add, Addr: addr}}
-				}
-			}
-			timer.Reset(timeout)
-		}
-	}
-}
-
 func (sr *addrArrayResolver) Resolve(target string) (naming.Watcher, error) {
 	return sr, nil
 }
@@ -86,10 +55,3 @@ func (sr *addrArrayResolver) Next() ([]*naming.Update, error) {
 func (sr *addrArrayResolver) Close() {
 	close(sr.updatesc)
 }
-
-func (sr *addrArrayResolver) Delete(addr Address) {
-	sr.updatesc <- []*naming.Update{&naming.Update{Op: naming.Delete, Addr: addr.Addr}}
-	//启动地址监控
-	sr.addrMonitor[addr.Addr] = struct{}{}
-
-}
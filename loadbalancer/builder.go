@@ -0,0 +1,32 @@
+package loadbalancer
+
+import (
+	"fmt"
+
+	"github.com/coffeehc/microserviceboot/base"
+	"golang.org/x/net/context"
+)
+
+//BalancerBuilder 根据 ServiceInfo 创建对应服务的 Balancer
+type BalancerBuilder interface {
+	Build(cxt context.Context, serviceInfo base.ServiceInfo) (Balancer, base.Error)
+}
+
+//BalancerBuilderFunc 函数形式的 BalancerBuilder
+type BalancerBuilderFunc func(cxt context.Context, serviceInfo base.ServiceInfo) (Balancer, base.Error)
+
+//Build implement BalancerBuilder interface
+func (f BalancerBuilderFunc) Build(cxt context.Context, serviceInfo base.ServiceInfo) (Balancer, base.Error) {
+	return f(cxt, serviceInfo)
+}
+
+//NewAddrArrayBalancerBuilder 使用固定的地址创建 Balancer,addrs 的 key 为服务名,策略由 cxt 中的 WithPolicy 与 WithZoneAware 指定
+func NewAddrArrayBalancerBuilder(addrs map[string][]string) BalancerBuilder {
+	return BalancerBuilderFunc(func(cxt context.Context, serviceInfo base.ServiceInfo) (Balancer, base.Error) {
+		serviceAddrs, ok := addrs[serviceInfo.GetServiceName()]
+		if !ok {
+			return nil, base.NewError(base.Error_System, errScopeBalance, fmt.Sprintf("没有服务[%s]的地址", serviceInfo.GetServiceName()))
+		}
+		return NewAddrArrayContextBalancer(cxt, serviceAddrs, serviceInfo.GetScheme() == "https")
+	})
+}
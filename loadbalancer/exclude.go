@@ -0,0 +1,97 @@
+package loadbalancer
+
+import (
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+type excludedAddrsKey struct{}
+
+//WithExcludedAddrs 指定本次调用尽量不要选择的地址,如重试时排除已经失败的地址,用于 grpc 调用或者 BalancerGetOptions 的 ctx
+func WithExcludedAddrs(ctx context.Context, addrs ...string) context.Context {
+	excluded := GetExcludedAddrs(ctx)
+	all := make([]string, 0, len(excluded)+len(addrs))
+	all = append(append(all, excluded...), addrs...)
+	return context.WithValue(ctx, excludedAddrsKey{}, all)
+}
+
+//GetExcludedAddrs 获取 ctx 中排除的地址,没有指定时返回 nil
+func GetExcludedAddrs(ctx context.Context) []string {
+	if ctx == nil {
+		return nil
+	}
+	addrs, _ := ctx.Value(excludedAddrsKey{}).([]string)
+	return addrs
+}
+
+//AddrGroup 同一次逻辑调用的多个并发尝试共享的地址组,如对冲请求,
+// Balancer 选择节点时排除组内已经选择过的地址,并把新选择的地址加入组内
+type AddrGroup struct {
+	mutex *sync.Mutex
+	addrs []string
+}
+
+//NewAddrGroup 创建空的地址组
+func NewAddrGroup() *AddrGroup {
+	return &AddrGroup{mutex: new(sync.Mutex)}
+}
+
+//Addrs 组内已经选择过的地址
+func (g *AddrGroup) Addrs() []string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return append([]string(nil), g.addrs...)
+}
+
+func (g *AddrGroup) add(addr string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.addrs = append(g.addrs, addr)
+}
+
+type addrGroupKey struct{}
+
+//WithAddrGroup 指定本次调用所属的地址组,用于 grpc 调用或者 BalancerGetOptions 的 ctx
+func WithAddrGroup(ctx context.Context, group *AddrGroup) context.Context {
+	return context.WithValue(ctx, addrGroupKey{}, group)
+}
+
+//GetAddrGroup 获取 ctx 中的地址组,没有指定时返回 nil
+func GetAddrGroup(ctx context.Context) *AddrGroup {
+	if ctx == nil {
+		return nil
+	}
+	group, _ := ctx.Value(addrGroupKey{}).(*AddrGroup)
+	return group
+}
+
+//filterExcluded 过滤掉 ctx 中排除的地址与地址组中已经选择过的地址,所有的节点都被排除时返回原节点
+func filterExcluded(ctx context.Context, nodes []*Node) []*Node {
+	excluded := GetExcludedAddrs(ctx)
+	if group := GetAddrGroup(ctx); group != nil {
+		excluded = append(excluded, group.Addrs()...)
+	}
+	if len(excluded) == 0 {
+		return nodes
+	}
+	available := make([]*Node, 0, len(nodes))
+	for _, node := range nodes {
+		if !containsAddr(excluded, node.Address.Addr) {
+			available = append(available, node)
+		}
+	}
+	if len(available) == 0 {
+		return nodes
+	}
+	return available
+}
+
+func containsAddr(addrs []string, addr string) bool {
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package loadbalancer
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+
+	"golang.org/x/net/context"
+)
+
+//hashReplicas 每个权重单位在环上的虚拟节点数
+const hashReplicas = 100
+
+type hashKey struct{}
+
+//WithHashKey 指定一致性哈希使用的 key,如用户 ID,租户,用于 grpc 调用或者 BalancerGetOptions 的 ctx
+func WithHashKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, hashKey{}, key)
+}
+
+//GetHashKey 获取 ctx 中的一致性哈希 key,没有指定时返回空
+func GetHashKey(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	key, _ := ctx.Value(hashKey{}).(string)
+	return key
+}
+
+//consistentHashPicker 基于虚拟节点的哈希环,环由 SetNodes 获得的所有节点构建,只在节点变化时重建,
+// Pick 时沿着环跳过未连接,被排除或者被摘除的节点,只有属于这些节点的 key 顺延到环上的下一个节点,恢复后重新回到该节点
+type consistentHashPicker struct {
+	hashes []uint32
+	owners map[uint32]string
+	next   int
+}
+
+func newConsistentHashPicker() Picker {
+	return &consistentHashPicker{}
+}
+
+//SetNodes implement NodeSetAware interface
+func (p *consistentHashPicker) SetNodes(nodes []*Node) {
+	p.buildRing(nodes)
+}
+
+func (p *consistentHashPicker) Pick(ctx context.Context, nodes []*Node) *Node {
+	key := GetHashKey(ctx)
+	if key == "" {
+		//没有指定 key 时轮询
+		p.next = (p.next + 1) % len(nodes)
+		return nodes[p.next]
+	}
+	if p.hashes == nil {
+		//没有通过 SetNodes 获得节点时使用第一次 Pick 的节点
+		p.buildRing(nodes)
+	}
+	available := make(map[string]*Node, len(nodes))
+	for _, node := range nodes {
+		available[node.Address.Addr] = node
+	}
+	hash := crc32.ChecksumIEEE([]byte(key))
+	start := sort.Search(len(p.hashes), func(i int) bool { return p.hashes[i] >= hash })
+	for i := 0; i < len(p.hashes); i++ {
+		if node, ok := available[p.owners[p.hashes[(start+i)%len(p.hashes)]]]; ok {
+			return node
+		}
+	}
+	//可用的节点都不在环上
+	return nodes[0]
+}
+
+//buildRing 使用所有的节点重建哈希环
+func (p *consistentHashPicker) buildRing(nodes []*Node) {
+	p.hashes = make([]uint32, 0, len(nodes)*hashReplicas)
+	p.owners = make(map[uint32]string, len(nodes)*hashReplicas)
+	for _, node := range nodes {
+		addr := node.Address.Addr
+		for _, hash := range p.nodeHashes(node) {
+			if owner, ok := p.owners[hash]; ok && owner < addr {
+				//哈希冲突时固定选择地址较小的节点,与节点的顺序无关
+				continue
+			}
+			if _, ok := p.owners[hash]; !ok {
+				p.hashes = append(p.hashes, hash)
+			}
+			p.owners[hash] = addr
+		}
+	}
+	sort.Sort(uint32Slice(p.hashes))
+}
+
+func (p *consistentHashPicker) nodeHashes(node *Node) []uint32 {
+	weight := node.Metadata.Weight
+	if weight <= 0 {
+		weight = defaultWeight
+	}
+	hashes := make([]uint32, 0, weight*hashReplicas)
+	for i := 0; i < weight*hashReplicas; i++ {
+		hashes = append(hashes, crc32.ChecksumIEEE([]byte(node.Address.Addr+"-"+strconv.Itoa(i))))
+	}
+	return hashes
+}
+
+type uint32Slice []uint32
+
+func (s uint32Slice) Len() int           { return len(s) }
+func (s uint32Slice) Less(i, j int) bool { return s[i] < s[j] }
+func (s uint32Slice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
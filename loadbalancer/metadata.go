@@ -0,0 +1,68 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	//MetadataWeight 节点权重的元数据 key
+	MetadataWeight = "weight"
+	//MetadataZone 节点所在可用区的元数据 key
+	MetadataZone = "zone"
+	//MetadataRegion 节点所在区域的元数据 key
+	MetadataRegion = "region"
+
+	defaultWeight = 1
+)
+
+//NodeMetadata 注册中心中节点的元数据,作为 Address.Metadata 使用,必须是可比较的值类型
+type NodeMetadata struct {
+	Weight int
+	Zone   string
+	Region string
+}
+
+//ParseNodeMetadata 从注册中心的元数据中解析 NodeMetadata,没有或者不合法的值使用默认值
+func ParseNodeMetadata(metadata map[string]string) NodeMetadata {
+	nodeMetadata := NodeMetadata{
+		Weight: defaultWeight,
+		Zone:   metadata[MetadataZone],
+		Region: metadata[MetadataRegion],
+	}
+	if weight, err := strconv.Atoi(metadata[MetadataWeight]); err == nil && weight > 0 {
+		nodeMetadata.Weight = weight
+	}
+	return nodeMetadata
+}
+
+//MetadataToTags 将元数据转换为 key=value 格式的 tag,用于不支持元数据的注册中心
+func MetadataToTags(metadata map[string]string) []string {
+	tags := make([]string, 0, len(metadata))
+	for key, value := range metadata {
+		tags = append(tags, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+//TagsToMetadata 从 key=value 格式的 tag 中解析元数据,其他的 tag 会被忽略
+func TagsToMetadata(tags []string) map[string]string {
+	metadata := make(map[string]string)
+	for _, tag := range tags {
+		kv := strings.SplitN(tag, "=", 2)
+		if len(kv) == 2 {
+			metadata[kv[0]] = kv[1]
+		}
+	}
+	return metadata
+}
+
+func getNodeMetadata(addr Address) NodeMetadata {
+	if metadata, ok := addr.Metadata.(NodeMetadata); ok {
+		return metadata
+	}
+	return NodeMetadata{Weight: defaultWeight}
+}
@@ -0,0 +1,219 @@
+package loadbalancer
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultConsecutiveErrors  = 5
+	defaultBaseEjectionTime   = time.Second * 30
+	defaultMaxEjectionTime    = time.Minute * 5
+	defaultMaxEjectionPercent = 50
+	//latencyMinNodes 延迟异常检测至少需要有延迟数据的节点数
+	latencyMinNodes = 3
+)
+
+//OutlierConfig 被动健康检查的配置,连续失败或者延迟异常的节点会被暂时摘除,摘除时间随次数增长,到期后自动恢复
+type OutlierConfig struct {
+	//Disable 关闭被动健康检查
+	Disable bool `yaml:"disable"`
+	//ConsecutiveErrors 连续失败多少次后摘除,默认5
+	ConsecutiveErrors int `yaml:"consecutive_errors" validate:"min=0"`
+	//LatencyFactor EWMA 延迟超过其他节点平均值的倍数时摘除,默认0表示不检查延迟
+	LatencyFactor float64 `yaml:"latency_factor" validate:"min=0"`
+	//BaseEjectionTime 第一次摘除的时间,之后每次摘除增加一个 BaseEjectionTime,默认30s
+	BaseEjectionTime time.Duration `yaml:"base_ejection_time" validate:"min=0s"`
+	//MaxEjectionTime 摘除时间的上限,恢复后超过该时间没有再被摘除时摘除次数清零,默认5m
+	MaxEjectionTime time.Duration `yaml:"max_ejection_time" validate:"min=0s"`
+	//MaxEjectionPercent 最多摘除的节点比例,默认50
+	MaxEjectionPercent int `yaml:"max_ejection_percent" validate:"min=0,max=100"`
+}
+
+func (config *OutlierConfig) getConsecutiveErrors() int {
+	if config.ConsecutiveErrors <= 0 {
+		return defaultConsecutiveErrors
+	}
+	return config.ConsecutiveErrors
+}
+
+func (config *OutlierConfig) getBaseEjectionTime() time.Duration {
+	if config.BaseEjectionTime <= 0 {
+		return defaultBaseEjectionTime
+	}
+	return config.BaseEjectionTime
+}
+
+func (config *OutlierConfig) getMaxEjectionTime() time.Duration {
+	if config.MaxEjectionTime <= 0 {
+		return defaultMaxEjectionTime
+	}
+	return config.MaxEjectionTime
+}
+
+func (config *OutlierConfig) getMaxEjectionPercent() int {
+	if config.MaxEjectionPercent <= 0 {
+		return defaultMaxEjectionPercent
+	}
+	return config.MaxEjectionPercent
+}
+
+type outlierKey struct{}
+
+//WithOutlierDetection 指定创建 Balancer 时使用的被动健康检查配置,用于 BalancerBuilder.Build 的 cxt
+func WithOutlierDetection(cxt context.Context, config *OutlierConfig) context.Context {
+	return context.WithValue(cxt, outlierKey{}, config)
+}
+
+//GetOutlierDetection 获取 cxt 中的被动健康检查配置,没有指定时返回 nil
+func GetOutlierDetection(cxt context.Context) *OutlierConfig {
+	if cxt == nil {
+		return nil
+	}
+	config, _ := cxt.Value(outlierKey{}).(*OutlierConfig)
+	return config
+}
+
+//ResultReporter Balancer 可选实现的接口,调用方上报每次调用的结果用于被动健康检查
+// addr 为实际调用的地址,failed 表示节点异常(连接失败,超时,服务端内部错误),业务错误不应该视为失败
+// 延迟由 Get 返回的 put 记录,不需要上报
+type ResultReporter interface {
+	ReportResult(addr string, failed bool)
+}
+
+//LatencyReporter Balancer 可选实现的接口,连接被复用的调用方(如 http)不能通过 put 记录每次调用的延迟,
+// 由调用方上报 addr 上每次调用的延迟
+type LatencyReporter interface {
+	ReportLatency(addr string, latency time.Duration)
+}
+
+//outlierState 节点的被动健康检查状态
+type outlierState struct {
+	consecutiveErrors int
+	ejections         int
+	ejectedUntil      time.Time
+	lastEjectedUntil  time.Time
+}
+
+//ejected 节点是否处于摘除状态,摘除到期时自动恢复
+func (n *Node) ejected(now time.Time) bool {
+	if n.outlier.ejectedUntil.IsZero() {
+		return false
+	}
+	if now.Before(n.outlier.ejectedUntil) {
+		return true
+	}
+	n.outlier.lastEjectedUntil = n.outlier.ejectedUntil
+	n.outlier.ejectedUntil = time.Time{}
+	n.outlier.consecutiveErrors = 0
+	return false
+}
+
+//outlierDetector 在 Balancer 的锁内使用,为 nil 时不做被动健康检查
+type outlierDetector struct {
+	config *OutlierConfig
+}
+
+func newOutlierDetector(config *OutlierConfig) *outlierDetector {
+	if config == nil {
+		config = &OutlierConfig{}
+	}
+	if config.Disable {
+		return nil
+	}
+	return &outlierDetector{config: config}
+}
+
+//filter 过滤掉被摘除的节点,所有的节点都被摘除时返回原节点
+func (d *outlierDetector) filter(nodes []*Node) []*Node {
+	if d == nil {
+		return nodes
+	}
+	now := time.Now()
+	available := make([]*Node, 0, len(nodes))
+	for _, node := range nodes {
+		if !node.ejected(now) {
+			available = append(available, node)
+		}
+	}
+	if len(available) == 0 {
+		return nodes
+	}
+	return available
+}
+
+//record 记录调用结果,nodes 为所有的节点
+func (d *outlierDetector) record(node *Node, nodes []*Node, failed bool) {
+	if d == nil {
+		return
+	}
+	now := time.Now()
+	if node.ejected(now) {
+		return
+	}
+	if !failed {
+		node.outlier.consecutiveErrors = 0
+		return
+	}
+	node.outlier.consecutiveErrors++
+	if node.outlier.consecutiveErrors >= d.config.getConsecutiveErrors() {
+		d.eject(node, nodes, now)
+	}
+}
+
+//observed 记录延迟之后调用,检查节点的延迟是否异常
+func (d *outlierDetector) observed(node *Node, nodes []*Node) {
+	if d == nil {
+		return
+	}
+	now := time.Now()
+	if !node.ejected(now) && d.isSlow(node, nodes) {
+		d.eject(node, nodes, now)
+	}
+}
+
+//isSlow 节点的 EWMA 延迟是否超过其他节点平均值的 LatencyFactor 倍
+func (d *outlierDetector) isSlow(node *Node, nodes []*Node) bool {
+	if d.config.LatencyFactor <= 0 || node.ewma == 0 {
+		return false
+	}
+	var total float64
+	var count int
+	for _, other := range nodes {
+		if other != node && other.ewma > 0 {
+			total += other.ewma
+			count++
+		}
+	}
+	if count+1 < latencyMinNodes {
+		return false
+	}
+	return node.ewma > total/float64(count)*d.config.LatencyFactor
+}
+
+func (d *outlierDetector) eject(node *Node, nodes []*Node, now time.Time) {
+	var ejected int
+	for _, other := range nodes {
+		if other.ejected(now) {
+			ejected++
+		}
+	}
+	if (ejected+1)*100 > len(nodes)*d.config.getMaxEjectionPercent() {
+		return
+	}
+	maxEjectionTime := d.config.getMaxEjectionTime()
+	if !node.outlier.lastEjectedUntil.IsZero() && now.Sub(node.outlier.lastEjectedUntil) > maxEjectionTime {
+		node.outlier.ejections = 0
+	}
+	node.outlier.ejections++
+	ejectionTime := d.config.getBaseEjectionTime() * time.Duration(node.outlier.ejections)
+	if ejectionTime > maxEjectionTime {
+		ejectionTime = maxEjectionTime
+	}
+	node.outlier.ejectedUntil = now.Add(ejectionTime)
+	node.outlier.consecutiveErrors = 0
+	//延迟异常的节点恢复后重新计算延迟
+	node.ewma = 0
+	node.lastUpdate = time.Time{}
+}
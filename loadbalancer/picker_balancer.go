@@ -0,0 +1,404 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coffeehc/logger"
+	"github.com/coffeehc/microserviceboot/base"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/naming"
+)
+
+const (
+	//PolicyRoundRobin 轮询,默认的策略
+	PolicyRoundRobin = "round_robin"
+	//PolicyWeightedRoundRobin 按照元数据中的 weight 平滑加权轮询
+	PolicyWeightedRoundRobin = "weighted_round_robin"
+	//PolicyLeastRequest 选择进行中请求数最少的节点
+	PolicyLeastRequest = "least_request"
+	//PolicyP2CEWMA 随机选择两个节点,选择 EWMA 延迟与进行中请求数综合代价较小的节点
+	PolicyP2CEWMA = "p2c_ewma"
+	//PolicyConsistentHash 根据 WithHashKey 指定的 key 一致性哈希,相同的 key 选择相同的节点
+	PolicyConsistentHash = "consistent_hash"
+)
+
+//Picker 负载均衡的选择策略,Pick 在 Balancer 的锁内调用,ctx 为调用的 context,nodes 为已连接的节点,不为空
+type Picker interface {
+	Pick(ctx context.Context, nodes []*Node) *Node
+}
+
+//NodeSetAware Picker 可选实现的接口,节点变化时获得所有的节点,包括未连接的节点
+type NodeSetAware interface {
+	SetNodes(nodes []*Node)
+}
+
+//PickerBuilder 创建 Picker,每个 Balancer 使用独立的 Picker
+type PickerBuilder func() Picker
+
+var (
+	pickerMutex    = new(sync.RWMutex)
+	pickerBuilders = map[string]PickerBuilder{
+		PolicyWeightedRoundRobin: newWeightedRoundRobinPicker,
+		PolicyLeastRequest:       newLeastRequestPicker,
+		PolicyP2CEWMA:            newP2CEWMAPicker,
+		PolicyConsistentHash:     newConsistentHashPicker,
+	}
+)
+
+//RegisterPicker 注册自定义的负载均衡策略
+func RegisterPicker(policy string, builder PickerBuilder) base.Error {
+	pickerMutex.Lock()
+	defer pickerMutex.Unlock()
+	if _, ok := pickerBuilders[policy]; ok || policy == PolicyRoundRobin {
+		return base.NewError(base.Error_System, errScopeBalance, fmt.Sprintf("policy %s 已经存在", policy))
+	}
+	pickerBuilders[policy] = builder
+	return nil
+}
+
+//NewBalancer 根据 policy 创建 Balancer,policy 为空时使用轮询,使用默认配置的被动健康检查
+func NewBalancer(policy string, r naming.Resolver) (Balancer, base.Error) {
+	picker, err := newPicker(policy)
+	if err != nil {
+		return nil, err
+	}
+	return NewPickerBalancer(r, picker), nil
+}
+
+func newPicker(policy string) (Picker, base.Error) {
+	if policy == "" || policy == PolicyRoundRobin {
+		return newRoundRobinPicker(), nil
+	}
+	pickerMutex.RLock()
+	builder, ok := pickerBuilders[policy]
+	pickerMutex.RUnlock()
+	if !ok {
+		return nil, base.NewError(base.Error_System, errScopeBalance, fmt.Sprintf("不支持的负载均衡策略 %s", policy))
+	}
+	return builder(), nil
+}
+
+//NewContextBalancer 根据 cxt 中 WithPolicy,WithZoneAware 与 WithOutlierDetection 的配置创建 Balancer
+func NewContextBalancer(cxt context.Context, r naming.Resolver) (Balancer, base.Error) {
+	picker, err := newPicker(GetPolicy(cxt))
+	if err != nil {
+		return nil, err
+	}
+	if zoneAware := GetZoneAware(cxt); zoneAware != nil {
+		picker = NewZoneAwarePicker(zoneAware, picker)
+	}
+	return &pickerBalancer{r: r, picker: picker, outlier: newOutlierDetector(GetOutlierDetection(cxt))}, nil
+}
+
+//HasPolicy 是否支持 policy 对应的负载均衡策略
+func HasPolicy(policy string) bool {
+	if policy == "" || policy == PolicyRoundRobin {
+		return true
+	}
+	pickerMutex.RLock()
+	defer pickerMutex.RUnlock()
+	_, ok := pickerBuilders[policy]
+	return ok
+}
+
+type policyKey struct{}
+
+//WithPolicy 指定创建 Balancer 时使用的负载均衡策略,用于 BalancerBuilder.Build 的 cxt
+func WithPolicy(cxt context.Context, policy string) context.Context {
+	return context.WithValue(cxt, policyKey{}, policy)
+}
+
+//GetPolicy 获取 cxt 中指定的负载均衡策略,没有指定时返回空
+func GetPolicy(cxt context.Context) string {
+	if cxt == nil {
+		return ""
+	}
+	policy, _ := cxt.Value(policyKey{}).(string)
+	return policy
+}
+
+//Node 负载均衡中的一个节点,记录进行中的请求数与 EWMA 延迟
+type Node struct {
+	Address     Address
+	Metadata    NodeMetadata
+	connected   bool
+	outstanding int64
+	ewma        float64
+	lastUpdate  time.Time
+	outlier     outlierState
+}
+
+//Outstanding 进行中的请求数
+func (n *Node) Outstanding() int64 {
+	return n.outstanding
+}
+
+//EWMA 指数加权的平均延迟(纳秒),没有数据时为0
+func (n *Node) EWMA() float64 {
+	return n.ewma
+}
+
+//NewPickerBalancer 使用 picker 创建 Balancer,地址的管理与 RoundRobin 相同,使用默认配置的被动健康检查
+func NewPickerBalancer(r naming.Resolver, picker Picker) Balancer {
+	return &pickerBalancer{r: r, picker: picker, outlier: newOutlierDetector(nil)}
+}
+
+type pickerBalancer struct {
+	r       naming.Resolver
+	w       naming.Watcher
+	picker  Picker
+	outlier *outlierDetector
+	nodes   []*Node
+	mu      sync.Mutex
+	addrCh  chan []Address
+	waitCh  chan struct{}
+	done    bool
+}
+
+func (pb *pickerBalancer) watchAddrUpdates() error {
+	updates, err := pb.w.Next()
+	if err != nil {
+		logger.Error("the naming watcher stops working due to %v.\n", err)
+		return err
+	}
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	for _, update := range updates {
+		addr := Address{
+			Addr:     update.Addr,
+			Metadata: update.Metadata,
+		}
+		switch update.Op {
+		case naming.Add:
+			if node := pb.findNode(addr.Addr); node != nil {
+				//元数据变化
+				node.Address = addr
+				node.Metadata = getNodeMetadata(addr)
+				continue
+			}
+			pb.nodes = append(pb.nodes, &Node{Address: addr, Metadata: getNodeMetadata(addr)})
+		case naming.Delete:
+			for i, node := range pb.nodes {
+				if node.Address.Addr == addr.Addr {
+					copy(pb.nodes[i:], pb.nodes[i+1:])
+					pb.nodes = pb.nodes[:len(pb.nodes)-1]
+					break
+				}
+			}
+		default:
+			logger.Error("Unknown update.Op %d", update.Op)
+		}
+	}
+	open := make([]Address, len(pb.nodes))
+	for i, node := range pb.nodes {
+		open[i] = node.Address
+	}
+	pb.notifyNodes()
+	if pb.done {
+		return errClientConnClosing
+	}
+	pb.addrCh <- open
+	return nil
+}
+
+//notifyNodes 在锁内调用,节点变化时通知实现了 NodeSetAware 的 Picker
+func (pb *pickerBalancer) notifyNodes() {
+	if nodeSetAware, ok := pb.picker.(NodeSetAware); ok {
+		nodes := make([]*Node, len(pb.nodes))
+		copy(nodes, pb.nodes)
+		nodeSetAware.SetNodes(nodes)
+	}
+}
+
+func (pb *pickerBalancer) findNode(addr string) *Node {
+	for _, node := range pb.nodes {
+		if node.Address.Addr == addr {
+			return node
+		}
+	}
+	return nil
+}
+
+func (pb *pickerBalancer) Start(target string, config BalancerConfig) error {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	if pb.done {
+		return errClientConnClosing
+	}
+	if pb.r == nil {
+		pb.nodes = append(pb.nodes, &Node{Address: Address{Addr: target}, Metadata: NodeMetadata{Weight: defaultWeight}})
+		pb.notifyNodes()
+		return nil
+	}
+	w, err := pb.r.Resolve(target)
+	if err != nil {
+		return err
+	}
+	pb.w = w
+	pb.addrCh = make(chan []Address)
+	go func() {
+		for {
+			if err := pb.watchAddrUpdates(); err != nil {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (pb *pickerBalancer) Up(addr Address) func(error) {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	var cnt int
+	for _, node := range pb.nodes {
+		if node.Address.Addr == addr.Addr {
+			if node.connected {
+				return nil
+			}
+			node.connected = true
+		}
+		if node.connected {
+			cnt++
+		}
+	}
+	if cnt == 1 && pb.waitCh != nil {
+		close(pb.waitCh)
+		pb.waitCh = nil
+	}
+	return func(err error) {
+		pb.down(addr, err)
+	}
+}
+
+func (pb *pickerBalancer) down(addr Address, err error) {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	if node := pb.findNode(addr.Addr); node != nil {
+		node.connected = false
+	}
+}
+
+//pick 在锁内调用,返回 nil 表示没有可用的节点
+func (pb *pickerBalancer) pick(ctx context.Context) *Node {
+	connected := make([]*Node, 0, len(pb.nodes))
+	for _, node := range pb.nodes {
+		if node.connected {
+			connected = append(connected, node)
+		}
+	}
+	if len(connected) == 0 {
+		return nil
+	}
+	node := pb.picker.Pick(ctx, filterExcluded(ctx, pb.outlier.filter(connected)))
+	if node == nil {
+		return nil
+	}
+	if group := GetAddrGroup(ctx); group != nil {
+		group.add(node.Address.Addr)
+	}
+	node.outstanding++
+	return node
+}
+
+func (pb *pickerBalancer) put(node *Node, start time.Time) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			pb.mu.Lock()
+			defer pb.mu.Unlock()
+			node.outstanding--
+			node.observe(time.Since(start))
+			pb.outlier.observed(node, pb.nodes)
+		})
+	}
+}
+
+//ReportResult implement ResultReporter interface
+func (pb *pickerBalancer) ReportResult(addr string, failed bool) {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	if node := pb.findNode(addr); node != nil {
+		pb.outlier.record(node, pb.nodes, failed)
+	}
+}
+
+//ReportLatency implement LatencyReporter interface
+func (pb *pickerBalancer) ReportLatency(addr string, latency time.Duration) {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	if node := pb.findNode(addr); node != nil {
+		node.observe(latency)
+		pb.outlier.observed(node, pb.nodes)
+	}
+}
+
+func (pb *pickerBalancer) Get(ctx context.Context, opts BalancerGetOptions) (addr Address, put func(), err error) {
+	pb.mu.Lock()
+	if pb.done {
+		pb.mu.Unlock()
+		err = errClientConnClosing
+		return
+	}
+	if node := pb.pick(ctx); node != nil {
+		pb.mu.Unlock()
+		return node.Address, pb.put(node, time.Now()), nil
+	}
+	if !opts.BlockingWait {
+		defer pb.mu.Unlock()
+		if len(pb.nodes) == 0 {
+			err = base.NewError(-1, errScopeBalance, "there is no address available")
+			return
+		}
+		//没有已连接的节点,交给 grpc 返回连接错误
+		return pb.nodes[0].Address, nil, nil
+	}
+	for {
+		if pb.waitCh == nil {
+			pb.waitCh = make(chan struct{})
+		}
+		ch := pb.waitCh
+		pb.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		case <-ch:
+		}
+		pb.mu.Lock()
+		if pb.done {
+			pb.mu.Unlock()
+			err = errClientConnClosing
+			return
+		}
+		if node := pb.pick(ctx); node != nil {
+			pb.mu.Unlock()
+			return node.Address, pb.put(node, time.Now()), nil
+		}
+	}
+}
+
+func (pb *pickerBalancer) Notify() <-chan []Address {
+	return pb.addrCh
+}
+
+func (pb *pickerBalancer) Close() error {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	if pb.done {
+		return nil
+	}
+	pb.done = true
+	if pb.w != nil {
+		pb.w.Close()
+	}
+	if pb.waitCh != nil {
+		close(pb.waitCh)
+		pb.waitCh = nil
+	}
+	if pb.addrCh != nil {
+		close(pb.addrCh)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package loadbalancer
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+//ewmaDecay EWMA 的衰减时间,越小对延迟的变化越敏感
+const ewmaDecay = float64(time.Second * 10)
+
+//observe 在 Balancer 的锁内调用,记录一次请求的延迟
+func (n *Node) observe(latency time.Duration) {
+	now := time.Now()
+	if n.lastUpdate.IsZero() {
+		n.ewma = float64(latency)
+		n.lastUpdate = now
+		return
+	}
+	w := math.Exp(-float64(now.Sub(n.lastUpdate)) / ewmaDecay)
+	n.ewma = n.ewma*w + float64(latency)*(1-w)
+	n.lastUpdate = now
+}
+
+//roundRobinPicker 轮询,用于需要包装 Picker 的 RoundRobin 策略
+type roundRobinPicker struct {
+	next int
+}
+
+func newRoundRobinPicker() Picker {
+	return &roundRobinPicker{}
+}
+
+func (p *roundRobinPicker) Pick(ctx context.Context, nodes []*Node) *Node {
+	p.next = (p.next + 1) % len(nodes)
+	return nodes[p.next]
+}
+
+//weightedRoundRobinPicker 平滑加权轮询,与 nginx 的算法相同
+type weightedRoundRobinPicker struct {
+	currentWeights map[string]int
+}
+
+func newWeightedRoundRobinPicker() Picker {
+	return &weightedRoundRobinPicker{currentWeights: make(map[string]int)}
+}
+
+func (p *weightedRoundRobinPicker) Pick(ctx context.Context, nodes []*Node) *Node {
+	if len(p.currentWeights) > len(nodes)*2 {
+		p.currentWeights = make(map[string]int, len(nodes))
+	}
+	var best *Node
+	var bestWeight, total int
+	for _, node := range nodes {
+		weight := nodeWeight(node)
+		total += weight
+		current := p.currentWeights[node.Address.Addr] + weight
+		p.currentWeights[node.Address.Addr] = current
+		if best == nil || current > bestWeight {
+			best, bestWeight = node, current
+		}
+	}
+	p.currentWeights[best.Address.Addr] -= total
+	return best
+}
+
+//leastRequestPicker 选择进行中请求数最少的节点,请求数相同时轮流选择
+type leastRequestPicker struct {
+	next int
+}
+
+func newLeastRequestPicker() Picker {
+	return &leastRequestPicker{}
+}
+
+func (p *leastRequestPicker) Pick(ctx context.Context, nodes []*Node) *Node {
+	p.next = (p.next + 1) % len(nodes)
+	best := nodes[p.next]
+	for i := 1; i < len(nodes); i++ {
+		node := nodes[(p.next+i)%len(nodes)]
+		if node.outstanding < best.outstanding {
+			best = node
+		}
+	}
+	return best
+}
+
+//p2cEWMAPicker 随机选择两个节点,代价为 EWMA 延迟乘以进行中的请求数+1,没有延迟数据的节点优先
+type p2cEWMAPicker struct {
+	random *rand.Rand
+}
+
+func newP2CEWMAPicker() Picker {
+	return &p2cEWMAPicker{random: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+func (p *p2cEWMAPicker) Pick(ctx context.Context, nodes []*Node) *Node {
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+	i := p.random.Intn(len(nodes))
+	j := p.random.Intn(len(nodes) - 1)
+	if j >= i {
+		j++
+	}
+	a, b := nodes[i], nodes[j]
+	if p2cCost(b) < p2cCost(a) {
+		return b
+	}
+	return a
+}
+
+func p2cCost(node *Node) float64 {
+	return node.ewma * float64(node.outstanding+1)
+}
@@ -0,0 +1,148 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	. "gopkg.in/check.v1"
+)
+
+type PickerSuite struct {
+}
+
+var _ = Suite(&PickerSuite{})
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+func newTestNode(addr string, weight int) *Node {
+	return &Node{Address: Address{Addr: addr}, Metadata: NodeMetadata{Weight: weight}, connected: true}
+}
+
+func (t *PickerSuite) TestWeightedRoundRobin(c *C) {
+	nodes := []*Node{newTestNode("a", 5), newTestNode("b", 1), newTestNode("c", 1)}
+	picker := newWeightedRoundRobinPicker()
+	counts := make(map[string]int)
+	sequence := ""
+	for i := 0; i < 7; i++ {
+		addr := picker.Pick(context.Background(), nodes).Address.Addr
+		counts[addr]++
+		sequence += addr
+	}
+	c.Assert(counts, DeepEquals, map[string]int{"a": 5, "b": 1, "c": 1})
+	//平滑加权,权重大的节点不会连续被选中
+	c.Assert(sequence, Equals, "aabacaa")
+}
+
+func (t *PickerSuite) TestLeastRequest(c *C) {
+	nodes := []*Node{newTestNode("a", 1), newTestNode("b", 1), newTestNode("c", 1)}
+	nodes[0].outstanding = 3
+	nodes[1].outstanding = 1
+	nodes[2].outstanding = 2
+	picker := newLeastRequestPicker()
+	for i := 0; i < 3; i++ {
+		c.Assert(picker.Pick(context.Background(), nodes).Address.Addr, Equals, "b")
+	}
+}
+
+func (t *PickerSuite) TestP2CEWMA(c *C) {
+	nodes := []*Node{newTestNode("slow", 1), newTestNode("fast", 1)}
+	nodes[0].observe(time.Millisecond * 100)
+	nodes[1].observe(time.Millisecond)
+	picker := newP2CEWMAPicker()
+	for i := 0; i < 10; i++ {
+		c.Assert(picker.Pick(context.Background(), nodes).Address.Addr, Equals, "fast")
+	}
+}
+
+func (t *PickerSuite) TestParseNodeMetadata(c *C) {
+	c.Assert(ParseNodeMetadata(TagsToMetadata([]string{"v1", "weight=3"})), Equals, NodeMetadata{Weight: 3})
+	c.Assert(ParseNodeMetadata(map[string]string{"weight": "-1"}), Equals, NodeMetadata{Weight: defaultWeight})
+	c.Assert(MetadataToTags(map[string]string{"weight": "3", "a": "b"}), DeepEquals, []string{"a=b", "weight=3"})
+}
+
+func (t *PickerSuite) TestConsistentHash(c *C) {
+	nodes := []*Node{newTestNode("a:1", 1), newTestNode("b:1", 1), newTestNode("c:1", 1)}
+	picker := newConsistentHashPicker()
+	owners := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("user-%d", i)
+		owners[key] = picker.Pick(WithHashKey(context.Background(), key), nodes).Address.Addr
+		c.Assert(picker.Pick(WithHashKey(context.Background(), key), nodes).Address.Addr, Equals, owners[key])
+	}
+	//移除一个节点,只有属于该节点的 key 重新分配
+	remain := []*Node{nodes[0], nodes[2]}
+	for key, owner := range owners {
+		addr := picker.Pick(WithHashKey(context.Background(), key), remain).Address.Addr
+		if owner != "b:1" {
+			c.Assert(addr, Equals, owner)
+		} else {
+			c.Assert(addr, Not(Equals), "b:1")
+		}
+	}
+	//环由所有的节点构建,Pick 只在可用的节点中沿环选择,不会重建
+	picker.(NodeSetAware).SetNodes(nodes)
+	ring := len(picker.(*consistentHashPicker).hashes)
+	for key, owner := range owners {
+		addr := picker.Pick(WithHashKey(context.Background(), key), remain).Address.Addr
+		if owner != "b:1" {
+			c.Assert(addr, Equals, owner)
+		}
+		c.Assert(picker.Pick(WithHashKey(context.Background(), key), nodes).Address.Addr, Equals, owner)
+	}
+	c.Assert(picker.(*consistentHashPicker).hashes, HasLen, ring)
+}
+
+func (t *PickerSuite) TestZoneAwareSpillover(c *C) {
+	nodes := []*Node{newTestNode("a1", 1), newTestNode("a2", 1), newTestNode("b1", 1)}
+	nodes[0].Metadata.Zone, nodes[1].Metadata.Zone, nodes[2].Metadata.Zone = "a", "a", "b"
+	picker := NewZoneAwarePicker(&ZoneAwareConfig{Zone: "a", MinHealthyRatio: 0.5}, newRoundRobinPicker())
+	picker.(NodeSetAware).SetNodes(nodes)
+	for i := 0; i < 4; i++ {
+		c.Assert(picker.Pick(context.Background(), nodes).Metadata.Zone, Equals, "a")
+	}
+	//本区域只剩一半的容量,仍然在本区域
+	c.Assert(picker.Pick(context.Background(), []*Node{nodes[1], nodes[2]}).Address.Addr, Equals, "a2")
+	//本区域没有可用的节点,溢出到其他区域
+	c.Assert(picker.Pick(context.Background(), []*Node{nodes[2]}).Address.Addr, Equals, "b1")
+	//低于阈值时在所有节点中选择
+	picker = NewZoneAwarePicker(&ZoneAwareConfig{Zone: "a", MinHealthyRatio: 0.8}, newRoundRobinPicker())
+	picker.(NodeSetAware).SetNodes(nodes)
+	zones := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		zones[picker.Pick(context.Background(), []*Node{nodes[1], nodes[2]}).Metadata.Zone] = true
+	}
+	c.Assert(zones, DeepEquals, map[string]bool{"a": true, "b": true})
+}
+
+func (t *PickerSuite) TestOutlierEjection(c *C) {
+	nodes := []*Node{newTestNode("a", 1), newTestNode("b", 1), newTestNode("c", 1)}
+	pb := &pickerBalancer{
+		picker:  newRoundRobinPicker(),
+		outlier: newOutlierDetector(&OutlierConfig{ConsecutiveErrors: 2, BaseEjectionTime: time.Millisecond * 50}),
+		nodes:   nodes,
+	}
+	pb.ReportResult("a", true)
+	pb.ReportResult("a", false)
+	pb.ReportResult("a", true)
+	c.Assert(nodes[0].ejected(time.Now()), Equals, false)
+	pb.ReportResult("a", true)
+	c.Assert(nodes[0].ejected(time.Now()), Equals, true)
+	for i := 0; i < 4; i++ {
+		c.Assert(pb.pick(context.Background()).Address.Addr, Not(Equals), "a")
+	}
+	//最多摘除一半的节点
+	pb.ReportResult("b", true)
+	pb.ReportResult("b", true)
+	c.Assert(nodes[1].ejected(time.Now()), Equals, false)
+	//到期后自动恢复,再次摘除的时间增长
+	time.Sleep(time.Millisecond * 60)
+	c.Assert(nodes[0].ejected(time.Now()), Equals, false)
+	pb.ReportResult("a", true)
+	pb.ReportResult("a", true)
+	c.Assert(nodes[0].outlier.ejections, Equals, 2)
+	c.Assert(nodes[0].outlier.ejectedUntil.Sub(time.Now()) > time.Millisecond*60, Equals, true)
+}
@@ -1,5 +0,0 @@
-package loadbalancer
-
-type NodeDown interface {
-	Delete(addr Address)
-}
@@ -0,0 +1,101 @@
+package loadbalancer
+
+import (
+	"golang.org/x/net/context"
+)
+
+const defaultMinHealthyRatio = 0.7
+
+//ZoneAwareConfig 同区域优先的配置,客户端优先选择同一可用区的节点,其次是同一区域的节点
+type ZoneAwareConfig struct {
+	//Zone 客户端所在的可用区
+	Zone string `yaml:"zone"`
+	//Region 客户端所在的区域
+	Region string `yaml:"region"`
+	//MinHealthyRatio 本地已连接节点的权重占本地所有节点权重的比例低于该值时溢出到更大的范围,默认0.7
+	MinHealthyRatio float64 `yaml:"min_healthy_ratio" validate:"min=0,max=1"`
+}
+
+func (config *ZoneAwareConfig) getMinHealthyRatio() float64 {
+	if config.MinHealthyRatio <= 0 {
+		return defaultMinHealthyRatio
+	}
+	return config.MinHealthyRatio
+}
+
+type zoneAwareKey struct{}
+
+//WithZoneAware 指定创建 Balancer 时使用同区域优先,用于 BalancerBuilder.Build 的 cxt
+func WithZoneAware(cxt context.Context, config *ZoneAwareConfig) context.Context {
+	return context.WithValue(cxt, zoneAwareKey{}, config)
+}
+
+//GetZoneAware 获取 cxt 中的同区域优先配置,没有指定时返回 nil
+func GetZoneAware(cxt context.Context) *ZoneAwareConfig {
+	if cxt == nil {
+		return nil
+	}
+	config, _ := cxt.Value(zoneAwareKey{}).(*ZoneAwareConfig)
+	return config
+}
+
+//NewZoneAwarePicker 包装 picker,只在本地的节点中选择,本地健康的容量不足时溢出到其他区域
+func NewZoneAwarePicker(config *ZoneAwareConfig, picker Picker) Picker {
+	return &zoneAwarePicker{config: config, picker: picker}
+}
+
+type zoneAwarePicker struct {
+	config *ZoneAwareConfig
+	picker Picker
+	nodes  []*Node
+}
+
+//SetNodes implement NodeSetAware interface
+func (p *zoneAwarePicker) SetNodes(nodes []*Node) {
+	p.nodes = nodes
+	if nodeSetAware, ok := p.picker.(NodeSetAware); ok {
+		nodeSetAware.SetNodes(nodes)
+	}
+}
+
+func (p *zoneAwarePicker) Pick(ctx context.Context, nodes []*Node) *Node {
+	if p.config.Zone != "" {
+		if local := p.localNodes(nodes, func(metadata NodeMetadata) bool { return metadata.Zone == p.config.Zone }); local != nil {
+			return p.picker.Pick(ctx, local)
+		}
+	}
+	if p.config.Region != "" {
+		if local := p.localNodes(nodes, func(metadata NodeMetadata) bool { return metadata.Region == p.config.Region }); local != nil {
+			return p.picker.Pick(ctx, local)
+		}
+	}
+	return p.picker.Pick(ctx, nodes)
+}
+
+//localNodes 返回本地已连接的节点,本地健康的容量低于 MinHealthyRatio 时返回 nil
+func (p *zoneAwarePicker) localNodes(connected []*Node, isLocal func(metadata NodeMetadata) bool) []*Node {
+	var totalWeight, healthyWeight int
+	for _, node := range p.nodes {
+		if isLocal(node.Metadata) {
+			totalWeight += nodeWeight(node)
+		}
+	}
+	local := make([]*Node, 0, len(connected))
+	for _, node := range connected {
+		if isLocal(node.Metadata) {
+			healthyWeight += nodeWeight(node)
+			local = append(local, node)
+		}
+	}
+	if len(local) == 0 || float64(healthyWeight) < float64(totalWeight)*p.config.getMinHealthyRatio() {
+		return nil
+	}
+	return local
+}
+
+func nodeWeight(node *Node) int {
+	if node.Metadata.Weight <= 0 {
+		return defaultWeight
+	}
+	return node.Metadata.Weight
+}
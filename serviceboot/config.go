@@ -2,17 +2,75 @@ package serviceboot
 
 import (
 	"fmt"
+	"net"
+	"time"
 
 	"github.com/coffeehc/httpx"
 	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/discovery"
+	"github.com/coffeehc/microserviceboot/loadbalancer"
+	"github.com/coffeehc/microserviceboot/serviceboot/ratelimit"
+	"github.com/coffeehc/microserviceboot/tracing"
 )
 
 // ServiceConfig 服务配置
 type ServiceConfig struct {
-	ServiceInfo            *base.SimpleServiceInfo `yaml:"service_info"`
+	ServiceInfo            *base.SimpleServiceInfo `yaml:"service_info" validate:"required"`
 	EnableAccessInfo       bool                    `yaml:"enableAccessInfo"`
 	DisableServiceRegister bool                    `yaml:"disable_service_register"`
 	HTTPConfig             *httpx.Config           `yaml:"http_config"`
+	ShutdownConfig         *ShutdownConfig         `yaml:"shutdown_config"`
+	//Discovery 服务发现的配置,没有配置时使用 Service 的 GetServiceDiscoveryRegister
+	Discovery *discovery.Config `yaml:"discovery"`
+	//Metadata 注册到服务发现中心的节点元数据,如 weight
+	Metadata map[string]string `yaml:"metadata"`
+	//Zone 服务所在的可用区,注册为元数据 zone,用于客户端同区域优先
+	Zone string `yaml:"zone"`
+	//Region 服务所在的区域,注册为元数据 region
+	Region string `yaml:"region"`
+	//RateLimit 服务端的限流与并发限制,没有配置时不限制
+	RateLimit *ratelimit.Config `yaml:"rate_limit"`
+	//Tracing 链路追踪的配置,没有配置时只传递 trace context,不导出 span
+	Tracing *tracing.Config `yaml:"tracing"`
+	//MinRequestTimeout 调用方传递的剩余时间小于该值时直接返回 Error_System_DeadlineTooShort,默认0只拒绝剩余时间已经用完的请求
+	MinRequestTimeout time.Duration `yaml:"min_request_timeout" validate:"min=0s"`
+}
+
+//ShutdownConfig 优雅关闭的配置
+type ShutdownConfig struct {
+	//PropagationDelay 注销服务后等待注销信息传播到各个客户端的时间,默认3s,小于0表示不等待
+	PropagationDelay time.Duration `yaml:"propagation_delay"`
+	//DrainTimeout 等待进行中的请求完成的最长时间,默认10s,小于0表示不等待
+	DrainTimeout time.Duration `yaml:"drain_timeout"`
+}
+
+//Validate 实现 base.ConfigValidator,校验 httpx.Config 中无法使用 tag 描述的配置
+func (sc *ServiceConfig) Validate() base.Error {
+	if sc.HTTPConfig == nil || sc.HTTPConfig.ServerAddr == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(sc.HTTPConfig.ServerAddr); err != nil {
+		return base.NewValidationError(&base.FieldViolation{
+			Field:   "http_config.server_addr",
+			Message: fmt.Sprintf("%q 不是合法的 host:port 地址", sc.HTTPConfig.ServerAddr),
+		})
+	}
+	return nil
+}
+
+//GetMetadata 获取注册到服务发现中心的元数据,包含 Zone 与 Region
+func (sc *ServiceConfig) GetMetadata() map[string]string {
+	metadata := make(map[string]string, len(sc.Metadata)+2)
+	for key, value := range sc.Metadata {
+		metadata[key] = value
+	}
+	if sc.Zone != "" {
+		metadata[loadbalancer.MetadataZone] = sc.Zone
+	}
+	if sc.Region != "" {
+		metadata[loadbalancer.MetadataRegion] = sc.Region
+	}
+	return metadata
 }
 
 //GetHTTPServerConfig 获取 HTTP config
@@ -30,3 +88,17 @@ func (sc *ServiceConfig) GetHTTPServerConfig() (*httpx.Config, base.Error) {
 	sc.HTTPConfig.DefaultRender = httpx.DefaultRenderJSON
 	return sc.HTTPConfig, nil
 }
+
+//GetShutdownConfig 获取优雅关闭的配置,没有配置则使用默认值
+func (sc *ServiceConfig) GetShutdownConfig() *ShutdownConfig {
+	if sc.ShutdownConfig == nil {
+		sc.ShutdownConfig = new(ShutdownConfig)
+	}
+	if sc.ShutdownConfig.PropagationDelay == 0 {
+		sc.ShutdownConfig.PropagationDelay = 3 * time.Second
+	}
+	if sc.ShutdownConfig.DrainTimeout == 0 {
+		sc.ShutdownConfig.DrainTimeout = 10 * time.Second
+	}
+	return sc.ShutdownConfig
+}
@@ -0,0 +1,91 @@
+package serviceboot
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/coffeehc/logger"
+	"github.com/coffeehc/microserviceboot/base"
+)
+
+const errScopeDynamicConfig = "dynamic config"
+
+type dynamicConfigWatcher struct {
+	service base.DynamicConfigService
+	sources []base.DynamicConfigSource
+	mutex   *sync.Mutex
+	current interface{}
+}
+
+//startDynamicConfig 如果 service 实现了 base.DynamicConfigService,加载初始的动态配置并开始监听变化
+func startDynamicConfig(cxt context.Context, microService MicroService) base.Error {
+	service, ok := microService.GetService().(base.DynamicConfigService)
+	if !ok {
+		return nil
+	}
+	sources, err := service.GetDynamicConfigSources()
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 {
+		return nil
+	}
+	watcher := &dynamicConfigWatcher{
+		service: service,
+		sources: sources,
+		mutex:   new(sync.Mutex),
+	}
+	err = watcher.reload()
+	if err != nil {
+		return err
+	}
+	watchCxt, cancel := context.WithCancel(cxt)
+	microService.AddCleanFunc(cancel)
+	for _, source := range sources {
+		err = source.Watch(watchCxt, watcher.onChange)
+		if err != nil {
+			cancel()
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *dynamicConfigWatcher) onChange() {
+	err := w.reload()
+	if err != nil {
+		logger.Error("动态配置没有生效,继续使用之前的配置:%s", err)
+	}
+}
+
+//reload 重新加载所有的配置来源,校验失败或者被 Service 拒绝时保持之前的配置
+func (w *dynamicConfigWatcher) reload() (err base.Error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	sources := make([]base.ConfigSource, 0, len(w.sources))
+	for _, source := range w.sources {
+		sources = append(sources, source)
+	}
+	config := w.service.NewDynamicConfig()
+	err = base.LoadConfigFromSources(config, sources...)
+	if err != nil {
+		return err
+	}
+	err = base.ValidateConfig(config)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = base.NewError(base.Error_System, errScopeDynamicConfig, fmt.Sprintf("OnConfigChange panic :%v", r))
+		}
+	}()
+	err = w.service.OnConfigChange(w.current, config)
+	if err != nil {
+		return err
+	}
+	w.current = config
+	logger.Info("动态配置已更新")
+	return nil
+}
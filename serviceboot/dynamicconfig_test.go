@@ -0,0 +1,130 @@
+package serviceboot
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/coffeehc/microserviceboot/base"
+	. "gopkg.in/check.v1"
+	"gopkg.in/yaml.v2"
+)
+
+type DynamicConfigSuite struct {
+}
+
+var _ = Suite(&DynamicConfigSuite{})
+
+type testDynamicConfig struct {
+	Limit int `yaml:"limit" validate:"min=1"`
+}
+
+//testDynamicSource 从内存中的 yaml 读取配置,Watch 只记录 onChange
+type testDynamicSource struct {
+	data     string
+	onChange func()
+}
+
+func (s *testDynamicSource) Apply(config interface{}) base.Error {
+	err := yaml.Unmarshal([]byte(s.data), config)
+	if err != nil {
+		return base.NewError(base.Error_System, "test", err.Error())
+	}
+	return nil
+}
+
+func (s *testDynamicSource) Watch(cxt context.Context, onChange func()) base.Error {
+	s.onChange = onChange
+	return nil
+}
+
+type testDynamicService struct {
+	base.ConfigChangeListener
+	source  *testDynamicSource
+	changes int
+	reject  bool
+	panic   bool
+}
+
+func (s *testDynamicService) GetDynamicConfigSources() ([]base.DynamicConfigSource, base.Error) {
+	return []base.DynamicConfigSource{s.source}, nil
+}
+
+func (s *testDynamicService) NewDynamicConfig() interface{} {
+	return new(testDynamicConfig)
+}
+
+func (s *testDynamicService) onConfigChange(old, new *testDynamicConfig) base.Error {
+	if s.panic {
+		panic("onConfigChange")
+	}
+	if s.reject {
+		return base.NewError(base.Error_System, "test", "reject")
+	}
+	s.changes++
+	return nil
+}
+
+func (suite *DynamicConfigSuite) TestReload(c *C) {
+	service := &testDynamicService{source: &testDynamicSource{data: "limit: 10"}}
+	listener, err := base.ConfigChangeFunc(service.onConfigChange)
+	c.Assert(err, IsNil)
+	service.ConfigChangeListener = listener
+	watcher := &dynamicConfigWatcher{
+		service: service,
+		sources: []base.DynamicConfigSource{service.source},
+		mutex:   new(sync.Mutex),
+	}
+	c.Assert(watcher.reload(), IsNil)
+	c.Assert(service.changes, Equals, 1)
+	c.Assert(watcher.current.(*testDynamicConfig).Limit, Equals, 10)
+
+	service.source.data = "limit: 20"
+	c.Assert(watcher.reload(), IsNil)
+	c.Assert(service.changes, Equals, 2)
+	c.Assert(watcher.current.(*testDynamicConfig).Limit, Equals, 20)
+
+	//校验失败
+	service.source.data = "limit: 0"
+	c.Assert(watcher.reload(), NotNil)
+	c.Assert(watcher.current.(*testDynamicConfig).Limit, Equals, 20)
+
+	//解析失败
+	service.source.data = "limit: ["
+	c.Assert(watcher.reload(), NotNil)
+	c.Assert(watcher.current.(*testDynamicConfig).Limit, Equals, 20)
+
+	//Service 拒绝
+	service.source.data = "limit: 30"
+	service.reject = true
+	c.Assert(watcher.reload(), NotNil)
+	c.Assert(watcher.current.(*testDynamicConfig).Limit, Equals, 20)
+
+	//Service panic
+	service.reject = false
+	service.panic = true
+	c.Assert(watcher.reload(), NotNil)
+	c.Assert(watcher.current.(*testDynamicConfig).Limit, Equals, 20)
+
+	//恢复后使用最新的配置
+	service.panic = false
+	watcher.onChange()
+	c.Assert(service.changes, Equals, 3)
+	c.Assert(watcher.current.(*testDynamicConfig).Limit, Equals, 30)
+}
+
+func (suite *DynamicConfigSuite) TestConfigChangeFunc(c *C) {
+	_, err := base.ConfigChangeFunc(func(old *testDynamicConfig, new int) base.Error { return nil })
+	c.Assert(err, NotNil)
+	_, err = base.ConfigChangeFunc(func(old, new *testDynamicConfig) error { return errors.New("") })
+	c.Assert(err, NotNil)
+	_, err = base.ConfigChangeFunc(nil)
+	c.Assert(err, NotNil)
+	var nilFunc func(old, new *testDynamicConfig) base.Error
+	_, err = base.ConfigChangeFunc(nilFunc)
+	c.Assert(err, NotNil)
+	listener, err := base.ConfigChangeFunc(func(old, new *testDynamicConfig) base.Error { return nil })
+	c.Assert(err, IsNil)
+	c.Assert(listener.OnConfigChange(nil, &testDynamicConfig{}), IsNil)
+	c.Assert(listener.OnConfigChange(nil, &struct{}{}), NotNil)
+}
@@ -5,16 +5,15 @@ import (
 
 	"github.com/coffeehc/microserviceboot/base"
 	"github.com/coffeehc/microserviceboot/serviceboot"
-	"github.com/grpc-ecosystem/go-grpc-prometheus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/keepalive"
 )
 
 //Config grpcboot config
 type Config struct {
-	ServiceConfig *serviceboot.ServiceConfig `yaml:"service_config"`
+	ServiceConfig *serviceboot.ServiceConfig `yaml:"service_config" validate:"required"`
 	GRPCConfig    struct {
-		MaxMsgSize           int    `yaml:"max_msg_size"`
+		MaxMsgSize           int    `yaml:"max_msg_size" validate:"min=0"`
 		MaxConcurrentStreams uint32 `yaml:"max_concurrent_streams"`
 	} `yaml:"grpc_config"`
 }
@@ -22,17 +21,10 @@ type Config struct {
 //GetGRPCOptions 获取 GRPCOption
 func (config *Config) GetGRPCOptions() []grpc.ServerOption {
 	config.initGRPCConfig()
-	grpc.EnableTracing = false
-	if base.IsDevModule() {
-		grpc.EnableTracing = true
-		AppendUnaryServerInterceptor("logger", loggingInterceptor)
-	}
-	AppendUnaryServerInterceptor("prometheus", grpc_prometheus.UnaryServerInterceptor)
+	grpc.EnableTracing = base.IsDevModule()
 	return []grpc.ServerOption{
 		grpc.MaxConcurrentStreams(config.GRPCConfig.MaxConcurrentStreams),
 		grpc.MaxMsgSize(config.GRPCConfig.MaxMsgSize),
-		grpc.StreamInterceptor(grpc_prometheus.StreamServerInterceptor),
-		grpc.UnaryInterceptor(_unaryServerInterceptor.Interceptor),
 		grpc.RPCCompressor(grpc.NewGZIPCompressor()),
 		grpc.RPCDecompressor(grpc.NewGZIPDecompressor()),
 		grpc.KeepaliveParams(keepalive.ServerParameters{
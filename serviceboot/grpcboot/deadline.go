@@ -0,0 +1,44 @@
+package grpcboot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coffeehc/microserviceboot/base"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+const errScopeDeadline = "grpc deadline"
+
+//checkDeadline 调用方设置了 deadline 且剩余时间已经用完或者小于 minTimeout 时返回 Error_System_DeadlineTooShort
+func checkDeadline(ctx context.Context, minTimeout time.Duration) base.Error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	timeout := deadline.Sub(time.Now())
+	if timeout <= 0 || timeout < minTimeout {
+		return base.NewError(base.Error_System_DeadlineTooShort, errScopeDeadline, fmt.Sprintf("请求的剩余时间 %s 不足以完成调用", timeout))
+	}
+	return nil
+}
+
+//newDeadlineInterceptor 拒绝剩余时间不足的调用,不执行注定超时的请求
+func newDeadlineInterceptor(minTimeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := checkDeadline(ctx, minTimeout); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func newStreamDeadlineInterceptor(minTimeout time.Duration) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := checkDeadline(ss.Context(), minTimeout); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
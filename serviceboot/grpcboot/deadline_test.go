@@ -0,0 +1,42 @@
+package grpcboot
+
+import (
+	"time"
+
+	"github.com/coffeehc/microserviceboot/base"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	. "gopkg.in/check.v1"
+)
+
+type DeadlineSuite struct {
+}
+
+var _ = Suite(&DeadlineSuite{})
+
+func (t *DeadlineSuite) TestDeadlineInterceptor(c *C) {
+	interceptor := newDeadlineInterceptor(time.Millisecond * 100)
+	calls := 0
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return nil, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Get"}
+	_, err := interceptor(context.Background(), nil, info, handler)
+	c.Assert(err, IsNil)
+	cxt, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = interceptor(cxt, nil, info, handler)
+	c.Assert(err, IsNil)
+	c.Assert(calls, Equals, 2)
+
+	shortCxt, shortCancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer shortCancel()
+	_, err = interceptor(shortCxt, nil, info, handler)
+	c.Assert(base.IsDeadlineTooShortError(err), Equals, true)
+	expiredCxt, expiredCancel := context.WithTimeout(context.Background(), -time.Millisecond)
+	defer expiredCancel()
+	_, err = newDeadlineInterceptor(0)(expiredCxt, nil, info, handler)
+	c.Assert(base.IsDeadlineTooShortError(err), Equals, true)
+	c.Assert(calls, Equals, 2)
+}
@@ -0,0 +1,88 @@
+package grpcboot
+
+import (
+	"testing"
+
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/base/grpcbase"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+type InterceptorSuite struct {
+}
+
+var _ = Suite(&InterceptorSuite{})
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+func recordInterceptor(name string, calls *[]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		*calls = append(*calls, name)
+		return handler(ctx, req)
+	}
+}
+
+func (t *InterceptorSuite) TestUnaryChainOrder(c *C) {
+	calls := make([]string, 0)
+	chain := newUnaryServerInterceptor()
+	c.Assert(chain.AppendInterceptor("a", recordInterceptor("a", &calls)), IsNil)
+	c.Assert(chain.AppendInterceptor("c", recordInterceptor("c", &calls)), IsNil)
+	c.Assert(chain.InsertBefore("c", "b", recordInterceptor("b", &calls)), IsNil)
+	c.Assert(chain.InsertAfter("c", "d", recordInterceptor("d", &calls)), IsNil)
+	c.Assert(chain.AppendInterceptor("a", recordInterceptor("a", &calls)), NotNil)
+	c.Assert(chain.InsertBefore("x", "e", recordInterceptor("e", &calls)), NotNil)
+	c.Assert(chain.Remove("b"), IsNil)
+	c.Assert(chain.Remove("b"), NotNil)
+	resp, err := chain.Interceptor(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/test"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls = append(calls, "handler")
+		return req, nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(resp, Equals, "req")
+	c.Assert(calls, DeepEquals, []string{"a", "c", "d", "handler"})
+}
+
+func (t *InterceptorSuite) TestStreamChainRecoverPanic(c *C) {
+	chain := newStreamServerInterceptor()
+	err := chain.Interceptor(nil, &testServerStream{}, &grpc.StreamServerInfo{FullMethod: "/test"}, func(srv interface{}, stream grpc.ServerStream) error {
+		panic("stream panic")
+	})
+	c.Assert(err, NotNil)
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+}
+
+func (s *testServerStream) Context() context.Context {
+	return context.Background()
+}
+
+func (t *InterceptorSuite) TestAdapteError(c *C) {
+	var observed codes.Code
+	interceptor := withStatusError(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		observed = grpc.Code(err)
+		return resp, err
+	})
+	_, err := catchPanicInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: "/test"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, base.NewError(base.Error_Message_NotFount, "test", "not found")
+		})
+	})
+	c.Assert(observed, Equals, codes.NotFound)
+	c.Assert(grpc.Code(err), Equals, codes.NotFound)
+	s, _ := status.FromError(err)
+	decoded, ok := grpcbase.ErrorFromStatus(s.Proto())
+	c.Assert(ok, Equals, true)
+	c.Assert(decoded.GetCode(), Equals, int32(base.Error_Message_NotFount))
+
+	permissionDenied := status.Error(codes.PermissionDenied, "denied")
+	c.Assert(adapteError(context.Background(), permissionDenied), Equals, permissionDenied)
+}
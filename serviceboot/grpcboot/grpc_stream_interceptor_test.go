@@ -0,0 +1,71 @@
+package grpcboot
+
+import (
+	"net"
+
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/base/grpcbase"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+type StreamInterceptorSuite struct {
+}
+
+var _ = Suite(&StreamInterceptorSuite{})
+
+//callStream 启动使用 chain 的 grpc 服务,handler 处理所有的方法,返回客户端第一次 RecvMsg 的错误
+func callStream(c *C, chain *streamServerInterceptor, handler grpc.StreamHandler) error {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	server := grpc.NewServer(grpc.StreamInterceptor(chain.Interceptor), grpc.UnknownServiceHandler(handler))
+	go server.Serve(listener)
+	defer server.Stop()
+	clientConn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	c.Assert(err, IsNil)
+	defer clientConn.Close()
+	stream, err := grpc.NewClientStream(context.Background(), &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}, clientConn, "/test.Service/Watch")
+	c.Assert(err, IsNil)
+	c.Assert(stream.SendMsg(&grpc_health_v1.HealthCheckRequest{}), IsNil)
+	c.Assert(stream.CloseSend(), IsNil)
+	return stream.RecvMsg(&grpc_health_v1.HealthCheckResponse{})
+}
+
+func (t *StreamInterceptorSuite) TestStreamPanicToInternal(c *C) {
+	err := callStream(c, newStreamServerInterceptor(), func(srv interface{}, stream grpc.ServerStream) error {
+		panic("stream panic")
+	})
+	c.Assert(grpc.Code(err), Equals, codes.Internal)
+	c.Assert(grpc.ErrorDesc(err), Equals, "stream panic")
+}
+
+func (t *StreamInterceptorSuite) TestStreamBaseError(c *C) {
+	chain := newStreamServerInterceptor()
+	calls := make([]string, 0)
+	var observed codes.Code
+	c.Assert(chain.AppendInterceptor("a", withStreamStatusError(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		calls = append(calls, "a:"+info.FullMethod)
+		err := handler(srv, ss)
+		observed = grpc.Code(err)
+		return err
+	})), IsNil)
+	c.Assert(chain.InsertBefore("a", "b", func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		calls = append(calls, "b")
+		return handler(srv, ss)
+	}), IsNil)
+	err := callStream(c, chain, func(srv interface{}, stream grpc.ServerStream) error {
+		return base.NewError(base.Error_Message_NotFount, "user", "user not found")
+	})
+	c.Assert(calls, DeepEquals, []string{"b", "a:/test.Service/Watch"})
+	c.Assert(observed, Equals, codes.NotFound)
+	c.Assert(grpc.Code(err), Equals, codes.NotFound)
+	s, _ := status.FromError(err)
+	decoded, ok := grpcbase.ErrorFromStatus(s.Proto())
+	c.Assert(ok, Equals, true)
+	c.Assert(decoded.GetCode(), Equals, int32(base.Error_Message_NotFount))
+	c.Assert(decoded.GetScopes(), Equals, "user")
+}
@@ -0,0 +1,137 @@
+package grpcboot
+
+import (
+	"sync"
+
+	"github.com/coffeehc/microserviceboot/base"
+	"google.golang.org/grpc"
+)
+
+func newStreamServerInterceptor() *streamServerInterceptor {
+	return &streamServerInterceptor{
+		interceptors: make(map[string]*streamServerInterceptorWapper),
+		rootInterceptor: &streamServerInterceptorWapper{
+			interceptor: catchPanicStreamInterceptor,
+		},
+		mutex: new(sync.Mutex),
+	}
+}
+
+type streamServerInterceptor struct {
+	interceptors    map[string]*streamServerInterceptorWapper
+	rootInterceptor *streamServerInterceptorWapper
+	mutex           *sync.Mutex
+}
+
+func (ssi *streamServerInterceptor) Interceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return ssi.rootInterceptor.intercept(srv, ss, info, handler)
+}
+
+func (ssi *streamServerInterceptor) AppendInterceptor(name string, interceptor grpc.StreamServerInterceptor) base.Error {
+	ssi.mutex.Lock()
+	defer ssi.mutex.Unlock()
+	if _, ok := ssi.interceptors[name]; ok {
+		return errInterceptorExist(name)
+	}
+	lastInterceptor := getLastStreamServerInterceptor(ssi.rootInterceptor)
+	lastInterceptor.next = &streamServerInterceptorWapper{interceptor: interceptor}
+	ssi.interceptors[name] = lastInterceptor.next
+	return nil
+}
+
+func (ssi *streamServerInterceptor) InsertBefore(target string, name string, interceptor grpc.StreamServerInterceptor) base.Error {
+	ssi.mutex.Lock()
+	defer ssi.mutex.Unlock()
+	if _, ok := ssi.interceptors[name]; ok {
+		return errInterceptorExist(name)
+	}
+	targetInterceptor, ok := ssi.interceptors[target]
+	if !ok {
+		return errInterceptorNotFound(target)
+	}
+	prevInterceptor := getPrevStreamServerInterceptor(ssi.rootInterceptor, targetInterceptor)
+	prevInterceptor.next = &streamServerInterceptorWapper{interceptor: interceptor, next: targetInterceptor}
+	ssi.interceptors[name] = prevInterceptor.next
+	return nil
+}
+
+func (ssi *streamServerInterceptor) InsertAfter(target string, name string, interceptor grpc.StreamServerInterceptor) base.Error {
+	ssi.mutex.Lock()
+	defer ssi.mutex.Unlock()
+	if _, ok := ssi.interceptors[name]; ok {
+		return errInterceptorExist(name)
+	}
+	targetInterceptor, ok := ssi.interceptors[target]
+	if !ok {
+		return errInterceptorNotFound(target)
+	}
+	targetInterceptor.next = &streamServerInterceptorWapper{interceptor: interceptor, next: targetInterceptor.next}
+	ssi.interceptors[name] = targetInterceptor.next
+	return nil
+}
+
+func (ssi *streamServerInterceptor) Remove(name string) base.Error {
+	ssi.mutex.Lock()
+	defer ssi.mutex.Unlock()
+	targetInterceptor, ok := ssi.interceptors[name]
+	if !ok {
+		return errInterceptorNotFound(name)
+	}
+	prevInterceptor := getPrevStreamServerInterceptor(ssi.rootInterceptor, targetInterceptor)
+	prevInterceptor.next = targetInterceptor.next
+	delete(ssi.interceptors, name)
+	return nil
+}
+
+func getLastStreamServerInterceptor(root *streamServerInterceptorWapper) *streamServerInterceptorWapper {
+	if root.next == nil {
+		return root
+	}
+	return getLastStreamServerInterceptor(root.next)
+}
+
+func getPrevStreamServerInterceptor(root *streamServerInterceptorWapper, target *streamServerInterceptorWapper) *streamServerInterceptorWapper {
+	if root.next == target {
+		return root
+	}
+	return getPrevStreamServerInterceptor(root.next, target)
+}
+
+type streamServerInterceptorWapper struct {
+	interceptor grpc.StreamServerInterceptor
+	next        *streamServerInterceptorWapper
+}
+
+func (ssiw *streamServerInterceptorWapper) intercept(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if ssiw.next == nil {
+		return ssiw.interceptor(srv, ss, info, handler)
+	}
+	return ssiw.interceptor(srv, ss, info, func(srv interface{}, ss grpc.ServerStream) error {
+		return ssiw.next.intercept(srv, ss, info, handler)
+	})
+}
+
+//withStreamStatusError 在 interceptor 内部将 handler 返回的错误转换为 grpc 的状态,使 interceptor 看到标准的 grpc 状态码
+func withStreamStatusError(interceptor grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return interceptor(srv, ss, info, func(srv interface{}, ss grpc.ServerStream) error {
+			if err := handler(srv, ss); err != nil {
+				return adapteError(ss.Context(), err)
+			}
+			return nil
+		})
+	}
+}
+
+func catchPanicStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = adapteError(ss.Context(), r)
+		}
+	}()
+	err = handler(srv, ss)
+	if err != nil {
+		return adapteError(ss.Context(), err)
+	}
+	return nil
+}
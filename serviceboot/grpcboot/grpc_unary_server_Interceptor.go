@@ -1,7 +1,6 @@
 package grpcboot
 
 import (
-	"fmt"
 	"sync"
 
 	"time"
@@ -10,25 +9,13 @@ import (
 
 	"github.com/coffeehc/logger"
 	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/base/grpcbase"
 	"golang.org/x/net/context"
-	spb "google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-var (
-	_internalUnaryServerInfo = "_internal_UnaryServerInfo"
-	_internalHandler         = "_internal_handler"
-)
-
-var _unaryServerInterceptor = newUnaryServerInterceptor()
-
-//AppendUnaryServerInterceptor 追加新的UnaryServerInterceptor
-func AppendUnaryServerInterceptor(name string, unaryServerInterceptor grpc.UnaryServerInterceptor) base.Error {
-	return _unaryServerInterceptor.AppendInterceptor(name, unaryServerInterceptor)
-}
-
 func newUnaryServerInterceptor() *unaryServerInterceptor {
 	return &unaryServerInterceptor{
 		interceptors: make(map[string]*unaryServerInterceptorWapper),
@@ -46,16 +33,14 @@ type unaryServerInterceptor struct {
 }
 
 func (usi *unaryServerInterceptor) Interceptor(cxt context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
-	cxt = context.WithValue(cxt, _internalUnaryServerInfo, info)
-	cxt = context.WithValue(cxt, _internalHandler, handler)
-	return usi.rootInterceptor.interceptor(cxt, req, info, usi.rootInterceptor.handler)
+	return usi.rootInterceptor.intercept(cxt, req, info, handler)
 }
 
 func (usi *unaryServerInterceptor) AppendInterceptor(name string, interceptor grpc.UnaryServerInterceptor) base.Error {
 	usi.mutex.Lock()
 	defer usi.mutex.Unlock()
 	if _, ok := usi.interceptors[name]; ok {
-		return base.NewError(base.Error_System, "grpc interceptor", fmt.Sprintf("%s 已经存在", name))
+		return errInterceptorExist(name)
 	}
 	lastInterceptor := getLastUnaryServerInterceptor(usi.rootInterceptor)
 	lastInterceptor.next = &unaryServerInterceptorWapper{interceptor: interceptor}
@@ -63,6 +48,50 @@ func (usi *unaryServerInterceptor) AppendInterceptor(name string, interceptor gr
 	return nil
 }
 
+func (usi *unaryServerInterceptor) InsertBefore(target string, name string, interceptor grpc.UnaryServerInterceptor) base.Error {
+	usi.mutex.Lock()
+	defer usi.mutex.Unlock()
+	if _, ok := usi.interceptors[name]; ok {
+		return errInterceptorExist(name)
+	}
+	targetInterceptor, ok := usi.interceptors[target]
+	if !ok {
+		return errInterceptorNotFound(target)
+	}
+	prevInterceptor := getPrevUnaryServerInterceptor(usi.rootInterceptor, targetInterceptor)
+	prevInterceptor.next = &unaryServerInterceptorWapper{interceptor: interceptor, next: targetInterceptor}
+	usi.interceptors[name] = prevInterceptor.next
+	return nil
+}
+
+func (usi *unaryServerInterceptor) InsertAfter(target string, name string, interceptor grpc.UnaryServerInterceptor) base.Error {
+	usi.mutex.Lock()
+	defer usi.mutex.Unlock()
+	if _, ok := usi.interceptors[name]; ok {
+		return errInterceptorExist(name)
+	}
+	targetInterceptor, ok := usi.interceptors[target]
+	if !ok {
+		return errInterceptorNotFound(target)
+	}
+	targetInterceptor.next = &unaryServerInterceptorWapper{interceptor: interceptor, next: targetInterceptor.next}
+	usi.interceptors[name] = targetInterceptor.next
+	return nil
+}
+
+func (usi *unaryServerInterceptor) Remove(name string) base.Error {
+	usi.mutex.Lock()
+	defer usi.mutex.Unlock()
+	targetInterceptor, ok := usi.interceptors[name]
+	if !ok {
+		return errInterceptorNotFound(name)
+	}
+	prevInterceptor := getPrevUnaryServerInterceptor(usi.rootInterceptor, targetInterceptor)
+	prevInterceptor.next = targetInterceptor.next
+	delete(usi.interceptors, name)
+	return nil
+}
+
 func getLastUnaryServerInterceptor(root *unaryServerInterceptorWapper) *unaryServerInterceptorWapper {
 	if root.next == nil {
 		return root
@@ -70,30 +99,25 @@ func getLastUnaryServerInterceptor(root *unaryServerInterceptorWapper) *unarySer
 	return getLastUnaryServerInterceptor(root.next)
 }
 
+func getPrevUnaryServerInterceptor(root *unaryServerInterceptorWapper, target *unaryServerInterceptorWapper) *unaryServerInterceptorWapper {
+	if root.next == target {
+		return root
+	}
+	return getPrevUnaryServerInterceptor(root.next, target)
+}
+
 type unaryServerInterceptorWapper struct {
 	interceptor grpc.UnaryServerInterceptor
 	next        *unaryServerInterceptorWapper
 }
 
-func (usiw *unaryServerInterceptorWapper) handler(ctx context.Context, req interface{}) (interface{}, error) {
+func (usiw *unaryServerInterceptorWapper) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 	if usiw.next == nil {
-		realHandler := ctx.Value(_internalHandler)
-		if realHandler == nil {
-			return nil, base.NewError(base.Error_System, "grpc handler", "没有 Handler")
-		}
-		if handler, ok := realHandler.(grpc.UnaryHandler); ok {
-			return handler(ctx, req)
-		}
-		return nil, base.NewError(base.Error_System, "grpc handler", "类型错误")
-	}
-	info := ctx.Value(_internalUnaryServerInfo)
-	if info == 0 {
-		return nil, base.NewError(base.Error_System, "grpc interceptor", "没有 ServerInfo")
-	}
-	if unaryServerInfo, ok := info.(*grpc.UnaryServerInfo); ok {
-		return usiw.next.interceptor(ctx, req, unaryServerInfo, usiw.next.handler)
+		return usiw.interceptor(ctx, req, info, handler)
 	}
-	return nil, base.NewError(base.Error_System, "grpc interceptor", "类型错误")
+	return usiw.interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return usiw.next.intercept(ctx, req, info, handler)
+	})
 }
 
 func catchPanicInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
@@ -109,10 +133,29 @@ func catchPanicInterceptor(ctx context.Context, req interface{}, info *grpc.Unar
 	return resp, nil
 }
 
+//withStatusError 在 interceptor 内部将 handler 返回的错误转换为 grpc 的状态,使 interceptor 看到标准的 grpc 状态码
+func withStatusError(interceptor grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			resp, err := handler(ctx, req)
+			if err != nil {
+				return nil, adapteError(ctx, err)
+			}
+			return resp, nil
+		})
+	}
+}
+
+//adapteError 将 err 转换为 grpc 的状态,base.Error 使用 grpcbase.ErrorToStatus,已经是 grpc 状态的错误保持不变
 func adapteError(cxt context.Context, err interface{}) error {
 	if err == nil {
 		return nil
 	}
+	if e, ok := err.(error); ok {
+		if _, ok := status.FromError(e); ok {
+			return e
+		}
+	}
 	if base.IsDevModule() {
 		if e, ok := err.(base.Error); ok && base.IsMessageError(e) {
 			logger.Error("发生异常:%s", e.Error())
@@ -124,10 +167,7 @@ func adapteError(cxt context.Context, err interface{}) error {
 	}
 	switch v := err.(type) {
 	case base.Error:
-		return status.ErrorProto(&spb.Status{
-			Code:    v.GetCode(),
-			Message: v.Error(),
-		})
+		return status.ErrorProto(grpcbase.ErrorToStatus(v))
 	case string:
 		return status.Errorf(codes.Internal, v)
 	case error:
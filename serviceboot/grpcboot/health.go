@@ -0,0 +1,142 @@
+package grpcboot
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/coffeehc/httpx"
+	"github.com/coffeehc/microserviceboot/base"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const grpcHealthPath = "/health/grpc"
+
+//healthServer grpc.health.v1.Health 的实现,服务名为空表示整个服务的状态,
+// statusMap 只保存服务自己设置的状态,启动之前与关闭之后所有的服务都是 NOT_SERVING,
+// 启动时不会覆盖服务在 Init 中设置的状态
+type healthServer struct {
+	mutex     *sync.RWMutex
+	statusMap map[string]healthpb.HealthCheckResponse_ServingStatus
+	started   bool
+	shutdown  bool
+}
+
+func newHealthServer() *healthServer {
+	return &healthServer{
+		mutex: new(sync.RWMutex),
+		statusMap: map[string]healthpb.HealthCheckResponse_ServingStatus{
+			"": healthpb.HealthCheckResponse_SERVING,
+		},
+	}
+}
+
+func (hs *healthServer) Check(ctx context.Context, in *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	hs.mutex.RLock()
+	defer hs.mutex.RUnlock()
+	if _, ok := hs.statusMap[in.Service]; ok {
+		return &healthpb.HealthCheckResponse{Status: hs.servingStatus(in.Service)}, nil
+	}
+	return nil, status.Errorf(codes.NotFound, "unknown service %s", in.Service)
+}
+
+//servingStatus 在锁内调用,服务没有启动或者已经关闭时为 NOT_SERVING,否则为服务设置的状态
+func (hs *healthServer) servingStatus(service string) healthpb.HealthCheckResponse_ServingStatus {
+	if !hs.started || hs.shutdown {
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	return hs.statusMap[service]
+}
+
+//SetServingStatus 设置服务状态,服务关闭后的修改会被忽略
+func (hs *healthServer) SetServingStatus(service string, serving bool) {
+	hs.mutex.Lock()
+	defer hs.mutex.Unlock()
+	if hs.shutdown {
+		return
+	}
+	hs.statusMap[service] = toServingStatus(serving)
+}
+
+//IsServing 整个服务是否处于 SERVING 状态
+func (hs *healthServer) IsServing() bool {
+	hs.mutex.RLock()
+	defer hs.mutex.RUnlock()
+	return hs.servingStatus("") == healthpb.HealthCheckResponse_SERVING
+}
+
+//checkServing 作为就绪检查项,服务代码可以通过 HealthReporter 将整个服务置为 NOT_SERVING
+func (hs *healthServer) checkServing() error {
+	if !hs.IsServing() {
+		return base.NewError(base.Error_System, "grpc health", "grpc 服务处于 NOT_SERVING 状态")
+	}
+	return nil
+}
+
+func (hs *healthServer) registerServices(grpcServer *grpc.Server) {
+	hs.mutex.Lock()
+	defer hs.mutex.Unlock()
+	for serviceName := range grpcServer.GetServiceInfo() {
+		if _, ok := hs.statusMap[serviceName]; !ok {
+			hs.statusMap[serviceName] = healthpb.HealthCheckResponse_SERVING
+		}
+	}
+}
+
+//resume 服务启动,所有的服务恢复为自己设置的状态
+func (hs *healthServer) resume() {
+	hs.mutex.Lock()
+	defer hs.mutex.Unlock()
+	hs.started = true
+}
+
+//shutdownAll 服务关闭,所有的服务都为 NOT_SERVING,之后的修改会被忽略
+func (hs *healthServer) shutdownAll() {
+	hs.mutex.Lock()
+	defer hs.mutex.Unlock()
+	hs.shutdown = true
+}
+
+//httpHealth 以 http 的方式暴露整个服务的 grpc 健康状态,供不支持 grpc 检查的注册中心使用
+func (hs *healthServer) httpHealth(reply httpx.Reply) {
+	servingStatus := healthpb.HealthCheckResponse_NOT_SERVING
+	if hs.IsServing() {
+		servingStatus = healthpb.HealthCheckResponse_SERVING
+	} else {
+		reply.SetStatusCode(http.StatusServiceUnavailable)
+	}
+	reply.With(map[string]string{"status": servingStatus.String()}).As(httpx.DefaultRenderJSON)
+}
+
+func toServingStatus(serving bool) healthpb.HealthCheckResponse_ServingStatus {
+	if serving {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
+
+//healthMethodPrefix grpc.health.v1.Health 服务的方法前缀
+const healthMethodPrefix = "/grpc.health.v1.Health/"
+
+//skipHealthCheck 健康检查的调用跳过 interceptor,如限流,否则服务繁忙时会被判断为不健康
+func skipHealthCheck(interceptor grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if strings.HasPrefix(info.FullMethod, healthMethodPrefix) {
+			return handler(ctx, req)
+		}
+		return interceptor(ctx, req, info, handler)
+	}
+}
+
+func skipStreamHealthCheck(interceptor grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if strings.HasPrefix(info.FullMethod, healthMethodPrefix) {
+			return handler(srv, ss)
+		}
+		return interceptor(srv, ss, info, handler)
+	}
+}
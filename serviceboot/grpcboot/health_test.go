@@ -0,0 +1,110 @@
+package grpcboot
+
+import (
+	"net/http"
+
+	"github.com/coffeehc/httpx"
+	"github.com/coffeehc/microserviceboot/base"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	. "gopkg.in/check.v1"
+)
+
+type HealthSuite struct {
+}
+
+var _ = Suite(&HealthSuite{})
+
+//testReply 只实现 httpHealth 使用的方法
+type testReply struct {
+	httpx.Reply
+	statusCode int
+	data       interface{}
+}
+
+func (reply *testReply) SetStatusCode(statusCode int) httpx.Reply {
+	reply.statusCode = statusCode
+	return reply
+}
+
+func (reply *testReply) With(data interface{}) httpx.Reply {
+	reply.data = data
+	return reply
+}
+
+func (reply *testReply) As(render httpx.Render) httpx.Reply {
+	return reply
+}
+
+func checkStatus(c *C, hs *healthServer, service string) healthpb.HealthCheckResponse_ServingStatus {
+	resp, err := hs.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	c.Assert(err, IsNil)
+	return resp.Status
+}
+
+func (t *HealthSuite) TestHealthServer(c *C) {
+	hs := newHealthServer()
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, hs)
+	hs.registerServices(grpcServer)
+	c.Assert(checkStatus(c, hs, ""), Equals, healthpb.HealthCheckResponse_NOT_SERVING)
+	c.Assert(checkStatus(c, hs, "grpc.health.v1.Health"), Equals, healthpb.HealthCheckResponse_NOT_SERVING)
+	_, err := hs.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	c.Assert(grpc.Code(err), Equals, codes.NotFound)
+
+	hs.resume()
+	c.Assert(checkStatus(c, hs, "grpc.health.v1.Health"), Equals, healthpb.HealthCheckResponse_SERVING)
+	c.Assert(hs.checkServing(), IsNil)
+	reply := &testReply{}
+	hs.httpHealth(reply)
+	c.Assert(reply.statusCode, Equals, 0)
+
+	hs.SetServingStatus("", false)
+	c.Assert(hs.checkServing(), NotNil)
+	reply = &testReply{}
+	hs.httpHealth(reply)
+	c.Assert(reply.statusCode, Equals, http.StatusServiceUnavailable)
+	c.Assert(reply.data, DeepEquals, map[string]string{"status": "NOT_SERVING"})
+
+	//关闭之后的修改被忽略
+	hs.SetServingStatus("", true)
+	hs.shutdownAll()
+	hs.SetServingStatus("", true)
+	c.Assert(hs.IsServing(), Equals, false)
+	c.Assert(checkStatus(c, hs, "grpc.health.v1.Health"), Equals, healthpb.HealthCheckResponse_NOT_SERVING)
+}
+
+func (t *HealthSuite) TestKeepStatusOnResume(c *C) {
+	hs := newHealthServer()
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, hs)
+	//服务在 Init 中设置的状态在启动之后仍然有效
+	hs.SetServingStatus("test.Service", false)
+	hs.registerServices(grpcServer)
+	c.Assert(checkStatus(c, hs, "grpc.health.v1.Health"), Equals, healthpb.HealthCheckResponse_NOT_SERVING)
+	hs.resume()
+	c.Assert(checkStatus(c, hs, "test.Service"), Equals, healthpb.HealthCheckResponse_NOT_SERVING)
+	c.Assert(checkStatus(c, hs, "grpc.health.v1.Health"), Equals, healthpb.HealthCheckResponse_SERVING)
+	c.Assert(hs.IsServing(), Equals, true)
+	hs.SetServingStatus("test.Service", true)
+	c.Assert(checkStatus(c, hs, "test.Service"), Equals, healthpb.HealthCheckResponse_SERVING)
+}
+
+func (t *HealthSuite) TestSkipHealthCheck(c *C) {
+	rejected := 0
+	interceptor := skipHealthCheck(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		rejected++
+		return nil, base.NewError(base.Error_System_RateLimited, "test", "rate limited")
+	})
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	resp, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, handler)
+	c.Assert(err, IsNil)
+	c.Assert(resp, Equals, "ok")
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Get"}, handler)
+	c.Assert(base.IsRateLimitedError(err), Equals, true)
+	c.Assert(rejected, Equals, 1)
+}
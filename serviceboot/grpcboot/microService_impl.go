@@ -5,14 +5,15 @@ import (
 	"net"
 
 	"github.com/coffeehc/httpx"
-	"github.com/coffeehc/logger"
 	"github.com/coffeehc/microserviceboot/base"
 	"github.com/coffeehc/microserviceboot/base/grpcbase"
 	"github.com/coffeehc/microserviceboot/serviceboot"
 	"github.com/coffeehc/microserviceboot/serviceboot/internal"
+	"github.com/coffeehc/microserviceboot/serviceboot/ratelimit"
 	"github.com/grpc-ecosystem/go-grpc-prometheus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/grpclog"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 //GRPCMicroServiceBuilder 默认用于 grpc 的MicroServiceBuilder 实现
@@ -24,17 +25,29 @@ func microServiceBuilder(service base.Service) (serviceboot.MicroService, base.E
 		return nil, base.NewError(-1, "GrpcMicroService build", "service 不是grpc 服务")
 	}
 	return &_GRPCMicroService{
-		service:    grpcService,
-		cleanFuncs: make([]func(), 0),
+		service:           grpcService,
+		cleanFuncs:        make([]func(), 0),
+		deregisterFuncs:   make([]func(), 0),
+		requestTracker:    internal.NewRequestTracker(),
+		unaryInterceptor:  newUnaryServerInterceptor(),
+		streamInterceptor: newStreamServerInterceptor(),
+		healthServer:      newHealthServer(),
+		healthRegistry:    serviceboot.NewHealthIndicatorRegistry(),
 	}, nil
 }
 
 type _GRPCMicroService struct {
-	service    grpcbase.GRPCService
-	config     *Config
-	httpServer httpx.Server
-	grpcServer *grpc.Server
-	cleanFuncs []func()
+	service           grpcbase.GRPCService
+	config            *Config
+	httpServer        httpx.Server
+	grpcServer        *grpc.Server
+	cleanFuncs        []func()
+	deregisterFuncs   []func()
+	requestTracker    *internal.RequestTracker
+	unaryInterceptor  *unaryServerInterceptor
+	streamInterceptor *streamServerInterceptor
+	healthServer      *healthServer
+	healthRegistry    *serviceboot.HealthIndicatorRegistry
 }
 
 func (ms *_GRPCMicroService) Init(cxt context.Context) (*serviceboot.ServiceConfig, base.Error) {
@@ -53,6 +66,14 @@ func (ms *_GRPCMicroService) Init(cxt context.Context) (*serviceboot.ServiceConf
 	if err != nil {
 		return nil, err
 	}
+	if base.IsCheckConfigModule() {
+		//只校验配置,不初始化 Service
+		err = internal.CheckServiceConfig(ms.service, configPath)
+		if err != nil {
+			return nil, err
+		}
+		return config.GetServiceConfig(), nil
+	}
 	//构建 TSL
 	if httpServerConfig.TLSConfig == nil {
 		httpServerConfig.TLSConfig, err = serviceboot.NewDefaultTLSConfig()
@@ -62,30 +83,112 @@ func (ms *_GRPCMicroService) Init(cxt context.Context) (*serviceboot.ServiceConf
 	}
 	tcpAddr, _ := net.ResolveTCPAddr("tcp", httpServerConfig.ServerAddr)
 	httpServerConfig.TLSConfig.ServerName = tcpAddr.IP.String()
-	httpServer, err := serviceboot.NewHTTPServer(httpServerConfig, ms.GetServiceInfo())
+	httpServer, err := serviceboot.NewHTTPServer(httpServerConfig, ms.GetServiceInfo(), ms.healthRegistry)
 	if err != nil {
 		return nil, err
 	}
 	ms.httpServer = httpServer
+	err = ms.initInterceptors()
+	if err != nil {
+		return nil, err
+	}
 	grpcOptions := ms.config.GetGRPCOptions()
+	grpcOptions = append(grpcOptions,
+		grpc.UnaryInterceptor(ms.unaryInterceptor.Interceptor),
+		grpc.StreamInterceptor(ms.streamInterceptor.Interceptor),
+	)
 	if len(ms.service.GetGRPCOptions()) > 0 {
 		grpcOptions = append(grpcOptions, ms.service.GetGRPCOptions()...)
 	}
 	ms.grpcServer = grpc.NewServer(grpcOptions...)
-	err = ms.service.Init(cxt, configPath, httpServer)
+	err = ms.service.Init(base.WithCleanFuncRegister(cxt, ms), configPath, httpServer)
+	if err != nil {
+		return nil, err
+	}
+	if interceptorService, ok := ms.service.(grpcbase.InterceptorService); ok {
+		err = interceptorService.InitInterceptors(ms.unaryInterceptor, ms.streamInterceptor)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = internal.InitHealthIndicators(ms.service, ms.healthRegistry)
 	if err != nil {
 		return nil, err
 	}
+	err = ms.healthRegistry.RegisterReadiness("grpc", base.HealthIndicatorFunc(func(cxt context.Context) error {
+		return ms.healthServer.checkServing()
+	}), 0)
+	if err != nil {
+		return nil, err
+	}
+	if healthReporterAware, ok := ms.service.(grpcbase.HealthReporterAware); ok {
+		healthReporterAware.SetHealthReporter(ms.healthServer)
+	}
 	ms.service.RegisterServer(ms.grpcServer)
+	healthpb.RegisterHealthServer(ms.grpcServer, ms.healthServer)
+	ms.healthServer.registerServices(ms.grpcServer)
 	grpc_prometheus.Register(ms.grpcServer)
+	registerErr := ms.httpServer.Register(grpcHealthPath, httpx.GET, ms.healthServer.httpHealth)
+	if registerErr != nil {
+		return nil, base.NewError(base.Error_System, "GrpcMicroService health", registerErr.Error())
+	}
 	grpcFilter := &grpcFilter{ms.grpcServer}
 	ms.httpServer.AddFirstFilter("*", grpcFilter.filter)
 	if base.IsDevModule() && config.GetServiceConfig().EnableAccessInfo {
 		ms.httpServer.AddFirstFilter("*", httpx.AccessLogFilter)
 	}
+	ms.httpServer.AddFirstFilter("*", ms.requestTracker.Filter)
 	return config.GetServiceConfig(), nil
 }
 
+func (ms *_GRPCMicroService) initInterceptors() base.Error {
+	err := ms.unaryInterceptor.AppendInterceptor("tracing", tracingInterceptor)
+	if err != nil {
+		return err
+	}
+	err = ms.streamInterceptor.AppendInterceptor("tracing", streamTracingInterceptor)
+	if err != nil {
+		return err
+	}
+	if base.IsDevModule() {
+		err = ms.unaryInterceptor.AppendInterceptor("logger", loggingInterceptor)
+		if err != nil {
+			return err
+		}
+		err = ms.streamInterceptor.AppendInterceptor("logger", streamLoggingInterceptor)
+		if err != nil {
+			return err
+		}
+	}
+	err = ms.unaryInterceptor.AppendInterceptor("prometheus", withStatusError(grpc_prometheus.UnaryServerInterceptor))
+	if err != nil {
+		return err
+	}
+	err = ms.streamInterceptor.AppendInterceptor("prometheus", withStreamStatusError(grpc_prometheus.StreamServerInterceptor))
+	if err != nil {
+		return err
+	}
+	minRequestTimeout := ms.config.GetServiceConfig().MinRequestTimeout
+	err = ms.unaryInterceptor.AppendInterceptor("deadline", newDeadlineInterceptor(minRequestTimeout))
+	if err != nil {
+		return err
+	}
+	err = ms.streamInterceptor.AppendInterceptor("deadline", newStreamDeadlineInterceptor(minRequestTimeout))
+	if err != nil {
+		return err
+	}
+	rateLimit := ms.config.GetServiceConfig().RateLimit
+	if rateLimit == nil {
+		return nil
+	}
+	limiter := ratelimit.NewLimiter(rateLimit)
+	err = ms.unaryInterceptor.AppendInterceptor("rate_limit", skipHealthCheck(limiter.UnaryServerInterceptor))
+	if err != nil {
+		return err
+	}
+	return ms.streamInterceptor.AppendInterceptor("rate_limit", skipStreamHealthCheck(limiter.StreamServerInterceptor))
+}
+
 func (ms *_GRPCMicroService) Start(cxt context.Context) base.Error {
 	err := internal.StartService(ms.service)
 	if err != nil {
@@ -99,6 +202,8 @@ func (ms *_GRPCMicroService) Start(cxt context.Context) base.Error {
 			panic(base.NewError(base.Error_System, "GrpcMicroService start", err.Error()))
 		}
 	}()
+	ms.healthServer.resume()
+	ms.healthRegistry.SetServing(true)
 	return nil
 }
 
@@ -106,23 +211,26 @@ func (ms *_GRPCMicroService) AddCleanFunc(f func()) {
 	ms.cleanFuncs = append(ms.cleanFuncs, f)
 }
 
+func (ms *_GRPCMicroService) AddDeregisterFunc(f func()) {
+	ms.deregisterFuncs = append(ms.deregisterFuncs, f)
+}
+
 func (ms *_GRPCMicroService) Stop() {
+	ms.healthRegistry.SetServing(false)
+	ms.healthServer.shutdownAll()
+	internal.DrainService(ms.config.GetServiceConfig().GetShutdownConfig(), ms.deregisterFuncs, ms.requestTracker)
 	if ms.httpServer != nil {
 		httpServer := ms.httpServer
 		ms.httpServer = nil
 		httpServer.Stop()
 	}
 	internal.StopService(ms.service)
-	for _, f := range ms.cleanFuncs {
-		func() {
-			defer func() {
-				if err := recover(); err != nil {
-					logger.Error("clean func painc :%s", err)
-				}
-			}()
-			f()
-		}()
-	}
+	internal.RunFuncs("clean", ms.cleanFuncs)
+}
+
+//GetHealthCheck 实现 serviceboot.HealthCheckProvider,使用就绪检查作为注册中心的健康检查
+func (ms *_GRPCMicroService) GetHealthCheck() *base.HealthCheck {
+	return ms.healthRegistry.GetHealthCheck()
 }
 
 func (ms *_GRPCMicroService) GetService() base.Service {
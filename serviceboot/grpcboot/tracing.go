@@ -0,0 +1,52 @@
+package grpcboot
+
+import (
+	"github.com/coffeehc/microserviceboot/tracing"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type tracingServerStream struct {
+	grpc.ServerStream
+	cxt context.Context
+}
+
+func (s *tracingServerStream) Context() context.Context {
+	return s.cxt
+}
+
+//startServerSpan 从 metadata 中解析上游的 traceparent,创建 server span
+func startServerSpan(cxt context.Context, method string) (context.Context, *tracing.Span) {
+	if md, ok := metadata.FromIncomingContext(cxt); ok {
+		var tracestate string
+		if values := md[tracing.HeaderTracestate]; len(values) > 0 {
+			tracestate = values[0]
+		}
+		if values := md[tracing.HeaderTraceparent]; len(values) > 0 {
+			if sc, ok := tracing.ParseTraceparent(values[0], tracestate); ok {
+				cxt = tracing.ContextWithRemoteSpanContext(cxt, sc)
+			}
+		}
+	}
+	spanCxt, span := tracing.StartSpan(cxt, method, tracing.SpanKindServer)
+	span.SetAttribute("rpc.system", "grpc")
+	span.SetAttribute("rpc.method", method)
+	return spanCxt, span
+}
+
+func tracingInterceptor(cxt context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	cxt, span := startServerSpan(cxt, info.FullMethod)
+	defer span.End()
+	resp, err := handler(cxt, req)
+	span.SetError(err)
+	return resp, err
+}
+
+func streamTracingInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	cxt, span := startServerSpan(ss.Context(), info.FullMethod)
+	defer span.End()
+	err := handler(srv, &tracingServerStream{ServerStream: ss, cxt: cxt})
+	span.SetError(err)
+	return err
+}
@@ -1,9 +1,11 @@
 package grpcboot
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/coffeehc/logger"
+	"github.com/coffeehc/microserviceboot/base"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 )
@@ -16,3 +18,20 @@ func loggingInterceptor(ctx context.Context, req interface{}, info *grpc.UnarySe
 	resp, err = handler(ctx, req)
 	return resp, err
 }
+
+func streamLoggingInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	start := time.Now()
+	defer func() {
+		logger.Debug("finished stream %s, took=%s, err=%v", info.FullMethod, time.Since(start), err)
+	}()
+	err = handler(srv, ss)
+	return err
+}
+
+func errInterceptorExist(name string) base.Error {
+	return base.NewError(base.Error_System, "grpc interceptor", fmt.Sprintf("%s 已经存在", name))
+}
+
+func errInterceptorNotFound(name string) base.Error {
+	return base.NewError(base.Error_System, "grpc interceptor", fmt.Sprintf("%s 不存在", name))
+}
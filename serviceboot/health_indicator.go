@@ -0,0 +1,219 @@
+package serviceboot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coffeehc/httpx"
+	"github.com/coffeehc/microserviceboot/base"
+)
+
+const (
+	//HealthLivePath 存活检查的路径
+	HealthLivePath = "/health/live"
+	//HealthReadyPath 就绪检查的路径,注册中心的健康检查应该使用此路径
+	HealthReadyPath = "/health/ready"
+
+	//HealthStatusUp 健康
+	HealthStatusUp = "UP"
+	//HealthStatusDown 不健康
+	HealthStatusDown = "DOWN"
+
+	defaultHealthCheckTimeout = time.Second * 3
+	errScopeHealth            = "health"
+	healthIndicatorLifecycle  = "lifecycle"
+)
+
+type healthIndicatorEntry struct {
+	name      string
+	indicator base.HealthIndicator
+	timeout   time.Duration
+	readiness bool
+}
+
+//HealthCheckResult 单个检查项的结果
+type HealthCheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+//HealthReport 汇总的检查结果
+type HealthReport struct {
+	Status string                        `json:"status"`
+	Checks map[string]*HealthCheckResult `json:"checks"`
+}
+
+//HealthIndicatorRegistry base.HealthRegistry 的默认实现,提供 /health/live 与 /health/ready
+type HealthIndicatorRegistry struct {
+	mutex      *sync.RWMutex
+	indicators map[string]*healthIndicatorEntry
+	serving    bool
+}
+
+//NewHealthIndicatorRegistry 创建 HealthIndicatorRegistry,默认包含一个跟随服务生命周期的就绪检查项
+func NewHealthIndicatorRegistry() *HealthIndicatorRegistry {
+	registry := &HealthIndicatorRegistry{
+		mutex:      new(sync.RWMutex),
+		indicators: make(map[string]*healthIndicatorEntry),
+	}
+	registry.RegisterReadiness(healthIndicatorLifecycle, base.HealthIndicatorFunc(registry.checkLifecycle), 0)
+	return registry
+}
+
+//RegisterLiveness implement base.HealthRegistry interface
+func (hr *HealthIndicatorRegistry) RegisterLiveness(name string, indicator base.HealthIndicator, timeout time.Duration) base.Error {
+	return hr.register(name, indicator, timeout, false)
+}
+
+//RegisterReadiness implement base.HealthRegistry interface
+func (hr *HealthIndicatorRegistry) RegisterReadiness(name string, indicator base.HealthIndicator, timeout time.Duration) base.Error {
+	return hr.register(name, indicator, timeout, true)
+}
+
+//Unregister implement base.HealthRegistry interface
+func (hr *HealthIndicatorRegistry) Unregister(name string) {
+	hr.mutex.Lock()
+	defer hr.mutex.Unlock()
+	delete(hr.indicators, name)
+}
+
+func (hr *HealthIndicatorRegistry) register(name string, indicator base.HealthIndicator, timeout time.Duration, readiness bool) base.Error {
+	if name == "" || indicator == nil {
+		return base.NewError(base.Error_System, errScopeHealth, "没有指定检查项名称或者 HealthIndicator")
+	}
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	hr.mutex.Lock()
+	defer hr.mutex.Unlock()
+	if _, ok := hr.indicators[name]; ok {
+		return base.NewError(base.Error_System, errScopeHealth, fmt.Sprintf("%s 已经存在", name))
+	}
+	hr.indicators[name] = &healthIndicatorEntry{
+		name:      name,
+		indicator: indicator,
+		timeout:   timeout,
+		readiness: readiness,
+	}
+	return nil
+}
+
+//SetServing 设置服务是否可以接收流量,启动完成后设置为 true,关闭时设置为 false
+func (hr *HealthIndicatorRegistry) SetServing(serving bool) {
+	hr.mutex.Lock()
+	defer hr.mutex.Unlock()
+	hr.serving = serving
+}
+
+func (hr *HealthIndicatorRegistry) checkLifecycle(cxt context.Context) error {
+	hr.mutex.RLock()
+	defer hr.mutex.RUnlock()
+	if !hr.serving {
+		return base.NewError(base.Error_System, errScopeHealth, "服务没有启动或者正在关闭")
+	}
+	return nil
+}
+
+//Liveness 执行所有的存活检查项
+func (hr *HealthIndicatorRegistry) Liveness(cxt context.Context) *HealthReport {
+	return hr.check(cxt, false)
+}
+
+//Readiness 执行所有的就绪检查项
+func (hr *HealthIndicatorRegistry) Readiness(cxt context.Context) *HealthReport {
+	return hr.check(cxt, true)
+}
+
+//IsReady 服务是否就绪,用于进程内的健康检查
+func (hr *HealthIndicatorRegistry) IsReady() bool {
+	return hr.Readiness(context.Background()).Status == HealthStatusUp
+}
+
+func (hr *HealthIndicatorRegistry) check(cxt context.Context, readiness bool) *HealthReport {
+	hr.mutex.RLock()
+	entries := make([]*healthIndicatorEntry, 0, len(hr.indicators))
+	for _, entry := range hr.indicators {
+		if entry.readiness == readiness {
+			entries = append(entries, entry)
+		}
+	}
+	hr.mutex.RUnlock()
+	report := &HealthReport{
+		Status: HealthStatusUp,
+		Checks: make(map[string]*HealthCheckResult, len(entries)),
+	}
+	results := make([]*HealthCheckResult, len(entries))
+	wg := new(sync.WaitGroup)
+	for i, entry := range entries {
+		wg.Add(1)
+		go func(i int, entry *healthIndicatorEntry) {
+			defer wg.Done()
+			results[i] = runHealthIndicator(cxt, entry)
+		}(i, entry)
+	}
+	wg.Wait()
+	for i, entry := range entries {
+		report.Checks[entry.name] = results[i]
+		if results[i].Status != HealthStatusUp {
+			report.Status = HealthStatusDown
+		}
+	}
+	return report
+}
+
+func runHealthIndicator(cxt context.Context, entry *healthIndicatorEntry) *HealthCheckResult {
+	cxt, cancel := context.WithTimeout(cxt, entry.timeout)
+	defer cancel()
+	start := time.Now()
+	errSign := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errSign <- fmt.Errorf("check painc :%v", r)
+			}
+		}()
+		errSign <- entry.indicator.Check(cxt)
+	}()
+	var err error
+	select {
+	case err = <-errSign:
+	case <-cxt.Done():
+		err = fmt.Errorf("检查超时(%s)", entry.timeout)
+	}
+	result := &HealthCheckResult{
+		Status:   HealthStatusUp,
+		Duration: time.Since(start).String(),
+	}
+	if err != nil {
+		result.Status = HealthStatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (hr *HealthIndicatorRegistry) live(reply httpx.Reply) {
+	renderHealthReport(reply, hr.Liveness(reply.GetRequest().Context()))
+}
+
+func (hr *HealthIndicatorRegistry) ready(reply httpx.Reply) {
+	renderHealthReport(reply, hr.Readiness(reply.GetRequest().Context()))
+}
+
+func renderHealthReport(reply httpx.Reply, report *HealthReport) {
+	if report.Status != HealthStatusUp {
+		reply.SetStatusCode(http.StatusServiceUnavailable)
+	}
+	reply.With(report).As(httpx.DefaultRenderJSON)
+}
+
+//GetHealthCheck 构建注册中心使用的健康检查,指向就绪检查
+func (hr *HealthIndicatorRegistry) GetHealthCheck() *base.HealthCheck {
+	return &base.HealthCheck{
+		HTTPPath: HealthReadyPath,
+		Checker:  hr.IsReady,
+	}
+}
@@ -0,0 +1,104 @@
+package serviceboot
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coffeehc/httpx"
+	"github.com/coffeehc/microserviceboot/base"
+	. "gopkg.in/check.v1"
+)
+
+type HealthIndicatorSuite struct {
+}
+
+var _ = Suite(&HealthIndicatorSuite{})
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+//testReply 只实现健康检查使用的方法
+type testReply struct {
+	httpx.Reply
+	request    *http.Request
+	statusCode int
+	data       interface{}
+}
+
+func (reply *testReply) GetRequest() *http.Request {
+	return reply.request
+}
+
+func (reply *testReply) SetStatusCode(statusCode int) httpx.Reply {
+	reply.statusCode = statusCode
+	return reply
+}
+
+func (reply *testReply) With(data interface{}) httpx.Reply {
+	reply.data = data
+	return reply
+}
+
+func (reply *testReply) As(render httpx.Render) httpx.Reply {
+	return reply
+}
+
+func newTestReply() *testReply {
+	request, _ := http.NewRequest(http.MethodGet, HealthReadyPath, nil)
+	return &testReply{request: request}
+}
+
+func (t *HealthIndicatorSuite) TestReadiness(c *C) {
+	registry := NewHealthIndicatorRegistry()
+	reply := newTestReply()
+	registry.ready(reply)
+	c.Assert(reply.statusCode, Equals, http.StatusServiceUnavailable)
+	c.Assert(reply.data.(*HealthReport).Checks[healthIndicatorLifecycle].Status, Equals, HealthStatusDown)
+
+	registry.SetServing(true)
+	reply = newTestReply()
+	registry.ready(reply)
+	c.Assert(reply.statusCode, Equals, 0)
+	c.Assert(reply.data.(*HealthReport).Status, Equals, HealthStatusUp)
+	c.Assert(registry.IsReady(), Equals, true)
+
+	//超时的检查项视为不健康,不会阻塞整个检查
+	err := registry.RegisterReadiness("slow", base.HealthIndicatorFunc(func(cxt context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}), time.Millisecond*20)
+	c.Assert(err, IsNil)
+	c.Assert(registry.RegisterReadiness("slow", base.HealthIndicatorFunc(func(cxt context.Context) error {
+		return nil
+	}), 0), NotNil)
+	start := time.Now()
+	reply = newTestReply()
+	registry.ready(reply)
+	c.Assert(time.Since(start) < time.Millisecond*500, Equals, true)
+	c.Assert(reply.statusCode, Equals, http.StatusServiceUnavailable)
+	report := reply.data.(*HealthReport)
+	c.Assert(report.Checks[healthIndicatorLifecycle].Status, Equals, HealthStatusUp)
+	c.Assert(report.Checks["slow"].Status, Equals, HealthStatusDown)
+	c.Assert(strings.Contains(report.Checks["slow"].Error, "超时"), Equals, true)
+
+	//就绪检查不影响存活检查
+	reply = newTestReply()
+	registry.live(reply)
+	c.Assert(reply.statusCode, Equals, 0)
+	registry.Unregister("slow")
+	c.Assert(registry.IsReady(), Equals, true)
+}
+
+func (t *HealthIndicatorSuite) TestIndicatorPanic(c *C) {
+	registry := NewHealthIndicatorRegistry()
+	c.Assert(registry.RegisterLiveness("panic", base.HealthIndicatorFunc(func(cxt context.Context) error {
+		panic("boom")
+	}), 0), IsNil)
+	report := registry.Liveness(context.Background())
+	c.Assert(report.Status, Equals, HealthStatusDown)
+	c.Assert(strings.Contains(report.Checks["panic"].Error, "boom"), Equals, true)
+}
@@ -1,14 +1,21 @@
 package serviceboot
 
 import (
+	"strings"
+
 	"github.com/coffeehc/httpx"
 	"github.com/coffeehc/httpx/pprof"
 	"github.com/coffeehc/microserviceboot/base"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-//NewHTTPServer 创建 http server
-func NewHTTPServer(config *httpx.Config, serviceInfo base.ServiceInfo) (httpx.Server, base.Error) {
+const (
+	metricsPath = "/metrics"
+	errorsPath  = "/errors"
+)
+
+//NewHTTPServer 创建 http server,healthRegistry 提供 /health/live 与 /health/ready,/errors 返回已声明的错误码
+func NewHTTPServer(config *httpx.Config, serviceInfo base.ServiceInfo, healthRegistry *HealthIndicatorRegistry) (httpx.Server, base.Error) {
 	httpServer := httpx.NewServer(config)
 	pprof.RegeditPprof(httpServer)
 	health := newHealth(serviceInfo)
@@ -16,9 +23,30 @@ func NewHTTPServer(config *httpx.Config, serviceInfo base.ServiceInfo) (httpx.Se
 	if err != nil {
 		return nil, base.NewErrorWrapper(0, "http server", err)
 	}
-	err = httpServer.RegisterHandler("/metrics", httpx.GET, prometheus.Handler())
+	err = httpServer.Register(HealthLivePath, httpx.GET, healthRegistry.live)
+	if err != nil {
+		return nil, base.NewErrorWrapper(0, "http server", err)
+	}
+	err = httpServer.Register(HealthReadyPath, httpx.GET, healthRegistry.ready)
+	if err != nil {
+		return nil, base.NewErrorWrapper(0, "http server", err)
+	}
+	err = httpServer.RegisterHandler(metricsPath, httpx.GET, prometheus.Handler())
+	if err != nil {
+		return nil, base.NewErrorWrapper(0, "http server", err)
+	}
+	err = httpServer.Register(errorsPath, httpx.GET, errorCatalog)
 	if err != nil {
 		return nil, base.NewErrorWrapper(0, "http server", err)
 	}
 	return httpServer, nil
 }
+
+func errorCatalog(reply httpx.Reply) {
+	reply.With(base.ErrorCatalog()).As(httpx.DefaultRenderJSON)
+}
+
+//IsManagementPath 是否是框架注册的健康检查,监控,错误码与 pprof 的路径,这些路径不应该被限流
+func IsManagementPath(path string) bool {
+	return path == "/health" || strings.HasPrefix(path, "/health/") || path == metricsPath || path == errorsPath || strings.HasPrefix(path, "/debug/pprof/")
+}
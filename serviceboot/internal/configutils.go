@@ -2,6 +2,7 @@ package internal
 
 import (
 	"flag"
+	"os"
 
 	"github.com/coffeehc/logger"
 	"github.com/coffeehc/microserviceboot/base"
@@ -17,19 +18,14 @@ type ServiceConfiguration interface {
 
 //LoadConfig 加载ServiceConfiguration的配置
 func LoadConfig(serviceConfig ServiceConfiguration) (string, base.Error) {
-	loaded := false
 	if base.IsDevModule() && *configPath == "./config.yml" {
-		err := base.LoadConfig("./config-dev.yml", serviceConfig)
-		if err == nil {
+		if _, statErr := os.Stat("./config-dev.yml"); statErr == nil {
 			*configPath = "./config-dev.yml"
-			loaded = true
 		}
 	}
-	if !loaded {
-		err := base.LoadConfig(*configPath, serviceConfig)
-		if err != nil {
-			return "", err
-		}
+	err := base.LoadConfig(*configPath, serviceConfig)
+	if err != nil {
+		return "", err
 	}
 	logger.Debug("serviceboot Config is %#v", serviceConfig.GetServiceConfig())
 	if serviceConfig.GetServiceConfig().ServiceInfo == nil {
@@ -44,14 +40,15 @@ func CheckServiceInfoConfig(serviceInfo base.ServiceInfo) base.Error {
 	if serviceInfo == nil {
 		return base.NewError(-1, errorScope, "没有配置 ServiceInfo")
 	}
-	if serviceInfo.GetServiceName() == "" {
-		return base.NewError(-1, errorScope, "没有配置 ServiceName")
-	}
-	if serviceInfo.GetServiceTag() == "" {
-		return base.NewError(-1, errorScope, "没有配置 ServiceTag")
-	}
-	if serviceInfo.GetVersion() == "" {
-		return base.NewError(-1, errorScope, "没有配置 ServiceVersion")
+	return base.ValidateConfig(serviceInfo)
+}
+
+//CheckServiceConfig -check-config 模式下校验 Service 自己的配置,Service 没有实现 base.ConfigCheckService 时跳过
+func CheckServiceConfig(service base.Service, configPath string) base.Error {
+	checker, ok := service.(base.ConfigCheckService)
+	if !ok {
+		logger.Warn("Service 没有实现 base.ConfigCheckService,跳过 Service 配置的校验")
+		return nil
 	}
-	return nil
+	return checker.CheckConfig(configPath)
 }
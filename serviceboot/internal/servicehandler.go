@@ -43,3 +43,11 @@ func StartService(service base.Service) (err base.Error) {
 	logger.Info("服务已正常启动")
 	return
 }
+
+//InitHealthIndicators 如果 service 实现了 base.HealthIndicatorService,注册服务自己的健康检查项
+func InitHealthIndicators(service base.Service, registry base.HealthRegistry) base.Error {
+	if healthIndicatorService, ok := service.(base.HealthIndicatorService); ok {
+		return healthIndicatorService.InitHealthIndicators(registry)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coffeehc/httpx"
+	"github.com/coffeehc/logger"
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/serviceboot"
+)
+
+const errScopeShutdown = "shutdown"
+
+//RequestTracker 记录进行中的请求,关闭时用于拒绝新请求并等待进行中的请求完成
+type RequestTracker struct {
+	mutex    *sync.Mutex
+	active   int
+	draining bool
+	idle     chan struct{}
+}
+
+//NewRequestTracker 创建一个RequestTracker
+func NewRequestTracker() *RequestTracker {
+	return &RequestTracker{
+		mutex: new(sync.Mutex),
+	}
+}
+
+//Filter httpx 的 Filter 实现,需要作为第一个 Filter 注册,grpc 与 http 请求都会经过此 Filter
+func (rt *RequestTracker) Filter(reply httpx.Reply, chain httpx.FilterChain) {
+	if !rt.enter() {
+		reply.SetHeader("Connection", "close")
+		reply.SetStatusCode(http.StatusServiceUnavailable).With(base.NewError(base.Error_System, errScopeShutdown, "服务正在关闭")).As(httpx.DefaultRenderJSON)
+		return
+	}
+	defer rt.leave()
+	chain(reply)
+}
+
+func (rt *RequestTracker) enter() bool {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	if rt.draining {
+		return false
+	}
+	rt.active++
+	return true
+}
+
+func (rt *RequestTracker) leave() {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	rt.active--
+	if rt.active == 0 && rt.idle != nil {
+		close(rt.idle)
+		rt.idle = nil
+	}
+}
+
+//Drain 停止接收新请求,并在 timeout 内等待进行中的请求完成,返回是否所有请求都已完成
+func (rt *RequestTracker) Drain(timeout time.Duration) bool {
+	rt.mutex.Lock()
+	rt.draining = true
+	if rt.active == 0 {
+		rt.mutex.Unlock()
+		return true
+	}
+	if rt.idle == nil {
+		rt.idle = make(chan struct{})
+	}
+	idle := rt.idle
+	logger.Info("等待%d个进行中的请求完成", rt.active)
+	rt.mutex.Unlock()
+	if timeout <= 0 {
+		return false
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-idle:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+//ActiveCount 当前进行中的请求数
+func (rt *RequestTracker) ActiveCount() int {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	return rt.active
+}
+
+//DrainService 按照 注销服务->等待注销传播->停止接收新请求->等待进行中的请求完成 的顺序执行关闭的前半段
+func DrainService(shutdownConfig *serviceboot.ShutdownConfig, deregisterFuncs []func(), tracker *RequestTracker) {
+	RunFuncs("deregister", deregisterFuncs)
+	if len(deregisterFuncs) > 0 && shutdownConfig.PropagationDelay > 0 {
+		logger.Info("服务已注销,等待%s后停止接收请求", shutdownConfig.PropagationDelay)
+		time.Sleep(shutdownConfig.PropagationDelay)
+	}
+	if tracker == nil {
+		return
+	}
+	if !tracker.Drain(shutdownConfig.DrainTimeout) {
+		logger.Warn("等待请求完成超时,仍有%d个请求未完成", tracker.ActiveCount())
+		return
+	}
+	logger.Info("进行中的请求已全部完成")
+}
+
+//RunFuncs 依次执行 funcs,单个函数 panic 不影响后续函数的执行
+func RunFuncs(name string, funcs []func()) {
+	for _, f := range funcs {
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					logger.Error("%s func painc :%s", name, err)
+				}
+			}()
+			f()
+		}()
+	}
+}
@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coffeehc/httpx"
+	"github.com/coffeehc/microserviceboot/serviceboot"
+	. "gopkg.in/check.v1"
+)
+
+type ShutdownSuite struct {
+}
+
+var _ = Suite(&ShutdownSuite{})
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+//testReply 只实现 Filter 使用的方法
+type testReply struct {
+	httpx.Reply
+	statusCode int
+	header     http.Header
+}
+
+func (reply *testReply) SetStatusCode(statusCode int) httpx.Reply {
+	reply.statusCode = statusCode
+	return reply
+}
+
+func (reply *testReply) SetHeader(key, value string) httpx.Reply {
+	reply.header.Set(key, value)
+	return reply
+}
+
+func (reply *testReply) With(data interface{}) httpx.Reply {
+	return reply
+}
+
+func (reply *testReply) As(render httpx.Render) httpx.Reply {
+	return reply
+}
+
+func (t *ShutdownSuite) TestRequestTracker(c *C) {
+	tracker := NewRequestTracker()
+	c.Assert(tracker.Drain(time.Second), Equals, true)
+	tracker = NewRequestTracker()
+	c.Assert(tracker.enter(), Equals, true)
+	c.Assert(tracker.enter(), Equals, true)
+	c.Assert(tracker.ActiveCount(), Equals, 2)
+	c.Assert(tracker.Drain(time.Millisecond*10), Equals, false)
+	c.Assert(tracker.enter(), Equals, false)
+	go func() {
+		time.Sleep(time.Millisecond * 20)
+		tracker.leave()
+		tracker.leave()
+	}()
+	c.Assert(tracker.Drain(time.Second), Equals, true)
+	c.Assert(tracker.ActiveCount(), Equals, 0)
+
+	reply := &testReply{header: make(http.Header)}
+	called := false
+	tracker.Filter(reply, func(reply httpx.Reply) {
+		called = true
+	})
+	c.Assert(called, Equals, false)
+	c.Assert(reply.statusCode, Equals, http.StatusServiceUnavailable)
+	c.Assert(reply.header.Get("Connection"), Equals, "close")
+}
+
+func (t *ShutdownSuite) TestDrainServiceOrder(c *C) {
+	tracker := NewRequestTracker()
+	c.Assert(tracker.enter(), Equals, true)
+	mutex := new(sync.Mutex)
+	events := make([]string, 0)
+	record := func(event string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		events = append(events, event)
+	}
+	deregister := func() {
+		//注销服务时还在接收新请求
+		if tracker.enter() {
+			tracker.leave()
+			record("deregister")
+		}
+	}
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		record("request done")
+		tracker.leave()
+	}()
+	start := time.Now()
+	DrainService(&serviceboot.ShutdownConfig{PropagationDelay: time.Millisecond * 20, DrainTimeout: time.Second}, []func(){deregister}, tracker)
+	record("drained")
+	c.Assert(time.Since(start) >= time.Millisecond*50, Equals, true)
+	c.Assert(events, DeepEquals, []string{"deregister", "request done", "drained"})
+	c.Assert(tracker.enter(), Equals, false)
+}
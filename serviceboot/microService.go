@@ -14,6 +14,13 @@ type MicroService interface {
 	GetService() base.Service
 	GetServiceInfo() base.ServiceInfo
 	AddCleanFunc(func())
+	//AddDeregisterFunc 添加服务注销函数,关闭时在停止接收请求之前执行
+	AddDeregisterFunc(func())
+}
+
+//HealthCheckProvider MicroService 可选实现的接口,提供注册到服务发现中心时使用的健康检查
+type HealthCheckProvider interface {
+	GetHealthCheck() *base.HealthCheck
 }
 
 //MicroServiceBuilder MicroService Builder function define
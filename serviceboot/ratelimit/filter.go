@@ -0,0 +1,29 @@
+package ratelimit
+
+import (
+	"github.com/coffeehc/httpx"
+	"github.com/coffeehc/microserviceboot/base/restbase"
+)
+
+//Handler 为 rest endpoint 添加限流,方法名为 "GET " 加上路径模板,超过限制时返回 Error_System_RateLimited,默认的 http 状态码为429
+// 使用注册时的路径模板而不是请求的路径,带参数的路径才能匹配 Methods 中的配置,也不会为每个路径创建限制器
+func (l *Limiter) Handler(method string, route string, handler httpx.RequestHandler) httpx.RequestHandler {
+	name := method + " " + route
+	return func(reply httpx.Reply) {
+		request := reply.GetRequest()
+		caller := ""
+		if l.config.TrustCallerHeader {
+			caller = request.Header.Get(HeaderCaller)
+		}
+		if caller == "" {
+			caller = hostOf(request.RemoteAddr)
+		}
+		release, err := l.Acquire(name, caller)
+		if err != nil {
+			reply.SetStatusCode(restbase.HTTPStatus(err.GetCode())).With(err).As(httpx.DefaultRenderJSON)
+			return
+		}
+		defer release()
+		handler(reply)
+	}
+}
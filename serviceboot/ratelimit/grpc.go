@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"net"
+	"strings"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+//UnaryServerInterceptor grpc 的限流拦截器,超过限制时返回 Error_System_RateLimited
+func (l *Limiter) UnaryServerInterceptor(cxt context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	release, err := l.Acquire(info.FullMethod, l.grpcCaller(cxt))
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return handler(cxt, req)
+}
+
+//StreamServerInterceptor grpc stream 的限流拦截器,并发限制覆盖整个 stream 的生命周期
+func (l *Limiter) StreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	release, err := l.Acquire(info.FullMethod, l.grpcCaller(ss.Context()))
+	if err != nil {
+		return err
+	}
+	defer release()
+	return handler(srv, ss)
+}
+
+func (l *Limiter) grpcCaller(cxt context.Context) string {
+	if md, ok := metadata.FromIncomingContext(cxt); ok && l.config.TrustCallerHeader {
+		if callers := md[strings.ToLower(HeaderCaller)]; len(callers) > 0 && callers[0] != "" {
+			return callers[0]
+		}
+	}
+	if p, ok := peer.FromContext(cxt); ok && p.Addr != nil {
+		return hostOf(p.Addr.String())
+	}
+	return ""
+}
+
+func hostOf(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
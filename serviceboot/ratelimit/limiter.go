@@ -0,0 +1,253 @@
+package ratelimit
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/coffeehc/microserviceboot/base"
+)
+
+const (
+	errScopeRateLimit = "rate limit"
+
+	defaultInitialLimit = 20
+	defaultMinLimit     = 1
+	defaultMaxLimit     = 1000
+	defaultSmoothing    = 0.2
+	defaultMinRTTWindow = time.Second * 30
+	defaultMaxLimiters  = 10000
+	minGradient         = 0.5
+)
+
+//HeaderCaller 调用方身份的 Header,grpc 使用小写的 metadata,只在 TrustCallerHeader 为 true 时使用,否则使用调用方的 IP
+const HeaderCaller = "X-Caller"
+
+//Config 服务端限流配置
+type Config struct {
+	//Default 没有单独配置的方法共享的限制,为 nil 时不限制
+	Default *LimitConfig `yaml:"default"`
+	//Methods 按方法配置的限制,grpc 的 key 为完整的方法名,如 /package.Service/Method,
+	// rest 的 key 为 "GET " 加上注册 endpoint 时的路径模板,如 "GET /users/{id}",只支持精确匹配
+	Methods map[string]*LimitConfig `yaml:"methods"`
+	//PerCaller 为 true 时每个调用方使用独立的限制
+	PerCaller bool `yaml:"per_caller"`
+	//TrustCallerHeader 为 true 时使用 HeaderCaller 作为调用方身份,Header 可以被调用方任意设置,
+	// 只应该在请求经过认证的网关时开启,默认使用调用方的 IP
+	TrustCallerHeader bool `yaml:"trust_caller_header"`
+	//MaxLimiters 最多保留的限制器数,超过时淘汰最久没有使用的调用方的限制器,默认10000
+	MaxLimiters int `yaml:"max_limiters" validate:"min=0"`
+}
+
+//LimitConfig 限流与并发限制
+type LimitConfig struct {
+	//Rate 令牌桶每秒产生的令牌数,0表示不限流
+	Rate float64 `yaml:"rate" validate:"min=0"`
+	//Burst 令牌桶的容量,默认为 Rate 向上取整
+	Burst int `yaml:"burst" validate:"min=0"`
+	//Concurrency 自适应并发限制,为 nil 时不限制并发
+	Concurrency *ConcurrencyConfig `yaml:"concurrency"`
+}
+
+//ConcurrencyConfig 基于延迟梯度的自适应并发限制,延迟相对最小延迟升高时降低并发上限,否则逐步提高
+type ConcurrencyConfig struct {
+	//InitialLimit 初始的并发上限,默认20
+	InitialLimit int `yaml:"initial_limit" validate:"min=0"`
+	//MinLimit 并发上限的下限,默认1
+	MinLimit int `yaml:"min_limit" validate:"min=0"`
+	//MaxLimit 并发上限的上限,默认1000
+	MaxLimit int `yaml:"max_limit" validate:"min=0"`
+	//Smoothing 每次调整并发上限的平滑系数,默认0.2
+	Smoothing float64 `yaml:"smoothing" validate:"min=0,max=1"`
+	//MinRTTWindow 重新探测最小延迟的周期,默认30s
+	MinRTTWindow time.Duration `yaml:"min_rtt_window" validate:"min=0s"`
+}
+
+//Limiter 按方法与调用方管理的限制器,可以并发使用
+type Limiter struct {
+	config      *Config
+	maxLimiters int
+	mutex       *sync.Mutex
+	limiters    map[string]*list.Element
+	//lru 最近使用的限制器在前面
+	lru *list.List
+}
+
+//NewLimiter 创建 Limiter
+func NewLimiter(config *Config) *Limiter {
+	maxLimiters := config.MaxLimiters
+	if maxLimiters <= 0 {
+		maxLimiters = defaultMaxLimiters
+	}
+	return &Limiter{
+		config:      config,
+		maxLimiters: maxLimiters,
+		mutex:       new(sync.Mutex),
+		limiters:    make(map[string]*list.Element),
+		lru:         list.New(),
+	}
+}
+
+//Acquire 获取调用许可,返回错误时拒绝调用,错误码为 Error_System_RateLimited,
+// 获取成功时调用结束后必须调用 release
+func (l *Limiter) Acquire(method string, caller string) (release func(), err base.Error) {
+	key := method
+	limitConfig, ok := l.config.Methods[method]
+	if !ok {
+		key = ""
+		limitConfig = l.config.Default
+	}
+	if limitConfig == nil {
+		return func() {}, nil
+	}
+	if l.config.PerCaller {
+		key = key + "|" + caller
+	}
+	current := l.getLimiter(key, limitConfig)
+	if current.bucket != nil && !current.bucket.allow() {
+		return nil, base.NewError(base.Error_System_RateLimited, errScopeRateLimit, fmt.Sprintf("%s 超过限流", method))
+	}
+	if current.concurrency == nil {
+		return func() {}, nil
+	}
+	release, ok = current.concurrency.acquire()
+	if !ok {
+		return nil, base.NewError(base.Error_System_RateLimited, errScopeRateLimit, fmt.Sprintf("%s 超过并发限制", method))
+	}
+	return release, nil
+}
+
+func (l *Limiter) getLimiter(key string, config *LimitConfig) *limiter {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if element, ok := l.limiters[key]; ok {
+		l.lru.MoveToFront(element)
+		return element.Value.(*limiter)
+	}
+	current := newLimiter(key, config)
+	l.limiters[key] = l.lru.PushFront(current)
+	for l.lru.Len() > l.maxLimiters {
+		oldest := l.lru.Back()
+		l.lru.Remove(oldest)
+		delete(l.limiters, oldest.Value.(*limiter).key)
+	}
+	return current
+}
+
+type limiter struct {
+	key         string
+	bucket      *tokenBucket
+	concurrency *concurrencyLimiter
+}
+
+func newLimiter(key string, config *LimitConfig) *limiter {
+	l := &limiter{key: key}
+	if config.Rate > 0 {
+		burst := float64(config.Burst)
+		if burst <= 0 {
+			burst = math.Ceil(config.Rate)
+		}
+		l.bucket = &tokenBucket{mutex: new(sync.Mutex), rate: config.Rate, burst: burst, tokens: burst, last: time.Now()}
+	}
+	if config.Concurrency != nil {
+		l.concurrency = newConcurrencyLimiter(config.Concurrency)
+	}
+	return l
+}
+
+type tokenBucket struct {
+	mutex  *sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+type concurrencyLimiter struct {
+	mutex        *sync.Mutex
+	minLimit     float64
+	maxLimit     float64
+	smoothing    float64
+	minRTTWindow time.Duration
+	limit        float64
+	inflight     int
+	minRTT       time.Duration
+	minRTTExpire time.Time
+}
+
+func newConcurrencyLimiter(config *ConcurrencyConfig) *concurrencyLimiter {
+	l := &concurrencyLimiter{
+		mutex:        new(sync.Mutex),
+		limit:        defaultInitialLimit,
+		minLimit:     defaultMinLimit,
+		maxLimit:     defaultMaxLimit,
+		smoothing:    defaultSmoothing,
+		minRTTWindow: defaultMinRTTWindow,
+	}
+	if config.InitialLimit > 0 {
+		l.limit = float64(config.InitialLimit)
+	}
+	if config.MinLimit > 0 {
+		l.minLimit = float64(config.MinLimit)
+	}
+	if config.MaxLimit > 0 {
+		l.maxLimit = float64(config.MaxLimit)
+	}
+	if config.Smoothing > 0 {
+		l.smoothing = config.Smoothing
+	}
+	if config.MinRTTWindow > 0 {
+		l.minRTTWindow = config.MinRTTWindow
+	}
+	return l
+}
+
+func (l *concurrencyLimiter) acquire() (release func(), ok bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.inflight >= int(l.limit) {
+		return nil, false
+	}
+	l.inflight++
+	start := time.Now()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.release(time.Since(start))
+		})
+	}, true
+}
+
+//release 按延迟梯度调整并发上限: newLimit = limit * minRTT / rtt + sqrt(limit)
+func (l *concurrencyLimiter) release(rtt time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.inflight--
+	if rtt <= 0 {
+		return
+	}
+	now := time.Now()
+	if l.minRTT == 0 || rtt < l.minRTT || now.After(l.minRTTExpire) {
+		l.minRTT = rtt
+		l.minRTTExpire = now.Add(l.minRTTWindow)
+	}
+	gradient := math.Max(minGradient, math.Min(1, float64(l.minRTT)/float64(rtt)))
+	newLimit := l.limit*gradient + math.Sqrt(l.limit)
+	l.limit = l.limit*(1-l.smoothing) + newLimit*l.smoothing
+	l.limit = math.Max(l.minLimit, math.Min(l.maxLimit, l.limit))
+}
@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/coffeehc/httpx"
+	"github.com/coffeehc/microserviceboot/base"
+	. "gopkg.in/check.v1"
+)
+
+type LimiterSuite struct {
+}
+
+var _ = Suite(&LimiterSuite{})
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+func (t *LimiterSuite) TestRateLimit(c *C) {
+	limiter := NewLimiter(&Config{
+		Methods:   map[string]*LimitConfig{"/test.Service/Get": {Rate: 1, Burst: 2}},
+		PerCaller: true,
+	})
+	for i := 0; i < 2; i++ {
+		release, err := limiter.Acquire("/test.Service/Get", "a")
+		c.Assert(err, IsNil)
+		release()
+	}
+	_, err := limiter.Acquire("/test.Service/Get", "a")
+	c.Assert(base.IsRateLimitedError(err), Equals, true)
+	//每个调用方独立限流
+	_, err = limiter.Acquire("/test.Service/Get", "b")
+	c.Assert(err, IsNil)
+	//没有配置的方法不限制
+	_, err = limiter.Acquire("/test.Service/List", "a")
+	c.Assert(err, IsNil)
+}
+
+func (t *LimiterSuite) TestConcurrencyLimit(c *C) {
+	l := newConcurrencyLimiter(&ConcurrencyConfig{InitialLimit: 10, MaxLimit: 20})
+	releases := make([]func(), 0)
+	for i := 0; i < 10; i++ {
+		release, ok := l.acquire()
+		c.Assert(ok, Equals, true)
+		releases = append(releases, release)
+	}
+	_, ok := l.acquire()
+	c.Assert(ok, Equals, false)
+	for _, release := range releases {
+		release()
+	}
+	//延迟升高时降低并发上限
+	l.minRTT = time.Millisecond
+	l.minRTTExpire = time.Now().Add(time.Minute)
+	for i := 0; i < 20; i++ {
+		l.release(time.Second)
+		l.inflight++
+	}
+	c.Assert(l.limit < 10, Equals, true)
+}
+
+func (t *LimiterSuite) TestEviction(c *C) {
+	limiter := NewLimiter(&Config{
+		Default:     &LimitConfig{Rate: 1, Burst: 1},
+		PerCaller:   true,
+		MaxLimiters: 2,
+	})
+	_, err := limiter.Acquire("/test.Service/Get", "a")
+	c.Assert(err, IsNil)
+	_, err = limiter.Acquire("/test.Service/Get", "b")
+	c.Assert(err, IsNil)
+	_, err = limiter.Acquire("/test.Service/Get", "a")
+	c.Assert(base.IsRateLimitedError(err), Equals, true)
+	//超过上限时淘汰最久没有使用的 b
+	_, err = limiter.Acquire("/test.Service/Get", "c")
+	c.Assert(err, IsNil)
+	c.Assert(limiter.limiters, HasLen, 2)
+	c.Assert(limiter.lru.Len(), Equals, 2)
+	_, ok := limiter.limiters["|b"]
+	c.Assert(ok, Equals, false)
+	_, err = limiter.Acquire("/test.Service/Get", "a")
+	c.Assert(base.IsRateLimitedError(err), Equals, true)
+}
+
+//testReply 只实现限流使用的方法
+type testReply struct {
+	httpx.Reply
+	request    *http.Request
+	statusCode int
+}
+
+func (reply *testReply) GetRequest() *http.Request {
+	return reply.request
+}
+
+func (reply *testReply) SetStatusCode(statusCode int) httpx.Reply {
+	reply.statusCode = statusCode
+	return reply
+}
+
+func (reply *testReply) With(data interface{}) httpx.Reply {
+	return reply
+}
+
+func (reply *testReply) As(render httpx.Render) httpx.Reply {
+	return reply
+}
+
+func newTestReply(path string, caller string) *testReply {
+	request, _ := http.NewRequest(http.MethodGet, path, nil)
+	request.RemoteAddr = "10.0.0.1:1234"
+	request.Header.Set(HeaderCaller, caller)
+	return &testReply{request: request}
+}
+
+func (t *LimiterSuite) TestHandler(c *C) {
+	limiter := NewLimiter(&Config{
+		Methods:   map[string]*LimitConfig{"GET /users/{id}": {Rate: 1, Burst: 1}},
+		PerCaller: true,
+	})
+	calls := 0
+	handler := limiter.Handler(http.MethodGet, "/users/{id}", func(reply httpx.Reply) {
+		calls++
+	})
+	//不同的路径参数使用同一个路径模板的配置
+	handler(newTestReply("/users/1", "a"))
+	reply := newTestReply("/users/2", "a")
+	handler(reply)
+	c.Assert(calls, Equals, 1)
+	c.Assert(reply.statusCode, Equals, http.StatusTooManyRequests)
+	//默认不信任调用方设置的 Header,同一个 IP 不能通过修改 Header 绕过限流
+	reply = newTestReply("/users/3", "b")
+	handler(reply)
+	c.Assert(calls, Equals, 1)
+	c.Assert(limiter.limiters, HasLen, 1)
+}
@@ -1,10 +1,15 @@
 package restboot
 
-import "github.com/coffeehc/microserviceboot/serviceboot"
+import (
+	"github.com/coffeehc/microserviceboot/base/restbase"
+	"github.com/coffeehc/microserviceboot/serviceboot"
+)
 
 //Config restboot config
 type Config struct {
-	ServiceConfig *serviceboot.ServiceConfig `yaml:"service_config"`
+	ServiceConfig *serviceboot.ServiceConfig `yaml:"service_config" validate:"required"`
+	//ErrorStatus 错误码到 http 状态码的映射,覆盖默认的映射
+	ErrorStatus []restbase.StatusRule `yaml:"error_status"`
 }
 
 //GetServiceConfig 实现的 ServiceConfiguration 的接口
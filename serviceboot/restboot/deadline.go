@@ -0,0 +1,41 @@
+package restboot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/coffeehc/httpx"
+	"github.com/coffeehc/microserviceboot/base"
+)
+
+//DeadlineFilter 读取调用方通过 base.HeaderRequestTimeout 传递的剩余时间,超时后取消请求的 context,
+// 处理请求时使用 reply.GetRequest().Context() 调用下游可以继续传递 deadline,剩余时间已经用完的请求直接拒绝
+func DeadlineFilter(reply httpx.Reply, chain httpx.FilterChain) {
+	deadlineFilter(0, reply, chain)
+}
+
+//NewDeadlineFilter 与 DeadlineFilter 相同,剩余时间小于 minTimeout 的请求也直接拒绝
+func NewDeadlineFilter(minTimeout time.Duration) httpx.Filter {
+	return func(reply httpx.Reply, chain httpx.FilterChain) {
+		deadlineFilter(minTimeout, reply, chain)
+	}
+}
+
+func deadlineFilter(minTimeout time.Duration, reply httpx.Reply, chain httpx.FilterChain) {
+	request := reply.GetRequest()
+	timeout, ok := base.ParseRequestTimeout(request.Header.Get(base.HeaderRequestTimeout))
+	if !ok {
+		chain(reply)
+		return
+	}
+	if timeout <= 0 || timeout < minTimeout {
+		err := base.NewError(base.Error_System_DeadlineTooShort, errScopeRest, fmt.Sprintf("请求的剩余时间 %s 不足以完成调用", timeout))
+		RenderError(reply, err)
+		return
+	}
+	cxt, cancel := context.WithTimeout(request.Context(), timeout)
+	defer cancel()
+	*request = *request.WithContext(cxt)
+	chain(reply)
+}
@@ -0,0 +1,88 @@
+package restboot
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/coffeehc/httpx"
+	"github.com/coffeehc/microserviceboot/base"
+	. "gopkg.in/check.v1"
+)
+
+type DeadlineSuite struct {
+}
+
+var _ = Suite(&DeadlineSuite{})
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+//testReply 只实现 DeadlineFilter 与 RenderError 使用的方法
+type testReply struct {
+	httpx.Reply
+	request    *http.Request
+	statusCode int
+}
+
+func (reply *testReply) GetRequest() *http.Request {
+	return reply.request
+}
+
+func (reply *testReply) SetStatusCode(statusCode int) httpx.Reply {
+	reply.statusCode = statusCode
+	return reply
+}
+
+func (reply *testReply) With(data interface{}) httpx.Reply {
+	return reply
+}
+
+func (reply *testReply) As(render httpx.Render) httpx.Reply {
+	return reply
+}
+
+func newTestReply(timeout string) *testReply {
+	request, _ := http.NewRequest(http.MethodGet, "/test", nil)
+	if timeout != "" {
+		request.Header.Set(base.HeaderRequestTimeout, timeout)
+	}
+	return &testReply{request: request}
+}
+
+func (t *DeadlineSuite) TestDeadlineFilter(c *C) {
+	filter := NewDeadlineFilter(time.Millisecond * 100)
+	called := false
+	var deadline time.Time
+	var hasDeadline bool
+	chain := func(reply httpx.Reply) {
+		called = true
+		deadline, hasDeadline = reply.GetRequest().Context().Deadline()
+	}
+	//没有传递剩余时间
+	filter(newTestReply(""), chain)
+	c.Assert(called, Equals, true)
+	c.Assert(hasDeadline, Equals, false)
+
+	called = false
+	filter(newTestReply("500"), chain)
+	c.Assert(called, Equals, true)
+	c.Assert(hasDeadline, Equals, true)
+	c.Assert(deadline.Sub(time.Now()) <= time.Millisecond*500, Equals, true)
+
+	//剩余时间小于 MinTimeout
+	called = false
+	reply := newTestReply("50")
+	filter(reply, chain)
+	c.Assert(called, Equals, false)
+	c.Assert(reply.statusCode, Not(Equals), 0)
+
+	//剩余时间已经用完
+	reply = newTestReply("0")
+	DeadlineFilter(reply, chain)
+	c.Assert(called, Equals, false)
+	c.Assert(reply.statusCode, Not(Equals), 0)
+	DeadlineFilter(newTestReply("50"), chain)
+	c.Assert(called, Equals, true)
+}
@@ -0,0 +1,46 @@
+package restboot
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/coffeehc/httpx"
+	"github.com/coffeehc/microserviceboot/base/restbase"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	endpointRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "microservice",
+		Subsystem: "http_server",
+		Name:      "requests_total",
+		Help:      "Total number of requests handled by rest endpoints.",
+	}, []string{"method", "route", "status_class"})
+	endpointDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "microservice",
+		Subsystem: "http_server",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of requests handled by rest endpoints.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status_class"})
+)
+
+func init() {
+	prometheus.MustRegister(endpointRequests, endpointDuration)
+}
+
+//instrumentEndpoint 记录 endpoint 的请求数与延迟,route 使用 EndpointMeta.Path 的模板,避免路径参数导致 label 过多
+func instrumentEndpoint(metadata restbase.EndpointMeta, handler httpx.RequestHandler) httpx.RequestHandler {
+	method := string(metadata.Method)
+	return func(reply httpx.Reply) {
+		start := time.Now()
+		statusCode := http.StatusInternalServerError
+		defer func() {
+			statusClass := restbase.StatusClass(statusCode)
+			endpointRequests.WithLabelValues(method, metadata.Path, statusClass).Inc()
+			endpointDuration.WithLabelValues(method, metadata.Path, statusClass).Observe(time.Since(start).Seconds())
+		}()
+		handler(reply)
+		statusCode = reply.GetStatusCode()
+	}
+}
@@ -10,16 +10,21 @@ import (
 	"github.com/coffeehc/microserviceboot/base/restbase"
 	"github.com/coffeehc/microserviceboot/serviceboot"
 	"github.com/coffeehc/microserviceboot/serviceboot/internal"
+	"github.com/coffeehc/microserviceboot/serviceboot/ratelimit"
 )
 
 //RestMicroServiceBuilder rest 服务的MicroServiceBuilder
 var RestMicroServiceBuilder serviceboot.MicroServiceBuilder = microServiceBuild
 
 type _RestMicroService struct {
-	config     *Config
-	httpServer httpx.Server
-	service    restbase.RestService
-	cleanFuncs []func()
+	config          *Config
+	httpServer      httpx.Server
+	service         restbase.RestService
+	cleanFuncs      []func()
+	deregisterFuncs []func()
+	requestTracker  *internal.RequestTracker
+	healthRegistry  *serviceboot.HealthIndicatorRegistry
+	limiter         *ratelimit.Limiter
 }
 
 func microServiceBuild(service base.Service) (serviceboot.MicroService, base.Error) {
@@ -28,8 +33,11 @@ func microServiceBuild(service base.Service) (serviceboot.MicroService, base.Err
 		return nil, base.NewError(-1, "RestMicroService build", "service 不是Rest 服务")
 	}
 	return &_RestMicroService{
-		service:    restService,
-		cleanFuncs: make([]func(), 0),
+		service:         restService,
+		cleanFuncs:      make([]func(), 0),
+		deregisterFuncs: make([]func(), 0),
+		requestTracker:  internal.NewRequestTracker(),
+		healthRegistry:  serviceboot.NewHealthIndicatorRegistry(),
 	}, nil
 }
 
@@ -44,6 +52,7 @@ func (ms *_RestMicroService) Init(cxt context.Context) (*serviceboot.ServiceConf
 		return nil, err
 	}
 	ms.config = config
+	restbase.AddStatusRules(config.ErrorStatus...)
 	serviceConfig := config.GetServiceConfig()
 	err = internal.CheckServiceInfoConfig(ms.GetServiceInfo())
 	if err != nil {
@@ -53,15 +62,31 @@ func (ms *_RestMicroService) Init(cxt context.Context) (*serviceboot.ServiceConf
 	if err != nil {
 		return nil, err
 	}
-	httpServer, err := serviceboot.NewHTTPServer(httpServerConfig, ms.GetServiceInfo())
+	if base.IsCheckConfigModule() {
+		//只校验配置,不初始化 Service
+		err = internal.CheckServiceConfig(ms.service, configPath)
+		if err != nil {
+			return nil, err
+		}
+		return config.GetServiceConfig(), nil
+	}
+	httpServer, err := serviceboot.NewHTTPServer(httpServerConfig, ms.GetServiceInfo(), ms.healthRegistry)
 	if err != nil {
 		return nil, err
 	}
 	ms.httpServer = httpServer
-	err = ms.service.Init(cxt, configPath, httpServer)
+	err = ms.service.Init(base.WithCleanFuncRegister(cxt, ms), configPath, httpServer)
 	if err != nil {
 		return nil, err
 	}
+	err = internal.InitHealthIndicators(ms.service, ms.healthRegistry)
+	if err != nil {
+		return nil, err
+	}
+	if serviceConfig.RateLimit != nil {
+		//只对 Service 的 endpoint 限流,管理接口不受影响
+		ms.limiter = ratelimit.NewLimiter(serviceConfig.RateLimit)
+	}
 	err = ms.registerEndpoints()
 	if err != nil {
 		return nil, err
@@ -76,6 +101,9 @@ func (ms *_RestMicroService) Init(cxt context.Context) (*serviceboot.ServiceConf
 			ms.httpServer.AddFirstFilter("/*", httpx.AccessLogFilter)
 		}
 	}
+	ms.httpServer.AddFirstFilter("/*", NewDeadlineFilter(serviceConfig.MinRequestTimeout))
+	ms.httpServer.AddFirstFilter("/*", TracingFilter)
+	ms.httpServer.AddFirstFilter("/*", ms.requestTracker.Filter)
 	return serviceConfig, nil
 }
 
@@ -91,9 +119,15 @@ func (ms *_RestMicroService) Start(cxt context.Context) base.Error {
 			panic(base.NewError(base.Error_System, "RestMicroService Start", err.Error()))
 		}
 	}()
+	ms.healthRegistry.SetServing(true)
 	return nil
 }
 
+//GetHealthCheck 实现 serviceboot.HealthCheckProvider,使用就绪检查作为注册中心的健康检查
+func (ms *_RestMicroService) GetHealthCheck() *base.HealthCheck {
+	return ms.healthRegistry.GetHealthCheck()
+}
+
 func (ms *_RestMicroService) GetService() base.Service {
 	return ms.service
 }
@@ -102,21 +136,18 @@ func (ms *_RestMicroService) AddCleanFunc(f func()) {
 	ms.cleanFuncs = append(ms.cleanFuncs, f)
 }
 
+func (ms *_RestMicroService) AddDeregisterFunc(f func()) {
+	ms.deregisterFuncs = append(ms.deregisterFuncs, f)
+}
+
 func (ms *_RestMicroService) Stop() {
+	ms.healthRegistry.SetServing(false)
+	internal.DrainService(ms.config.GetServiceConfig().GetShutdownConfig(), ms.deregisterFuncs, ms.requestTracker)
 	if ms.httpServer != nil {
 		ms.httpServer.Stop()
 	}
 	internal.StopService(ms.service)
-	for _, f := range ms.cleanFuncs {
-		func() {
-			defer func() {
-				if err := recover(); err != nil {
-					logger.Error("clean func painc :%s", err)
-				}
-			}()
-			f()
-		}()
-	}
+	internal.RunFuncs("clean", ms.cleanFuncs)
 }
 
 func buildAPIDefineRequestHandler(serviceInfo base.ServiceInfo) httpx.RequestHandler {
@@ -128,7 +159,11 @@ func buildAPIDefineRequestHandler(serviceInfo base.ServiceInfo) httpx.RequestHan
 func (ms *_RestMicroService) registerEndpoint(endPoint restbase.Endpoint) base.Error {
 	metadata := endPoint.Metadata
 	logger.Debug("register endpoint [%s] %s %s", metadata.Method, metadata.Path, metadata.Description)
-	err := ms.httpServer.Register(metadata.Path, metadata.Method, endPoint.HandlerFunc)
+	handler := endPoint.HandlerFunc
+	if ms.limiter != nil {
+		handler = ms.limiter.Handler(string(metadata.Method), metadata.Path, handler)
+	}
+	err := ms.httpServer.Register(metadata.Path, metadata.Method, instrumentEndpoint(metadata, traceEndpoint(metadata, handler)))
 	if err != nil {
 		return base.NewError(base.Error_System, "RestMicroService register", err.Error())
 	}
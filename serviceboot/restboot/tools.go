@@ -2,6 +2,7 @@ package restboot
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -9,21 +10,20 @@ import (
 	"github.com/coffeehc/httpx"
 	"github.com/coffeehc/logger"
 	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/base/restbase"
 	"github.com/golang/protobuf/proto"
 	"github.com/pquerna/ffjson/ffjson"
 )
 
 const errScopeRest = "restRequest"
 
-//ErrorRecover 对 err 进行转换
+//ErrorRecover 对 err 进行转换,http 状态码由 restbase.HTTPStatus 根据错误码决定
 func ErrorRecover(reply httpx.Reply) {
 	if err := recover(); err != nil {
 		logger.Error("处理请求,发生错误:%s", err)
 		var errorResponse base.Error
-		statusCode := http.StatusInternalServerError
 		switch e := err.(type) {
 		case base.Error:
-			statusCode = http.StatusBadRequest
 			errorResponse = e
 		case string:
 			errorResponse = base.NewError(base.Error_System, errScopeRest, e)
@@ -32,11 +32,16 @@ func ErrorRecover(reply httpx.Reply) {
 		default:
 			errorResponse = base.NewError(base.Error_System, errScopeRest, fmt.Sprintf("%#v", err))
 		}
-		//暂时统一按照400处理
-		reply.SetStatusCode(statusCode).With(errorResponse).As(httpx.DefaultRenderJSON)
+		RenderError(reply, errorResponse)
 	}
 }
 
+//RenderError 以 json 返回 err,包括 cause,metadata,localized 与 stack,http 状态码由 restbase.HTTPStatus 根据错误码决定,
+// restclient 会解析为原来的 base.Error
+func RenderError(reply httpx.Reply, err base.Error) {
+	reply.SetStatusCode(restbase.HTTPStatus(err.GetCode())).With(json.RawMessage(base.ErrorToJson(err))).As(httpx.DefaultRenderJSON)
+}
+
 //UnmarshalWhitJSON 将 request 的 Json内容解析为 对象
 func UnmarshalWhitJSON(request *http.Request, data interface{}) {
 	dataBytes, err := ioutil.ReadAll(request.Body)
@@ -0,0 +1,57 @@
+package restboot
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/coffeehc/httpx"
+	"github.com/coffeehc/microserviceboot/base/restbase"
+	"github.com/coffeehc/microserviceboot/serviceboot"
+	"github.com/coffeehc/microserviceboot/tracing"
+)
+
+//TracingFilter 从 traceparent Header 解析上游的 trace context 并创建 server span,健康检查与监控的请求不记录,
+// span 放入请求的 context,处理请求时使用 reply.GetRequest().Context() 调用下游可以继续传递,
+// span 的名称在匹配到 endpoint 后由 traceEndpoint 设置为路由模板,没有匹配到 endpoint 时只有 http method
+func TracingFilter(reply httpx.Reply, chain httpx.FilterChain) {
+	request := reply.GetRequest()
+	if serviceboot.IsManagementPath(request.URL.Path) {
+		chain(reply)
+		return
+	}
+	cxt := request.Context()
+	if sc, ok := tracing.ParseTraceparent(request.Header.Get(tracing.HeaderTraceparent), request.Header.Get(tracing.HeaderTracestate)); ok {
+		cxt = tracing.ContextWithRemoteSpanContext(cxt, sc)
+	}
+	cxt, span := tracing.StartSpan(cxt, request.Method, tracing.SpanKindServer)
+	defer span.End()
+	span.SetAttribute("http.method", request.Method)
+	span.SetAttribute("http.target", request.URL.Path)
+	*request = *request.WithContext(cxt)
+	defer func() {
+		if err := recover(); err != nil {
+			span.SetError(fmt.Errorf("%v", err))
+			panic(err)
+		}
+	}()
+	chain(reply)
+	statusCode := reply.GetStatusCode()
+	span.SetAttribute("http.status_code", fmt.Sprint(statusCode))
+	if statusCode >= http.StatusInternalServerError {
+		span.SetError(fmt.Errorf("http status %d", statusCode))
+	} else {
+		span.SetError(nil)
+	}
+}
+
+//traceEndpoint 使用 EndpointMeta.Path 的模板作为 server span 的名称,避免路径参数导致 span 名称过多,
+// 原始路径只记录在 http.target 属性中
+func traceEndpoint(metadata restbase.EndpointMeta, handler httpx.RequestHandler) httpx.RequestHandler {
+	name := string(metadata.Method) + " " + metadata.Path
+	return func(reply httpx.Reply) {
+		span := tracing.SpanFromContext(reply.GetRequest().Context())
+		span.SetName(name)
+		span.SetAttribute("http.route", metadata.Path)
+		handler(reply)
+	}
+}
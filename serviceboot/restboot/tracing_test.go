@@ -0,0 +1,55 @@
+package restboot
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/coffeehc/httpx"
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/base/restbase"
+	"github.com/coffeehc/microserviceboot/tracing"
+	. "gopkg.in/check.v1"
+)
+
+type TracingSuite struct {
+}
+
+var _ = Suite(&TracingSuite{})
+
+func (reply *testReply) GetStatusCode() int {
+	if reply.statusCode == 0 {
+		return http.StatusOK
+	}
+	return reply.statusCode
+}
+
+func (t *TracingSuite) TestSpanNameUsesRoute(c *C) {
+	buffer := new(bytes.Buffer)
+	tracing.RegisterExporter("restboot_test", func(config *tracing.Config) (tracing.Exporter, base.Error) {
+		return tracing.NewWriterExporter(buffer), nil
+	})
+	tracer, err := tracing.NewTracer(&base.SimpleServiceInfo{ServiceName: "demo", Version: "1.0"}, &tracing.Config{Exporter: "restboot_test"})
+	c.Assert(err, IsNil)
+	origin := tracing.GetTracer()
+	tracing.SetTracer(tracer)
+	defer tracing.SetTracer(origin)
+
+	handler := traceEndpoint(restbase.EndpointMeta{Path: "/users/{id}", Method: httpx.GET}, func(reply httpx.Reply) {})
+	for _, path := range []string{"/users/123", "/users/456"} {
+		request, _ := http.NewRequest(http.MethodGet, path, nil)
+		TracingFilter(&testReply{request: request}, httpx.FilterChain(handler))
+	}
+	tracer.Close()
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	c.Assert(lines, HasLen, 2)
+	for i, path := range []string{"/users/123", "/users/456"} {
+		span := &tracing.SpanData{}
+		c.Assert(json.Unmarshal([]byte(lines[i]), span), IsNil)
+		c.Assert(span.Name, Equals, "GET /users/{id}")
+		c.Assert(span.Attributes["http.route"], Equals, "/users/{id}")
+		c.Assert(span.Attributes["http.target"], Equals, path)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/coffeehc/commons"
 	"github.com/coffeehc/logger"
 	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/tracing"
 )
 
 //ServiceLaunch Service 启动
@@ -35,6 +36,10 @@ func ServiceLaunch(cxt context.Context, service base.Service, serviceBuilder Mic
 		launchError(err)
 		return
 	}
+	if base.IsCheckConfigModule() {
+		fmt.Printf("服务[%s]配置校验通过\n", microService.GetServiceInfo().GetServiceName())
+		return
+	}
 	defer func() {
 		microService.Stop()
 		fmt.Printf("服务[%s]关闭\n", microService.GetServiceInfo().GetServiceName())
@@ -50,6 +55,10 @@ func Launch(cxt context.Context, service base.Service, serviceBuilder MicroServi
 		logger.Error("service is nil")
 		return nil, base.NewError(base.Error_System, "Launch", "service is nil")
 	}
+	if err := base.CheckErrorCodes(); err != nil {
+		logger.Error("错误码声明冲突:%s", err)
+		return nil, err
+	}
 	microService, err := serviceBuilder(service)
 	if err != nil {
 		return nil, err
@@ -60,10 +69,24 @@ func Launch(cxt context.Context, service base.Service, serviceBuilder MicroServi
 		return nil, initErr
 	}
 	logger.Info("Service inited")
+	if base.IsCheckConfigModule() {
+		//只校验配置,不加载动态配置也不启动服务
+		return microService, nil
+	}
+	err = startDynamicConfig(cxt, microService)
+	if err != nil {
+		return nil, err
+	}
 	serviceInfo := microService.GetServiceInfo()
 	if serviceInfo == nil {
 		return nil, base.NewError(base.Error_System, "Launch", "没有指定 ServiceInfo")
 	}
+	tracer, err := tracing.NewTracer(serviceInfo, config.Tracing)
+	if err != nil {
+		return nil, err
+	}
+	tracing.SetTracer(tracer)
+	microService.AddCleanFunc(tracer.Close)
 	logger.Info("ServiceName: %s", serviceInfo.GetServiceName())
 	logger.Info("Version: %s", serviceInfo.GetVersion())
 	logger.Info("Descriptor: %s", serviceInfo.GetDescriptor())
@@ -78,7 +101,7 @@ func Launch(cxt context.Context, service base.Service, serviceBuilder MicroServi
 	}
 	logger.Info("核心服务启动成功,服务地址:%s,启动耗时:%s", httpServerConfig.ServerAddr, time.Since(startTime))
 	//注册是在服务完全启动之后
-	deregisterFunc := serviceDiscoverRegister(cxt, microService.GetService(), microService.GetServiceInfo(), config)
-	microService.AddCleanFunc(deregisterFunc)
+	deregisterFunc := serviceDiscoverRegister(cxt, microService, config)
+	microService.AddDeregisterFunc(deregisterFunc)
 	return microService, nil
 }
@@ -8,15 +8,33 @@ import (
 
 	"github.com/coffeehc/logger"
 	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/discovery"
 )
 
-//ServiceRegister 服务注册到服务发现中心,暂时支持的就是 consul
-func serviceDiscoverRegister(cxt context.Context, service base.Service, serviceInfo base.ServiceInfo, serviceConfig *ServiceConfig) func() {
-	serviceDiscoveryRegister, err := service.GetServiceDiscoveryRegister()
+//getServiceDiscoveryRegister 配置了 discovery 时使用对应的 backend,否则使用 Service 提供的 ServiceDiscoveryRegister
+func getServiceDiscoveryRegister(microService MicroService, serviceConfig *ServiceConfig) (base.ServiceDiscoveryRegister, bool, base.Error) {
+	if serviceConfig.Discovery == nil || serviceConfig.Discovery.Backend == "" {
+		serviceDiscoveryRegister, err := microService.GetService().GetServiceDiscoveryRegister()
+		return serviceDiscoveryRegister, true, err
+	}
+	backend, err := discovery.NewBackend(serviceConfig.Discovery)
 	if err != nil {
-		launchError(fmt.Errorf("获取没有指定serviceDiscoveryRegister失败,注册服务[%s]失败", serviceInfo.GetServiceName()))
+		return nil, false, err
 	}
-	if !serviceConfig.DisableServiceRegister {
+	microService.AddCleanFunc(backend.Close)
+	serviceDiscoveryRegister, err := backend.GetServiceDiscoveryRegister()
+	//static 与 none 不需要注册
+	return serviceDiscoveryRegister, serviceDiscoveryRegister != nil, err
+}
+
+//ServiceRegister 服务注册到服务发现中心,由 discovery 配置或者 Service 决定使用的注册中心
+func serviceDiscoverRegister(cxt context.Context, microService MicroService, serviceConfig *ServiceConfig) func() {
+	serviceInfo := microService.GetServiceInfo()
+	serviceDiscoveryRegister, needRegister, err := getServiceDiscoveryRegister(microService, serviceConfig)
+	if err != nil {
+		launchError(fmt.Errorf("获取serviceDiscoveryRegister失败,注册服务[%s]失败,%s", serviceInfo.GetServiceName(), err))
+	}
+	if !serviceConfig.DisableServiceRegister && needRegister {
 		if serviceDiscoveryRegister == nil {
 			launchError(fmt.Errorf("没有指定serviceDiscoveryRegister,注册服务[%s]失败", serviceInfo.GetServiceName()))
 		}
@@ -25,6 +43,16 @@ func serviceDiscoverRegister(cxt context.Context, service base.Service, serviceI
 			launchError(fmt.Errorf("没有可用的 Http server 的配置,注册服务[%s]失败", serviceInfo.GetServiceName()))
 		}
 		serverAddr := httpServerConfig.ServerAddr
+		if healthCheckProvider, ok := microService.(HealthCheckProvider); ok {
+			if healthCheckRegister, ok := serviceDiscoveryRegister.(base.HealthCheckRegister); ok {
+				healthCheckRegister.SetHealthCheck(healthCheckProvider.GetHealthCheck())
+			}
+		}
+		if metadataRegister, ok := serviceDiscoveryRegister.(base.MetadataRegister); ok {
+			if metadata := serviceConfig.GetMetadata(); len(metadata) > 0 {
+				metadataRegister.SetMetadata(metadata)
+			}
+		}
 
 		deregister, registerError := serviceDiscoveryRegister.RegService(cxt, serviceInfo, serverAddr)
 		if registerError != nil {
@@ -0,0 +1,259 @@
+package circuitbreaker
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coffeehc/logger"
+	"github.com/coffeehc/microserviceboot/base"
+)
+
+const errScopeCircuitBreaker = "circuit breaker"
+
+//State 熔断器的状态
+type State int
+
+const (
+	//StateClosed 正常调用,统计失败率
+	StateClosed State = iota
+	//StateOpen 拒绝所有调用,OpenTimeout 之后进入半开
+	StateOpen
+	//StateHalfOpen 允许少量的探测调用,全部成功后关闭,任何失败重新打开
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("unknown(%d)", int(s))
+}
+
+//Config 熔断器配置,零值使用默认值
+type Config struct {
+	//Window 统计失败率的滚动窗口,默认10s
+	Window time.Duration `yaml:"window" validate:"min=0s"`
+	//Buckets 滚动窗口的分桶数,默认10
+	Buckets int `yaml:"buckets" validate:"min=0"`
+	//MinRequests 窗口内的调用数达到该值才会计算失败率,默认20
+	MinRequests int `yaml:"min_requests" validate:"min=0"`
+	//ErrorRatio 失败率达到该值时打开,默认0.5
+	ErrorRatio float64 `yaml:"error_ratio" validate:"min=0,max=1"`
+	//SlowCallDuration 超过该时间的调用视为慢调用,默认0表示不统计慢调用
+	SlowCallDuration time.Duration `yaml:"slow_call_duration" validate:"min=0s"`
+	//SlowCallRatio 慢调用比例达到该值时打开,默认0.5
+	SlowCallRatio float64 `yaml:"slow_call_ratio" validate:"min=0,max=1"`
+	//OpenTimeout 打开之后进入半开的时间,默认30s
+	OpenTimeout time.Duration `yaml:"open_timeout" validate:"min=0s"`
+	//HalfOpenRequests 半开时允许的探测调用数,默认5
+	HalfOpenRequests int `yaml:"half_open_requests" validate:"min=0"`
+}
+
+func (config *Config) withDefaults() *Config {
+	c := *config
+	if c.Window <= 0 {
+		c.Window = time.Second * 10
+	}
+	if c.Buckets <= 0 {
+		c.Buckets = 10
+	}
+	if c.MinRequests <= 0 {
+		c.MinRequests = 20
+	}
+	if c.ErrorRatio <= 0 {
+		c.ErrorRatio = 0.5
+	}
+	if c.SlowCallRatio <= 0 {
+		c.SlowCallRatio = 0.5
+	}
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = time.Second * 30
+	}
+	if c.HalfOpenRequests <= 0 {
+		c.HalfOpenRequests = 5
+	}
+	return &c
+}
+
+type bucket struct {
+	start    time.Time
+	total    int
+	failures int
+	slow     int
+}
+
+//Breaker 熔断器,可以并发使用
+type Breaker struct {
+	name       string
+	config     *Config
+	source     *Config
+	mutex      *sync.Mutex
+	state      State
+	generation int64
+	openedAt   time.Time
+	buckets    []*bucket
+	//半开时已经放行与成功的探测调用数
+	probes    int
+	successes int
+}
+
+//NewBreaker 创建熔断器,name 用于错误信息与监控
+func NewBreaker(name string, config *Config) *Breaker {
+	source := config
+	if config == nil {
+		config = &Config{}
+	}
+	config = config.withDefaults()
+	b := &Breaker{
+		name:    name,
+		config:  config,
+		source:  source,
+		mutex:   new(sync.Mutex),
+		buckets: make([]*bucket, config.Buckets),
+	}
+	for i := range b.buckets {
+		b.buckets[i] = &bucket{}
+	}
+	breakerState.WithLabelValues(name).Set(float64(StateClosed))
+	return b
+}
+
+//conflictsWith config 使用默认值之后是否与当前的配置不一致
+func (b *Breaker) conflictsWith(config *Config) bool {
+	if config == nil {
+		config = &Config{}
+	}
+	return *config.withDefaults() != *b.config
+}
+
+//Name 熔断器的名称
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+//State 当前的状态
+func (b *Breaker) State() State {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.checkOpenTimeout(time.Now())
+	return b.state
+}
+
+//Allow 判断是否允许调用,允许时返回的 done 必须在调用结束后调用,failed 表示调用失败
+// 不允许时返回 Error_System_CircuitOpen 的错误
+func (b *Breaker) Allow() (done func(failed bool), err base.Error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	now := time.Now()
+	b.checkOpenTimeout(now)
+	switch b.state {
+	case StateOpen:
+		exportRejected(b.name)
+		return nil, base.NewError(base.Error_System_CircuitOpen, errScopeCircuitBreaker, fmt.Sprintf("熔断器[%s]已经打开", b.name))
+	case StateHalfOpen:
+		if b.probes >= b.config.HalfOpenRequests {
+			exportRejected(b.name)
+			return nil, base.NewError(base.Error_System_CircuitOpen, errScopeCircuitBreaker, fmt.Sprintf("熔断器[%s]处于半开状态,探测调用已满", b.name))
+		}
+		b.probes++
+	}
+	generation := b.generation
+	var once sync.Once
+	return func(failed bool) {
+		once.Do(func() {
+			b.record(generation, failed, time.Since(now))
+		})
+	}, nil
+}
+
+func (b *Breaker) record(generation int64, failed bool, duration time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if generation != b.generation {
+		//状态已经变化,忽略之前状态中的调用结果
+		return
+	}
+	slow := b.config.SlowCallDuration > 0 && duration > b.config.SlowCallDuration
+	now := time.Now()
+	switch b.state {
+	case StateClosed:
+		current := b.currentBucket(now)
+		current.total++
+		if failed {
+			current.failures++
+		}
+		if slow {
+			current.slow++
+		}
+		if b.shouldOpen(now) {
+			b.setState(StateOpen, now)
+		}
+	case StateHalfOpen:
+		if failed || slow {
+			b.setState(StateOpen, now)
+			return
+		}
+		b.successes++
+		if b.successes >= b.config.HalfOpenRequests {
+			b.setState(StateClosed, now)
+		}
+	}
+}
+
+func (b *Breaker) shouldOpen(now time.Time) bool {
+	var total, failures, slow int
+	for _, bucket := range b.buckets {
+		if now.Sub(bucket.start) < b.config.Window {
+			total += bucket.total
+			failures += bucket.failures
+			slow += bucket.slow
+		}
+	}
+	if total < b.config.MinRequests {
+		return false
+	}
+	if float64(failures) >= float64(total)*b.config.ErrorRatio {
+		return true
+	}
+	return b.config.SlowCallDuration > 0 && float64(slow) >= float64(total)*b.config.SlowCallRatio
+}
+
+func (b *Breaker) currentBucket(now time.Time) *bucket {
+	width := b.config.Window / time.Duration(len(b.buckets))
+	index := int(now.UnixNano()/int64(width)) % len(b.buckets)
+	current := b.buckets[index]
+	start := now.Truncate(width)
+	if !current.start.Equal(start) {
+		*current = bucket{start: start}
+	}
+	return current
+}
+
+func (b *Breaker) checkOpenTimeout(now time.Time) {
+	if b.state == StateOpen && now.Sub(b.openedAt) >= b.config.OpenTimeout {
+		b.setState(StateHalfOpen, now)
+	}
+}
+
+func (b *Breaker) setState(state State, now time.Time) {
+	logger.Warn("熔断器[%s]状态变化 %s -> %s", b.name, b.state, state)
+	b.state = state
+	b.generation++
+	b.probes = 0
+	b.successes = 0
+	switch state {
+	case StateOpen:
+		b.openedAt = now
+	case StateClosed:
+		for _, bucket := range b.buckets {
+			bucket.total, bucket.failures, bucket.slow = 0, 0, 0
+		}
+	}
+	exportState(b.name, state)
+}
@@ -0,0 +1,66 @@
+package circuitbreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coffeehc/microserviceboot/base"
+	. "gopkg.in/check.v1"
+)
+
+type BreakerSuite struct {
+}
+
+var _ = Suite(&BreakerSuite{})
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+func (t *BreakerSuite) TestStateTransitions(c *C) {
+	breaker := NewBreaker("test", &Config{MinRequests: 4, ErrorRatio: 0.5, OpenTimeout: time.Millisecond * 50, HalfOpenRequests: 2})
+	for i := 0; i < 4; i++ {
+		done, err := breaker.Allow()
+		c.Assert(err, IsNil)
+		done(i%2 == 0)
+	}
+	c.Assert(breaker.State(), Equals, StateOpen)
+	_, err := breaker.Allow()
+	c.Assert(base.IsCircuitOpenError(err), Equals, true)
+
+	time.Sleep(time.Millisecond * 60)
+	c.Assert(breaker.State(), Equals, StateHalfOpen)
+	probe1, err := breaker.Allow()
+	c.Assert(err, IsNil)
+	probe2, err := breaker.Allow()
+	c.Assert(err, IsNil)
+	_, err = breaker.Allow()
+	c.Assert(err, NotNil)
+	probe1(false)
+	probe2(false)
+	c.Assert(breaker.State(), Equals, StateClosed)
+
+	//半开时探测失败重新打开
+	for i := 0; i < 4; i++ {
+		done, _ := breaker.Allow()
+		done(true)
+	}
+	time.Sleep(time.Millisecond * 60)
+	probe, err := breaker.Allow()
+	c.Assert(err, IsNil)
+	probe(true)
+	c.Assert(breaker.State(), Equals, StateOpen)
+}
+
+func (t *BreakerSuite) TestGetBreakerConflict(c *C) {
+	registry := newRegistry()
+	config := &Config{MinRequests: 4}
+	breaker := registry.getBreaker("conflict", config)
+	c.Assert(registry.getBreaker("conflict", config), Equals, breaker)
+	//相同的配置使用不同的对象,不是冲突
+	c.Assert(registry.getBreaker("conflict", &Config{MinRequests: 4, Buckets: 10}), Equals, breaker)
+	c.Assert(registry.conflicts["conflict"], Equals, false)
+	c.Assert(registry.getBreaker("conflict", &Config{MinRequests: 8}), Equals, breaker)
+	c.Assert(registry.conflicts["conflict"], Equals, true)
+	c.Assert(registry.states(), DeepEquals, map[string]State{"conflict": StateClosed})
+}
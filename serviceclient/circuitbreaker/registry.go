@@ -0,0 +1,101 @@
+package circuitbreaker
+
+import (
+	"sync"
+
+	"github.com/coffeehc/logger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	breakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "microservice",
+		Subsystem: "circuit_breaker",
+		Name:      "state",
+		Help:      "Circuit breaker state, 0 closed, 1 open, 2 half-open.",
+	}, []string{"name"})
+	breakerRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "microservice",
+		Subsystem: "circuit_breaker",
+		Name:      "rejected_total",
+		Help:      "Total number of calls rejected by the circuit breaker.",
+	}, []string{"name"})
+	breakerTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "microservice",
+		Subsystem: "circuit_breaker",
+		Name:      "transitions_total",
+		Help:      "Total number of circuit breaker state transitions.",
+	}, []string{"name", "state"})
+
+	defaultRegistry = newRegistry()
+)
+
+func init() {
+	prometheus.MustRegister(breakerState, breakerRejected, breakerTransitions)
+}
+
+func exportState(name string, state State) {
+	breakerState.WithLabelValues(name).Set(float64(state))
+	breakerTransitions.WithLabelValues(name, state.String()).Inc()
+}
+
+func exportRejected(name string) {
+	breakerRejected.WithLabelValues(name).Inc()
+}
+
+//registry 按名称保存熔断器
+type registry struct {
+	mutex    *sync.Mutex
+	breakers map[string]*Breaker
+	//conflicts 已经警告过配置冲突的熔断器
+	conflicts map[string]bool
+}
+
+func newRegistry() *registry {
+	return &registry{
+		mutex:     new(sync.Mutex),
+		breakers:  make(map[string]*Breaker),
+		conflicts: make(map[string]bool),
+	}
+}
+
+func (r *registry) getBreaker(name string, config *Config) *Breaker {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	breaker, ok := r.breakers[name]
+	if !ok {
+		breaker = NewBreaker(name, config)
+		r.breakers[name] = breaker
+		return breaker
+	}
+	if config != breaker.source && !r.conflicts[name] && breaker.conflictsWith(config) {
+		r.conflicts[name] = true
+		logger.Warn("熔断器[%s]已经存在,忽略不一致的配置 %#v", name, config)
+	}
+	return breaker
+}
+
+func (r *registry) states() map[string]State {
+	r.mutex.Lock()
+	all := make([]*Breaker, 0, len(r.breakers))
+	for _, breaker := range r.breakers {
+		all = append(all, breaker)
+	}
+	r.mutex.Unlock()
+	states := make(map[string]State, len(all))
+	for _, breaker := range all {
+		states[breaker.Name()] = breaker.State()
+	}
+	return states
+}
+
+//GetBreaker 按名称获取熔断器,不存在时使用 config 创建,相同名称的调用方共享同一个熔断器,
+// 已经存在的熔断器不会使用新的 config,配置不一致时输出警告
+func GetBreaker(name string, config *Config) *Breaker {
+	return defaultRegistry.getBreaker(name, config)
+}
+
+//Breakers 所有已经创建的熔断器的状态,key 为名称
+func Breakers() map[string]State {
+	return defaultRegistry.states()
+}
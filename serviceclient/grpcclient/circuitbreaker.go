@@ -0,0 +1,47 @@
+package grpcclient
+
+import (
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/serviceclient/circuitbreaker"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+//InterceptorNameCircuitBreaker 熔断器拦截器的名称
+const InterceptorNameCircuitBreaker = "circuit_breaker"
+
+//NewCircuitBreakerInitializer 为每个 ClientConn 添加熔断器拦截器,熔断器的名称为服务名,
+// perMethod 为 true 时每个方法使用独立的熔断器,名称为 服务名+方法名,熔断器打开时返回 Error_System_CircuitOpen
+func NewCircuitBreakerInitializer(config *circuitbreaker.Config, perMethod bool) InterceptorInitializer {
+	return func(serviceInfo base.ServiceInfo, unaryChain UnaryClientInterceptorChain, streamChain StreamClientInterceptorChain) base.Error {
+		serviceName := serviceInfo.GetServiceName()
+		getBreaker := func(method string) *circuitbreaker.Breaker {
+			if perMethod {
+				return circuitbreaker.GetBreaker(serviceName+method, config)
+			}
+			return circuitbreaker.GetBreaker(serviceName, config)
+		}
+		err := unaryChain.AppendInterceptor(InterceptorNameCircuitBreaker, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			done, err := getBreaker(method).Allow()
+			if err != nil {
+				return err
+			}
+			invokeErr := invoker(ctx, method, req, reply, cc, opts...)
+			done(isNodeFailure(invokeErr) && ctx.Err() != context.Canceled)
+			return invokeErr
+		})
+		if err != nil {
+			return err
+		}
+		return streamChain.AppendInterceptor(InterceptorNameCircuitBreaker, func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			done, err := getBreaker(method).Allow()
+			if err != nil {
+				return nil, err
+			}
+			//stream 只统计建立的过程
+			stream, streamErr := streamer(ctx, desc, cc, method, opts...)
+			done(isNodeFailure(streamErr) && ctx.Err() != context.Canceled)
+			return stream, streamErr
+		})
+	}
+}
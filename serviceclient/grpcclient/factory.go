@@ -0,0 +1,154 @@
+package grpcclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coffeehc/logger"
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/loadbalancer"
+	"google.golang.org/grpc"
+)
+
+// ClientConnFactory 按照服务名与 tag 缓存 ClientConn,同一个服务只会建立一次连接
+type ClientConnFactory interface {
+	//GetClientConn 获取服务对应的 ClientConn,timeout 大于0时会堵塞到连接建立或者超时
+	// cxt 中包含 base.CleanFuncRegister(如 Service.Init 的 cxt)时,服务关闭时会自动关闭所有的 ClientConn,
+	// cxt 中 loadbalancer.WithPolicy,WithZoneAware 与 WithOutlierDetection 的配置用于创建该服务的 Balancer,
+	// 连接被缓存,以第一次创建连接时的配置为准
+	GetClientConn(cxt context.Context, serviceInfo base.ServiceInfo, timeout time.Duration) (*grpc.ClientConn, base.Error)
+	//Close 关闭所有的 ClientConn
+	Close()
+}
+
+// NewClientConnFactory 创建 ClientConnFactory,balancerBuilder 用于为每个服务创建 Balancer
+func NewClientConnFactory(balancerBuilder loadbalancer.BalancerBuilder, interceptorInitializers ...InterceptorInitializer) ClientConnFactory {
+	return newClientConnFactory(NewGRPCClient(interceptorInitializers...), balancerBuilder)
+}
+
+func newClientConnFactory(grpcClient GRPCClient, balancerBuilder loadbalancer.BalancerBuilder) *_ClientConnFactory {
+	rootCxt, cancel := context.WithCancel(context.Background())
+	return &_ClientConnFactory{
+		grpcClient:      grpcClient,
+		balancerBuilder: balancerBuilder,
+		rootCxt:         rootCxt,
+		cancel:          cancel,
+		mutex:           new(sync.Mutex),
+		clientConns:     make(map[string]*clientConnEntry),
+	}
+}
+
+type clientConnEntry struct {
+	ready      chan struct{}
+	clientConn *grpc.ClientConn
+	err        base.Error
+}
+
+type _ClientConnFactory struct {
+	grpcClient      GRPCClient
+	balancerBuilder loadbalancer.BalancerBuilder
+	//rootCxt 缓存的 ClientConn 被所有调用方共享,不能使用某一个调用方的 cxt,Close 时结束
+	rootCxt         context.Context
+	cancel          context.CancelFunc
+	mutex           *sync.Mutex
+	clientConns     map[string]*clientConnEntry
+	cleanRegistered bool
+	closed          bool
+}
+
+func (f *_ClientConnFactory) GetClientConn(cxt context.Context, serviceInfo base.ServiceInfo, timeout time.Duration) (*grpc.ClientConn, base.Error) {
+	if serviceInfo == nil {
+		return nil, base.NewError(base.Error_System, errScopeGRPCClient, "serviceInfo is nil")
+	}
+	key := fmt.Sprintf("%s/%s", serviceInfo.GetServiceName(), serviceInfo.GetServiceTag())
+	f.mutex.Lock()
+	if f.closed {
+		f.mutex.Unlock()
+		return nil, base.NewError(base.Error_System, errScopeGRPCClient, "ClientConnFactory 已经关闭")
+	}
+	if !f.cleanRegistered {
+		if register, ok := base.GetCleanFuncRegister(cxt); ok {
+			register.AddCleanFunc(f.Close)
+			f.cleanRegistered = true
+		}
+	}
+	entry, ok := f.clientConns[key]
+	if !ok {
+		entry = &clientConnEntry{ready: make(chan struct{})}
+		f.clientConns[key] = entry
+		go f.dial(key, entry, withBalancerOptions(f.rootCxt, cxt), serviceInfo, timeout)
+	}
+	f.mutex.Unlock()
+	select {
+	case <-entry.ready:
+		return entry.clientConn, entry.err
+	case <-cxt.Done():
+		return nil, base.NewErrorWrapper(base.Error_System, errScopeGRPCClient, cxt.Err())
+	}
+}
+
+//withBalancerOptions 将 cxt 中的负载均衡配置复制到 rootCxt 上,只复制配置不继承 cxt 的取消
+func withBalancerOptions(rootCxt, cxt context.Context) context.Context {
+	if policy := loadbalancer.GetPolicy(cxt); policy != "" {
+		rootCxt = loadbalancer.WithPolicy(rootCxt, policy)
+	}
+	if config := loadbalancer.GetZoneAware(cxt); config != nil {
+		rootCxt = loadbalancer.WithZoneAware(rootCxt, config)
+	}
+	if config := loadbalancer.GetOutlierDetection(cxt); config != nil {
+		rootCxt = loadbalancer.WithOutlierDetection(rootCxt, config)
+	}
+	return rootCxt
+}
+
+// dial 使用 buildCxt 建立连接,buildCxt 由 rootCxt 派生,调用方的 cxt 结束不会影响其他等待同一个连接的调用方
+func (f *_ClientConnFactory) dial(key string, entry *clientConnEntry, buildCxt context.Context, serviceInfo base.ServiceInfo, timeout time.Duration) {
+	defer close(entry.ready)
+	entry.clientConn, entry.err = f.newClientConn(buildCxt, serviceInfo, timeout)
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if entry.err != nil {
+		delete(f.clientConns, key)
+	} else if f.closed {
+		entry.clientConn.Close()
+		entry.clientConn, entry.err = nil, base.NewError(base.Error_System, errScopeGRPCClient, "ClientConnFactory 已经关闭")
+	}
+}
+
+func (f *_ClientConnFactory) newClientConn(buildCxt context.Context, serviceInfo base.ServiceInfo, timeout time.Duration) (*grpc.ClientConn, base.Error) {
+	balancer, err := f.balancerBuilder.Build(buildCxt, serviceInfo)
+	if err != nil {
+		return nil, err
+	}
+	clientConn, err := f.grpcClient.NewClientConn(buildCxt, serviceInfo, balancer, timeout, timeout > 0)
+	if err != nil {
+		balancer.Close()
+		return nil, err
+	}
+	return clientConn, nil
+}
+
+func (f *_ClientConnFactory) Close() {
+	f.mutex.Lock()
+	f.closed = true
+	f.cancel()
+	entries := f.clientConns
+	f.clientConns = make(map[string]*clientConnEntry)
+	f.mutex.Unlock()
+	for key, entry := range entries {
+		select {
+		case <-entry.ready:
+		default:
+			//还在建立连接,建立完成后会自行关闭
+			continue
+		}
+		if entry.clientConn != nil {
+			err := entry.clientConn.Close()
+			if err != nil {
+				logger.Warn("关闭 ClientConn[%s] 失败:%s", key, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package grpcclient
+
+import (
+	"context"
+	"time"
+
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/loadbalancer"
+	xcontext "golang.org/x/net/context"
+	"google.golang.org/grpc"
+	. "gopkg.in/check.v1"
+)
+
+type FactorySuite struct {
+}
+
+var _ = Suite(&FactorySuite{})
+
+//testBalancer 记录 Close 的次数
+type testBalancer struct {
+	loadbalancer.Balancer
+	closed int
+}
+
+func (b *testBalancer) Close() error {
+	b.closed++
+	return nil
+}
+
+//testGRPCClient 在 release 关闭后返回 err
+type testGRPCClient struct {
+	release chan struct{}
+	err     base.Error
+}
+
+func (client *testGRPCClient) NewClientConn(cxt context.Context, serviceInfo base.ServiceInfo, balancer loadbalancer.Balancer, timeout time.Duration, block bool) (*grpc.ClientConn, base.Error) {
+	<-client.release
+	return nil, client.err
+}
+
+type testBalancerBuilder struct {
+	cxts      []xcontext.Context
+	balancers []*testBalancer
+}
+
+func (builder *testBalancerBuilder) Build(cxt xcontext.Context, serviceInfo base.ServiceInfo) (loadbalancer.Balancer, base.Error) {
+	balancer := &testBalancer{}
+	builder.cxts = append(builder.cxts, cxt)
+	builder.balancers = append(builder.balancers, balancer)
+	return balancer, nil
+}
+
+func (t *FactorySuite) TestRootContext(c *C) {
+	serviceInfo := base.NewSimpleServiceInfo("test", "1.0", "dev", "https", "", "")
+	builder := &testBalancerBuilder{}
+	grpcClient := &testGRPCClient{release: make(chan struct{})}
+	factory := newClientConnFactory(grpcClient, builder)
+	//第一个调用方在连接建立前放弃,不影响正在建立的连接
+	cxt, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	_, err := factory.GetClientConn(cxt, serviceInfo, time.Second)
+	c.Assert(err, NotNil)
+	close(grpcClient.release)
+	_, err = factory.GetClientConn(context.Background(), serviceInfo, time.Second)
+	c.Assert(err, IsNil)
+	c.Assert(builder.cxts, HasLen, 1)
+	c.Assert(builder.cxts[0].Err(), IsNil)
+	factory.Close()
+	c.Assert(builder.cxts[0].Err(), NotNil)
+}
+
+func (t *FactorySuite) TestCloseBalancerOnError(c *C) {
+	serviceInfo := base.NewSimpleServiceInfo("test", "1.0", "dev", "https", "", "")
+	builder := &testBalancerBuilder{}
+	grpcClient := &testGRPCClient{release: make(chan struct{}), err: base.NewError(base.Error_System, "test", "dial error")}
+	close(grpcClient.release)
+	factory := newClientConnFactory(grpcClient, builder)
+	defer factory.Close()
+	_, err := factory.GetClientConn(context.Background(), serviceInfo, time.Second)
+	c.Assert(err, NotNil)
+	_, err = factory.GetClientConn(context.Background(), serviceInfo, time.Second)
+	c.Assert(err, NotNil)
+	//失败的连接不会被缓存,每次失败都会关闭创建的 balancer
+	c.Assert(builder.balancers, HasLen, 2)
+	c.Assert(builder.balancers[0].closed, Equals, 1)
+	c.Assert(builder.balancers[1].closed, Equals, 1)
+}
+
+func (t *FactorySuite) TestBalancerOptions(c *C) {
+	serviceInfo := base.NewSimpleServiceInfo("test", "1.0", "dev", "https", "", "")
+	builder := &testBalancerBuilder{}
+	grpcClient := &testGRPCClient{release: make(chan struct{})}
+	close(grpcClient.release)
+	factory := newClientConnFactory(grpcClient, builder)
+	outlier := &loadbalancer.OutlierConfig{}
+	cxt, cancel := context.WithCancel(context.Background())
+	cxt = loadbalancer.WithPolicy(cxt, loadbalancer.PolicyLeastRequest)
+	cxt = loadbalancer.WithOutlierDetection(cxt, outlier)
+	_, err := factory.GetClientConn(cxt, serviceInfo, time.Second)
+	c.Assert(err, IsNil)
+	//单个客户端指定的策略传递到 Build,调用方的 cxt 结束不影响 Balancer
+	c.Assert(builder.cxts, HasLen, 1)
+	c.Assert(loadbalancer.GetPolicy(builder.cxts[0]), Equals, loadbalancer.PolicyLeastRequest)
+	c.Assert(loadbalancer.GetOutlierDetection(builder.cxts[0]), Equals, outlier)
+	c.Assert(loadbalancer.GetZoneAware(builder.cxts[0]), IsNil)
+	cancel()
+	c.Assert(builder.cxts[0].Err(), IsNil)
+	factory.Close()
+	c.Assert(builder.cxts[0].Err(), NotNil)
+}
@@ -0,0 +1,162 @@
+package grpcclient
+
+import (
+	"io"
+	"sync"
+
+	"github.com/coffeehc/microserviceboot/base"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func newStreamClientInterceptor(serviceInfo base.ServiceInfo) *streamClientInterceptor {
+	return &streamClientInterceptor{
+		serviceInfo:  serviceInfo,
+		interceptors: make(map[string]*streamClientInterceptorWapper),
+		rootInterceptor: &streamClientInterceptorWapper{
+			interceptor: paincStreamInterceptor,
+		},
+		mutex: new(sync.Mutex),
+	}
+}
+
+type streamClientInterceptor struct {
+	serviceInfo     base.ServiceInfo
+	interceptors    map[string]*streamClientInterceptorWapper
+	rootInterceptor *streamClientInterceptorWapper
+	mutex           *sync.Mutex
+}
+
+func (sci *streamClientInterceptor) Interceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	opts = append(opts, grpc.FailFast(false))
+	return sci.rootInterceptor.intercept(context.WithValue(ctx, context_serviceInfoKey, sci.serviceInfo), desc, cc, method, streamer, opts...)
+}
+
+func (sci *streamClientInterceptor) AppendInterceptor(name string, interceptor grpc.StreamClientInterceptor) base.Error {
+	sci.mutex.Lock()
+	defer sci.mutex.Unlock()
+	if _, ok := sci.interceptors[name]; ok {
+		return errInterceptorExist(name)
+	}
+	lastInterceptor := getLastStreamClientInterceptor(sci.rootInterceptor)
+	lastInterceptor.next = &streamClientInterceptorWapper{interceptor: interceptor}
+	sci.interceptors[name] = lastInterceptor.next
+	return nil
+}
+
+func (sci *streamClientInterceptor) InsertBefore(target string, name string, interceptor grpc.StreamClientInterceptor) base.Error {
+	sci.mutex.Lock()
+	defer sci.mutex.Unlock()
+	if _, ok := sci.interceptors[name]; ok {
+		return errInterceptorExist(name)
+	}
+	targetInterceptor, ok := sci.interceptors[target]
+	if !ok {
+		return errInterceptorNotFound(target)
+	}
+	prevInterceptor := getPrevStreamClientInterceptor(sci.rootInterceptor, targetInterceptor)
+	prevInterceptor.next = &streamClientInterceptorWapper{interceptor: interceptor, next: targetInterceptor}
+	sci.interceptors[name] = prevInterceptor.next
+	return nil
+}
+
+func (sci *streamClientInterceptor) InsertAfter(target string, name string, interceptor grpc.StreamClientInterceptor) base.Error {
+	sci.mutex.Lock()
+	defer sci.mutex.Unlock()
+	if _, ok := sci.interceptors[name]; ok {
+		return errInterceptorExist(name)
+	}
+	targetInterceptor, ok := sci.interceptors[target]
+	if !ok {
+		return errInterceptorNotFound(target)
+	}
+	targetInterceptor.next = &streamClientInterceptorWapper{interceptor: interceptor, next: targetInterceptor.next}
+	sci.interceptors[name] = targetInterceptor.next
+	return nil
+}
+
+func (sci *streamClientInterceptor) Remove(name string) base.Error {
+	sci.mutex.Lock()
+	defer sci.mutex.Unlock()
+	targetInterceptor, ok := sci.interceptors[name]
+	if !ok {
+		return errInterceptorNotFound(name)
+	}
+	prevInterceptor := getPrevStreamClientInterceptor(sci.rootInterceptor, targetInterceptor)
+	prevInterceptor.next = targetInterceptor.next
+	delete(sci.interceptors, name)
+	return nil
+}
+
+func getLastStreamClientInterceptor(root *streamClientInterceptorWapper) *streamClientInterceptorWapper {
+	if root.next == nil {
+		return root
+	}
+	return getLastStreamClientInterceptor(root.next)
+}
+
+func getPrevStreamClientInterceptor(root *streamClientInterceptorWapper, target *streamClientInterceptorWapper) *streamClientInterceptorWapper {
+	if root.next == target {
+		return root
+	}
+	return getPrevStreamClientInterceptor(root.next, target)
+}
+
+type streamClientInterceptorWapper struct {
+	interceptor grpc.StreamClientInterceptor
+	next        *streamClientInterceptorWapper
+}
+
+func (sciw *streamClientInterceptorWapper) intercept(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if sciw.next == nil {
+		return sciw.interceptor(ctx, desc, cc, method, streamer, opts...)
+	}
+	return sciw.interceptor(ctx, desc, cc, method, func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return sciw.next.intercept(ctx, desc, cc, method, streamer, opts...)
+	}, opts...)
+}
+
+func paincStreamInterceptor(cxt context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (clientStream grpc.ClientStream, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			clientStream = nil
+			err = adapteError(cxt, r)
+		}
+	}()
+	clientStream, err = streamer(cxt, desc, cc, method, opts...)
+	if err != nil {
+		return nil, adapteError(cxt, err)
+	}
+	return &adapteErrorClientStream{ClientStream: clientStream, cxt: cxt}, nil
+}
+
+//adapteErrorClientStream 将 ClientStream 返回的错误转换为 base.Error,io.EOF 保持不变
+type adapteErrorClientStream struct {
+	grpc.ClientStream
+	cxt context.Context
+}
+
+func (s *adapteErrorClientStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	return md, adapteStreamError(s.cxt, err)
+}
+
+func (s *adapteErrorClientStream) CloseSend() error {
+	return adapteStreamError(s.cxt, s.ClientStream.CloseSend())
+}
+
+func (s *adapteErrorClientStream) SendMsg(m interface{}) error {
+	return adapteStreamError(s.cxt, s.ClientStream.SendMsg(m))
+}
+
+func (s *adapteErrorClientStream) RecvMsg(m interface{}) error {
+	return adapteStreamError(s.cxt, s.ClientStream.RecvMsg(m))
+}
+
+func adapteStreamError(cxt context.Context, err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	return adapteError(cxt, err)
+}
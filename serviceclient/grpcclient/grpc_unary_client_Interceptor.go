@@ -6,31 +6,17 @@ import (
 
 	"github.com/coffeehc/logger"
 	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/base/grpcbase"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/status"
 )
 
-var (
-	_unaryClientInterceptor = newUnartClientInterceptor()
-)
-
-const _internalInvoker = "_internal_invoker"
 const context_serviceInfoKey = "__serviceInfo__"
 
-func wapperUnartClientInterceptor(serviceInfo base.ServiceInfo) grpc.UnaryClientInterceptor {
-	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) (err error) {
-		return _unaryClientInterceptor.Interceptor(context.WithValue(ctx, context_serviceInfoKey, serviceInfo), method, req, reply, cc, invoker, opts...)
-	}
-}
-
-//AppendUnartClientInterceptor 追加一个UnartClientInterceptor
-func AppendUnartClientInterceptor(name string, unaryClientInterceptor grpc.UnaryClientInterceptor) base.Error {
-	return _unaryClientInterceptor.AppendInterceptor(name, unaryClientInterceptor)
-}
-
-func newUnartClientInterceptor() *unartClientInterceptor {
+func newUnartClientInterceptor(serviceInfo base.ServiceInfo) *unartClientInterceptor {
 	return &unartClientInterceptor{
+		serviceInfo:  serviceInfo,
 		interceptors: make(map[string]*unaryClientInterceptorWapper),
 		rootInterceptor: &unaryClientInterceptorWapper{
 			interceptor: paincInterceptor,
@@ -40,6 +26,7 @@ func newUnartClientInterceptor() *unartClientInterceptor {
 }
 
 type unartClientInterceptor struct {
+	serviceInfo     base.ServiceInfo
 	interceptors    map[string]*unaryClientInterceptorWapper
 	rootInterceptor *unaryClientInterceptorWapper
 	mutex           *sync.Mutex
@@ -47,14 +34,14 @@ type unartClientInterceptor struct {
 
 func (uci *unartClientInterceptor) Interceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) (err error) {
 	opts = append(opts, grpc.FailFast(false))
-	return uci.rootInterceptor.interceptor(context.WithValue(ctx, _internalInvoker, invoker), method, req, reply, cc, uci.rootInterceptor.invoker, opts...)
+	return uci.rootInterceptor.intercept(context.WithValue(ctx, context_serviceInfoKey, uci.serviceInfo), method, req, reply, cc, invoker, opts...)
 }
 
 func (uci *unartClientInterceptor) AppendInterceptor(name string, interceptor grpc.UnaryClientInterceptor) base.Error {
 	uci.mutex.Lock()
 	defer uci.mutex.Unlock()
 	if _, ok := uci.interceptors[name]; ok {
-		return base.NewError(base.Error_System, "grpc interceptor", fmt.Sprintf("%s 已经存在", name))
+		return errInterceptorExist(name)
 	}
 	lastInterceptor := getLastUnaryClientInterceptor(uci.rootInterceptor)
 	lastInterceptor.next = &unaryClientInterceptorWapper{interceptor: interceptor}
@@ -62,6 +49,50 @@ func (uci *unartClientInterceptor) AppendInterceptor(name string, interceptor gr
 	return nil
 }
 
+func (uci *unartClientInterceptor) InsertBefore(target string, name string, interceptor grpc.UnaryClientInterceptor) base.Error {
+	uci.mutex.Lock()
+	defer uci.mutex.Unlock()
+	if _, ok := uci.interceptors[name]; ok {
+		return errInterceptorExist(name)
+	}
+	targetInterceptor, ok := uci.interceptors[target]
+	if !ok {
+		return errInterceptorNotFound(target)
+	}
+	prevInterceptor := getPrevUnaryClientInterceptor(uci.rootInterceptor, targetInterceptor)
+	prevInterceptor.next = &unaryClientInterceptorWapper{interceptor: interceptor, next: targetInterceptor}
+	uci.interceptors[name] = prevInterceptor.next
+	return nil
+}
+
+func (uci *unartClientInterceptor) InsertAfter(target string, name string, interceptor grpc.UnaryClientInterceptor) base.Error {
+	uci.mutex.Lock()
+	defer uci.mutex.Unlock()
+	if _, ok := uci.interceptors[name]; ok {
+		return errInterceptorExist(name)
+	}
+	targetInterceptor, ok := uci.interceptors[target]
+	if !ok {
+		return errInterceptorNotFound(target)
+	}
+	targetInterceptor.next = &unaryClientInterceptorWapper{interceptor: interceptor, next: targetInterceptor.next}
+	uci.interceptors[name] = targetInterceptor.next
+	return nil
+}
+
+func (uci *unartClientInterceptor) Remove(name string) base.Error {
+	uci.mutex.Lock()
+	defer uci.mutex.Unlock()
+	targetInterceptor, ok := uci.interceptors[name]
+	if !ok {
+		return errInterceptorNotFound(name)
+	}
+	prevInterceptor := getPrevUnaryClientInterceptor(uci.rootInterceptor, targetInterceptor)
+	prevInterceptor.next = targetInterceptor.next
+	delete(uci.interceptors, name)
+	return nil
+}
+
 func getLastUnaryClientInterceptor(root *unaryClientInterceptorWapper) *unaryClientInterceptorWapper {
 	if root.next == nil {
 		return root
@@ -69,23 +100,25 @@ func getLastUnaryClientInterceptor(root *unaryClientInterceptorWapper) *unaryCli
 	return getLastUnaryClientInterceptor(root.next)
 }
 
+func getPrevUnaryClientInterceptor(root *unaryClientInterceptorWapper, target *unaryClientInterceptorWapper) *unaryClientInterceptorWapper {
+	if root.next == target {
+		return root
+	}
+	return getPrevUnaryClientInterceptor(root.next, target)
+}
+
 type unaryClientInterceptorWapper struct {
 	interceptor grpc.UnaryClientInterceptor
 	next        *unaryClientInterceptorWapper
 }
 
-func (uciw *unaryClientInterceptorWapper) invoker(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) (err error) {
+func (uciw *unaryClientInterceptorWapper) intercept(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
 	if uciw.next == nil {
-		realInvoker := ctx.Value(_internalInvoker)
-		if realInvoker == nil {
-			return base.NewError(base.Error_System, "grpc", "没有 Handler")
-		}
-		if invoker, ok := realInvoker.(grpc.UnaryInvoker); ok {
-			return invoker(ctx, method, req, reply, cc, opts...)
-		}
-		return base.NewError(base.Error_System, "grpc", "类型错误")
+		return uciw.interceptor(ctx, method, req, reply, cc, invoker, opts...)
 	}
-	return uciw.next.interceptor(ctx, method, req, reply, cc, uciw.next.invoker, opts...)
+	return uciw.interceptor(ctx, method, req, reply, cc, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return uciw.next.intercept(ctx, method, req, reply, cc, invoker, opts...)
+	}, opts...)
 }
 
 func paincInterceptor(cxt context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) (err error) {
@@ -97,6 +130,8 @@ func paincInterceptor(cxt context.Context, method string, req, reply interface{}
 	return adapteError(cxt, invoker(cxt, method, req, reply, cc, opts...))
 }
 
+//adapteError 将 err 转换为 base.Error,服务端返回的 grpc 状态从 Details 中还原原来的 Error,
+// 没有 Details 时 grpc 状态码是基础错误码的保留为错误码,否则封装为 Error_System
 func adapteError(cxt context.Context, err interface{}) base.Error {
 	if err == nil {
 		return nil
@@ -117,6 +152,9 @@ func adapteError(cxt context.Context, err interface{}) base.Error {
 		return base.NewError(base.Error_System, serviceName, v)
 	case error:
 		if s, ok := status.FromError(v); ok {
+			if e, ok := grpcbase.ErrorFromStatus(s.Proto()); ok {
+				return e
+			}
 			code := int32(s.Code())
 			if !base.IsBaseErrorCode(code) {
 				return base.NewErrorWrapper(base.Error_System, serviceName, s.Err())
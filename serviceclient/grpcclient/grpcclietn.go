@@ -14,43 +14,73 @@ import (
 
 const (
 	errScopeGRPCClient = "grpcClient"
+	defaultDialTimeout = time.Second * 3
 )
 
+//GRPCClient 创建 ClientConn,timeout 为建立连接的超时时间,为0时使用默认的3s,调用的超时时间通过 NewTimeoutInitializer 配置
 type GRPCClient interface {
 	NewClientConn(cxt context.Context, serviceInfo base.ServiceInfo, balancer loadbalancer.Balancer, timeout time.Duration, block bool) (*grpc.ClientConn, base.Error)
 }
 
 type _GRPCClient struct {
+	interceptorInitializers []InterceptorInitializer
 }
 
-func NewGRPCClient() GRPCClient {
-	return &_GRPCClient{}
+//NewGRPCClient 创建 GRPCClient,interceptorInitializers 会应用到每个新建的 ClientConn 的拦截器链上
+func NewGRPCClient(interceptorInitializers ...InterceptorInitializer) GRPCClient {
+	return &_GRPCClient{
+		interceptorInitializers: interceptorInitializers,
+	}
 }
 
 func (client *_GRPCClient) NewClientConn(cxt context.Context, serviceInfo base.ServiceInfo, balancer loadbalancer.Balancer, timeout time.Duration, block bool) (*grpc.ClientConn, base.Error) {
+	unaryInterceptor := newUnartClientInterceptor(serviceInfo)
+	streamInterceptor := newStreamClientInterceptor(serviceInfo)
+	err := unaryInterceptor.AppendInterceptor(InterceptorNameTracing, newTracingUnaryInterceptor(serviceInfo.GetServiceName()))
+	if err != nil {
+		return nil, err
+	}
+	err = streamInterceptor.AppendInterceptor(InterceptorNameTracing, newTracingStreamInterceptor(serviceInfo.GetServiceName()))
+	if err != nil {
+		return nil, err
+	}
+	for _, initializer := range client.interceptorInitializers {
+		err = initializer(serviceInfo, unaryInterceptor, streamInterceptor)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if reporter, ok := balancer.(loadbalancer.ResultReporter); ok {
+		//放在最内层,重试与对冲的每一次调用都单独上报
+		err = unaryInterceptor.AppendInterceptor(InterceptorNameOutlierDetection, newOutlierDetectionInterceptor(reporter))
+		if err != nil {
+			return nil, err
+		}
+	}
 	opts := []grpc.DialOption{
 		grpc.WithBackoffMaxDelay(time.Second * 10),
 		grpc.WithKeepaliveParams(keepalive.ClientParameters{Time: time.Second * 5, Timeout: time.Second * 20, PermitWithoutStream: true}),
 		grpc.WithBalancer(adopterToGRPCBalancer(balancer)),
 		grpc.WithUserAgent("coffee's grpc client"),
-		grpc.WithTimeout(time.Second * 3),
 		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
 			InsecureSkipVerify: true,
 			NextProtos:         []string{"h2"},
 		})),
 		grpc.WithCompressor(grpc.NewGZIPCompressor()),
 		grpc.WithDecompressor(grpc.NewGZIPDecompressor()),
-		grpc.WithUnaryInterceptor(wapperUnartClientInterceptor(serviceInfo)),
+		grpc.WithUnaryInterceptor(unaryInterceptor.Interceptor),
+		grpc.WithStreamInterceptor(streamInterceptor.Interceptor),
 	}
 	if block {
 		opts = append(opts, grpc.WithBlock())
 	}
-	if timeout > 0 {
-		opts = append(opts, grpc.WithTimeout(timeout))
+	if timeout <= 0 {
+		timeout = defaultDialTimeout
 	}
-	clientConn, err := grpc.DialContext(cxt, serviceInfo.GetServiceName(), opts...)
-	if err != nil {
-		return nil, base.NewErrorWrapper(base.Error_System, errScopeGRPCClient+"."+serviceInfo.GetServiceName(), err)
+	opts = append(opts, grpc.WithTimeout(timeout))
+	clientConn, dialErr := grpc.DialContext(cxt, serviceInfo.GetServiceName(), opts...)
+	if dialErr != nil {
+		return nil, base.NewErrorWrapper(base.Error_System, errScopeGRPCClient+"."+serviceInfo.GetServiceName(), dialErr)
 	}
 	return clientConn, nil
 }
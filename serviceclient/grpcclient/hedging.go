@@ -0,0 +1,218 @@
+package grpcclient
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/loadbalancer"
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+//InterceptorNameHedging 对冲请求拦截器的名称
+const InterceptorNameHedging = "hedging"
+
+const (
+	defaultHedgingMaxAttempts     = 2
+	defaultHedgingDelayPercentile = 0.95
+	hedgingLatencySamples         = 100
+	hedgingMinLatencySamples      = 20
+	hedgingRecomputeInterval      = 10
+)
+
+//HedgingPolicy 方法的对冲策略,只应该用于只读或者幂等的方法
+type HedgingPolicy struct {
+	//MaxAttempts 包括第一次调用在内最多同时发出的调用数,默认2
+	MaxAttempts int `yaml:"max_attempts" validate:"min=0"`
+	//Delay 调用没有返回时发出下一个调用的等待时间,为0时使用 DelayPercentile
+	Delay time.Duration `yaml:"delay" validate:"min=0s"`
+	//DelayPercentile 使用该方法最近成功调用延迟的分位数作为等待时间,默认0.95,样本不足时不对冲
+	DelayPercentile float64 `yaml:"delay_percentile" validate:"min=0,max=1"`
+}
+
+//HedgingConfig 对冲配置,只对 Methods 中配置的方法对冲
+type HedgingConfig struct {
+	//Methods 按方法配置的对冲策略,key 为完整的方法名,如 /package.Service/Method
+	Methods map[string]*HedgingPolicy `yaml:"methods"`
+	//BudgetRatio 每个 ClientConn 对冲的调用数不超过调用次数的比例,默认0.1
+	BudgetRatio float64 `yaml:"budget_ratio" validate:"min=0"`
+	//BudgetMinPerSecond 调用量很少时每秒至少允许的对冲调用数,默认10
+	BudgetMinPerSecond int `yaml:"budget_min_per_second" validate:"min=0"`
+}
+
+//NewHedgingInitializer 为每个 ClientConn 添加对冲拦截器,第一个调用在等待时间内没有返回时向其他地址发出下一个调用,
+// 使用第一个成功的结果并取消其他调用,对冲的调用数受每个 ClientConn 的预算限制,只对 unary 调用生效
+func NewHedgingInitializer(config *HedgingConfig) InterceptorInitializer {
+	return func(serviceInfo base.ServiceInfo, unaryChain UnaryClientInterceptorChain, streamChain StreamClientInterceptorChain) base.Error {
+		ratio := config.BudgetRatio
+		if ratio <= 0 {
+			ratio = defaultRetryBudgetRatio
+		}
+		minPerSecond := config.BudgetMinPerSecond
+		if minPerSecond <= 0 {
+			minPerSecond = defaultRetryBudgetMinPerSecond
+		}
+		return unaryChain.AppendInterceptor(InterceptorNameHedging, newHedgingInterceptor(config, newRetryBudget(ratio, minPerSecond)))
+	}
+}
+
+type hedgingResult struct {
+	reply    interface{}
+	err      error
+	duration time.Duration
+}
+
+func newHedgingInterceptor(config *HedgingConfig, budget *retryBudget) grpc.UnaryClientInterceptor {
+	trackers := make(map[string]*latencyTracker)
+	trackersMutex := new(sync.Mutex)
+	getTracker := func(method string) *latencyTracker {
+		trackersMutex.Lock()
+		defer trackersMutex.Unlock()
+		tracker, ok := trackers[method]
+		if !ok {
+			tracker = newLatencyTracker()
+			trackers[method] = tracker
+		}
+		return tracker
+	}
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		budget.deposit()
+		policy, ok := config.Methods[method]
+		replyType := reflect.TypeOf(reply)
+		if !ok || replyType == nil || replyType.Kind() != reflect.Ptr {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		tracker := getTracker(method)
+		delay := policy.getDelay(tracker)
+		maxAttempts := policy.MaxAttempts
+		if maxAttempts <= 0 {
+			maxAttempts = defaultHedgingMaxAttempts
+		}
+		if delay <= 0 || maxAttempts <= 1 {
+			start := time.Now()
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			if err == nil {
+				tracker.observe(time.Since(start))
+			}
+			return err
+		}
+		hedgingCtx, cancel := context.WithCancel(loadbalancer.WithAddrGroup(ctx, loadbalancer.NewAddrGroup()))
+		defer cancel()
+		results := make(chan hedgingResult, maxAttempts)
+		launch := func() {
+			attemptReply := reflect.New(replyType.Elem()).Interface()
+			//每个调用使用独立的 opts,并发的调用不能共享 append 的底层数组
+			attemptOpts := make([]grpc.CallOption, len(opts))
+			copy(attemptOpts, opts)
+			go func() {
+				start := time.Now()
+				err := invoker(hedgingCtx, method, req, attemptReply, cc, attemptOpts...)
+				results <- hedgingResult{reply: attemptReply, err: err, duration: time.Since(start)}
+			}()
+		}
+		launch()
+		attempts, inflight := 1, 1
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		for {
+			select {
+			case <-timer.C:
+				if attempts < maxAttempts && budget.withdraw() {
+					launch()
+					attempts++
+					inflight++
+					timer.Reset(delay)
+				}
+			case result := <-results:
+				inflight--
+				if result.err == nil {
+					tracker.observe(result.duration)
+					copyReply(reply, result.reply)
+					return nil
+				}
+				if inflight == 0 {
+					return result.err
+				}
+			}
+		}
+	}
+}
+
+//copyReply 把成功的调用的结果复制到调用方的 reply,protobuf 的消息重置后使用 proto.Merge,
+// 避免浅拷贝共享 XXX_unrecognized 等内部字段,其他类型使用反射复制
+func copyReply(reply, result interface{}) {
+	if message, ok := reply.(proto.Message); ok {
+		message.Reset()
+		proto.Merge(message, result.(proto.Message))
+		return
+	}
+	reflect.ValueOf(reply).Elem().Set(reflect.ValueOf(result).Elem())
+}
+
+func (policy *HedgingPolicy) getDelay(tracker *latencyTracker) time.Duration {
+	if policy.Delay > 0 {
+		return policy.Delay
+	}
+	percentile := policy.DelayPercentile
+	if percentile <= 0 {
+		percentile = defaultHedgingDelayPercentile
+	}
+	return tracker.percentile(percentile)
+}
+
+//latencyTracker 记录最近成功调用的延迟,用于计算分位数
+type latencyTracker struct {
+	mutex    *sync.Mutex
+	samples  []time.Duration
+	next     int
+	pending  int
+	computed map[float64]time.Duration
+}
+
+func newLatencyTracker() *latencyTracker {
+	return &latencyTracker{
+		mutex:    new(sync.Mutex),
+		samples:  make([]time.Duration, 0, hedgingLatencySamples),
+		computed: make(map[float64]time.Duration),
+	}
+}
+
+func (t *latencyTracker) observe(d time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if len(t.samples) < hedgingLatencySamples {
+		t.samples = append(t.samples, d)
+	} else {
+		t.samples[t.next] = d
+		t.next = (t.next + 1) % hedgingLatencySamples
+	}
+	t.pending++
+	if t.pending >= hedgingRecomputeInterval {
+		t.pending = 0
+		t.computed = make(map[float64]time.Duration)
+	}
+}
+
+//percentile 样本不足时返回0
+func (t *latencyTracker) percentile(p float64) time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if len(t.samples) < hedgingMinLatencySamples {
+		return 0
+	}
+	if d, ok := t.computed[p]; ok {
+		return d
+	}
+	sorted := make([]time.Duration, len(t.samples))
+	copy(sorted, t.samples)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	index := int(float64(len(sorted)-1) * p)
+	t.computed[p] = sorted[index]
+	return sorted[index]
+}
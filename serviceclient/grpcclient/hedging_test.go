@@ -0,0 +1,171 @@
+package grpcclient
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/loadbalancer"
+	"github.com/coffeehc/microserviceboot/serviceboot"
+	"github.com/golang/protobuf/proto"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	. "gopkg.in/check.v1"
+)
+
+type HedgingSuite struct {
+}
+
+var _ = Suite(&HedgingSuite{})
+
+func (t *HedgingSuite) TestHedging(c *C) {
+	config := &HedgingConfig{
+		Methods: map[string]*HedgingPolicy{
+			"/test.Service/Get": {MaxAttempts: 2, Delay: time.Millisecond * 10},
+		},
+	}
+	interceptor := newHedgingInterceptor(config, newRetryBudget(0.1, 10))
+	calls := make(chan int, 2)
+	canceled := make(chan bool, 1)
+	var attempts int32
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		attempt := atomic.AddInt32(&attempts, 1)
+		calls <- int(attempt)
+		if attempt == 1 {
+			select {
+			case <-ctx.Done():
+				canceled <- true
+				return ctx.Err()
+			case <-time.After(time.Second):
+			}
+			*reply.(*string) = "slow"
+			return nil
+		}
+		*reply.(*string) = "fast"
+		return nil
+	}
+	var reply string
+	start := time.Now()
+	err := interceptor(context.Background(), "/test.Service/Get", nil, &reply, nil, invoker)
+	c.Assert(err, IsNil)
+	c.Assert(reply, Equals, "fast")
+	c.Assert(time.Since(start) < time.Millisecond*500, Equals, true)
+	c.Assert(len(calls), Equals, 2)
+	//其他的调用被取消
+	c.Assert(<-canceled, Equals, true)
+}
+
+func (t *HedgingSuite) TestHedgingProtoReply(c *C) {
+	config := &HedgingConfig{
+		Methods: map[string]*HedgingPolicy{
+			"/test.Service/Get": {MaxAttempts: 2, Delay: time.Millisecond * 10},
+		},
+	}
+	interceptor := newHedgingInterceptor(config, newRetryBudget(0.1, 10))
+	winner := make(chan *structpb.Struct, 1)
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		message := reply.(*structpb.Struct)
+		message.Fields = map[string]*structpb.Value{"name": {Kind: &structpb.Value_StringValue{StringValue: "fast"}}}
+		winner <- message
+		return nil
+	}
+	reply := &structpb.Struct{Fields: map[string]*structpb.Value{"old": {Kind: &structpb.Value_BoolValue{BoolValue: true}}}}
+	err := interceptor(context.Background(), "/test.Service/Get", nil, reply, nil, invoker)
+	c.Assert(err, IsNil)
+	//reply 被重置后合并,不与对冲调用的结果共享内部的数据
+	result := <-winner
+	c.Assert(proto.Equal(reply, result), Equals, true)
+	result.Fields["other"] = &structpb.Value{Kind: &structpb.Value_BoolValue{BoolValue: true}}
+	c.Assert(reply.Fields, HasLen, 1)
+	c.Assert(reply.Fields["name"].GetStringValue(), Equals, "fast")
+}
+
+//reportRecorder 记录 outlier 拦截器上报的结果
+type reportRecorder struct {
+	loadbalancer.Balancer
+	mutex   *sync.Mutex
+	reports map[string]int
+}
+
+func (r *reportRecorder) ReportResult(addr string, failed bool) {
+	r.Balancer.(loadbalancer.ResultReporter).ReportResult(addr, failed)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.reports[addr]++
+}
+
+//TestHedgingWithOutlier 对冲的调用并发经过 outlier 拦截器,需要使用 -race 运行
+func (t *HedgingSuite) TestHedgingWithOutlier(c *C) {
+	tlsConfig, err := serviceboot.NewDefaultTLSConfig()
+	c.Assert(err, IsNil)
+	var calls int32
+	waitingMutex := new(sync.Mutex)
+	var waiting chan struct{}
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)), grpc.RPCCompressor(grpc.NewGZIPCompressor()), grpc.RPCDecompressor(grpc.NewGZIPDecompressor()), grpc.UnknownServiceHandler(func(srv interface{}, stream grpc.ServerStream) error {
+		req := new(grpc_health_v1.HealthCheckRequest)
+		if err := stream.RecvMsg(req); err != nil {
+			return err
+		}
+		waitingMutex.Lock()
+		if atomic.AddInt32(&calls, 1)%2 == 1 {
+			//奇数次的调用等到对冲的调用到达后一起返回,两个调用并发的完成
+			ch := make(chan struct{})
+			waiting = ch
+			waitingMutex.Unlock()
+			select {
+			case <-stream.Context().Done():
+				return stream.Context().Err()
+			case <-ch:
+			case <-time.After(time.Millisecond * 200):
+			}
+		} else {
+			close(waiting)
+			waitingMutex.Unlock()
+		}
+		return stream.SendMsg(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING})
+	}))
+	addrs := make([]string, 0, 2)
+	for i := 0; i < 2; i++ {
+		listener, listenErr := net.Listen("tcp", "127.0.0.1:0")
+		c.Assert(listenErr, IsNil)
+		addrs = append(addrs, listener.Addr().String())
+		go server.Serve(listener)
+	}
+	defer server.Stop()
+
+	balancer, err := loadbalancer.NewAddrArrayContextBalancer(loadbalancer.WithOutlierDetection(context.Background(), &loadbalancer.OutlierConfig{}), addrs, true)
+	c.Assert(err, IsNil)
+	recorder := &reportRecorder{Balancer: balancer, mutex: new(sync.Mutex), reports: make(map[string]int)}
+	config := &HedgingConfig{
+		Methods: map[string]*HedgingPolicy{
+			"/test.Service/Get": {MaxAttempts: 2, Delay: time.Millisecond * 20},
+		},
+		BudgetMinPerSecond: 100,
+	}
+	serviceInfo := base.NewSimpleServiceInfo("test", "1.0", "dev", "https", "", "")
+	clientConn, err := NewGRPCClient(NewHedgingInitializer(config)).NewClientConn(context.Background(), serviceInfo, recorder, time.Second*3, true)
+	c.Assert(err, IsNil)
+	defer clientConn.Close()
+	for i := 0; i < 10; i++ {
+		reply := new(grpc_health_v1.HealthCheckResponse)
+		start := time.Now()
+		invokeErr := grpc.Invoke(context.Background(), "/test.Service/Get", &grpc_health_v1.HealthCheckRequest{}, reply, clientConn, make([]grpc.CallOption, 0, 4)...)
+		c.Assert(invokeErr, IsNil)
+		c.Assert(reply.Status, Equals, grpc_health_v1.HealthCheckResponse_SERVING)
+		c.Assert(time.Since(start) < time.Millisecond*150, Equals, true)
+	}
+	//每个成功的调用都按照实际的地址上报
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	total := 0
+	for addr, count := range recorder.reports {
+		c.Assert(addr == addrs[0] || addr == addrs[1], Equals, true)
+		total += count
+	}
+	c.Assert(total >= 10, Equals, true)
+}
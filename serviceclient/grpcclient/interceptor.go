@@ -0,0 +1,35 @@
+package grpcclient
+
+import (
+	"fmt"
+
+	"github.com/coffeehc/microserviceboot/base"
+	"google.golang.org/grpc"
+)
+
+//UnaryClientInterceptorChain 按名称管理的有序UnaryClientInterceptor链,每个 ClientConn 拥有自己的链
+type UnaryClientInterceptorChain interface {
+	AppendInterceptor(name string, interceptor grpc.UnaryClientInterceptor) base.Error
+	InsertBefore(target string, name string, interceptor grpc.UnaryClientInterceptor) base.Error
+	InsertAfter(target string, name string, interceptor grpc.UnaryClientInterceptor) base.Error
+	Remove(name string) base.Error
+}
+
+//StreamClientInterceptorChain 按名称管理的有序StreamClientInterceptor链,每个 ClientConn 拥有自己的链
+type StreamClientInterceptorChain interface {
+	AppendInterceptor(name string, interceptor grpc.StreamClientInterceptor) base.Error
+	InsertBefore(target string, name string, interceptor grpc.StreamClientInterceptor) base.Error
+	InsertAfter(target string, name string, interceptor grpc.StreamClientInterceptor) base.Error
+	Remove(name string) base.Error
+}
+
+//InterceptorInitializer 在创建 ClientConn 时初始化该连接的拦截器链
+type InterceptorInitializer func(serviceInfo base.ServiceInfo, unaryChain UnaryClientInterceptorChain, streamChain StreamClientInterceptorChain) base.Error
+
+func errInterceptorExist(name string) base.Error {
+	return base.NewError(base.Error_System, "grpc interceptor", fmt.Sprintf("%s 已经存在", name))
+}
+
+func errInterceptorNotFound(name string) base.Error {
+	return base.NewError(base.Error_System, "grpc interceptor", fmt.Sprintf("%s 不存在", name))
+}
@@ -0,0 +1,51 @@
+package grpcclient
+
+import (
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/base/grpcbase"
+	"github.com/coffeehc/microserviceboot/loadbalancer"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+//InterceptorNameOutlierDetection 上报调用结果用于被动健康检查的拦截器名称,Balancer 实现了 loadbalancer.ResultReporter 时自动添加到最内层
+const InterceptorNameOutlierDetection = "outlier_detection"
+
+func newOutlierDetectionInterceptor(reporter loadbalancer.ResultReporter) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		//每次调用使用自己的 peer,不修改调用方的 opts
+		p := &peer.Peer{}
+		err := invoker(ctx, method, req, reply, cc, append(opts[:len(opts):len(opts)], grpc.Peer(p))...)
+		if p.Addr == nil || ctx.Err() == context.Canceled {
+			return err
+		}
+		reporter.ReportResult(p.Addr.String(), isNodeFailure(err))
+		return err
+	}
+}
+
+//isNodeFailure 只有连接失败,超时与服务端内部错误视为节点异常,业务错误不影响节点的健康状态,
+// 服务端返回的 base.Error 与客户端拦截器产生的 base.Error 都不视为节点异常
+func isNodeFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if _, ok := err.(base.Error); ok {
+		return false
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		return true
+	}
+	if _, ok := grpcbase.ErrorFromStatus(s.Proto()); ok {
+		return false
+	}
+	switch s.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
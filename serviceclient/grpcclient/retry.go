@@ -0,0 +1,243 @@
+package grpcclient
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/coffeehc/logger"
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/base/grpcbase"
+	"github.com/coffeehc/microserviceboot/loadbalancer"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+)
+
+//InterceptorNameRetry 重试拦截器的名称
+const InterceptorNameRetry = "retry"
+
+const (
+	defaultRetryMaxAttempts        = 3
+	defaultRetryInitialBackoff     = time.Millisecond * 50
+	defaultRetryMaxBackoff         = time.Second
+	defaultRetryBackoffMultiplier  = 2
+	defaultRetryJitter             = 0.2
+	defaultRetryBudgetRatio        = 0.1
+	defaultRetryBudgetMinPerSecond = 10
+	retryBudgetMaxTokensSecond     = 10
+)
+
+//RetryPolicy 方法的重试策略,零值使用默认值
+type RetryPolicy struct {
+	//MaxAttempts 包括第一次调用在内的最大调用次数,默认3,1表示不重试
+	MaxAttempts int `yaml:"max_attempts" validate:"min=0"`
+	//Idempotent 方法是否幂等,非幂等的方法只在被限流或者不带服务端错误码的 Unavailable 时重试,
+	// 幂等的方法在所有 Unavailable,RetryableCodes 与 RetryableErrorCodes 时都会重试
+	Idempotent bool `yaml:"idempotent"`
+	//RetryableCodes 除 Unavailable 之外可以重试的 grpc 状态码
+	RetryableCodes []codes.Code `yaml:"retryable_codes"`
+	//RetryableErrorCodes 可以重试的 base.Error 错误码,服务端返回的错误码从 grpc 状态的 Details 中解析
+	RetryableErrorCodes []int32 `yaml:"retryable_error_codes"`
+	//InitialBackoff 第一次重试前的等待时间,默认50ms
+	InitialBackoff time.Duration `yaml:"initial_backoff" validate:"min=0s"`
+	//MaxBackoff 重试等待时间的上限,默认1s
+	MaxBackoff time.Duration `yaml:"max_backoff" validate:"min=0s"`
+	//BackoffMultiplier 每次重试等待时间的倍数,默认2
+	BackoffMultiplier float64 `yaml:"backoff_multiplier" validate:"min=0"`
+	//Jitter 等待时间随机浮动的比例,默认0.2
+	Jitter float64 `yaml:"jitter" validate:"min=0,max=1"`
+}
+
+func (policy *RetryPolicy) getMaxAttempts() int {
+	if policy.MaxAttempts <= 0 {
+		return defaultRetryMaxAttempts
+	}
+	return policy.MaxAttempts
+}
+
+//backoff 第 attempt 次调用失败之后的等待时间
+func (policy *RetryPolicy) backoff(attempt int) time.Duration {
+	initial := policy.InitialBackoff
+	if initial <= 0 {
+		initial = defaultRetryInitialBackoff
+	}
+	max := policy.MaxBackoff
+	if max <= 0 {
+		max = defaultRetryMaxBackoff
+	}
+	multiplier := policy.BackoffMultiplier
+	if multiplier <= 0 {
+		multiplier = defaultRetryBackoffMultiplier
+	}
+	jitter := policy.Jitter
+	if jitter <= 0 {
+		jitter = defaultRetryJitter
+	}
+	backoff := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+	if backoff > float64(max) {
+		backoff = float64(max)
+	}
+	backoff *= 1 - jitter + 2*jitter*rand.Float64()
+	return time.Duration(backoff)
+}
+
+func (policy *RetryPolicy) retryable(err error) bool {
+	if err == nil || err == context.Canceled {
+		return false
+	}
+	grpcCode, errorCode := grpcbase.ErrorCodes(err)
+	//熔断与超时时间不足是本地快速失败的错误,状态码同样是 Unavailable,重试只会得到相同的结果
+	if errorCode == base.Error_System_CircuitOpen || errorCode == base.Error_System_DeadlineTooShort {
+		return false
+	}
+	//被服务端限流的请求没有被处理,可以安全的重试其他节点
+	if errorCode == base.Error_System_RateLimited {
+		return true
+	}
+	//不带错误码的 Unavailable 是连接不可用,带有错误码的是服务端处理后返回的业务错误,非幂等的方法重试可能重复写入
+	if grpcCode == codes.Unavailable && (errorCode == 0 || policy.Idempotent) {
+		return true
+	}
+	if !policy.Idempotent {
+		return false
+	}
+	for _, c := range policy.RetryableCodes {
+		if grpcCode == c {
+			return true
+		}
+	}
+	for _, c := range policy.RetryableErrorCodes {
+		if errorCode == c {
+			return true
+		}
+	}
+	return false
+}
+
+//RetryConfig 重试配置,Default 为 nil 时只重试 Methods 中配置的方法
+type RetryConfig struct {
+	//Default 没有单独配置的方法使用的重试策略
+	Default *RetryPolicy `yaml:"default"`
+	//Methods 按方法配置的重试策略,key 为完整的方法名,如 /package.Service/Method
+	Methods map[string]*RetryPolicy `yaml:"methods"`
+	//BudgetRatio 每个 ClientConn 的重试次数不超过调用次数的比例,默认0.1
+	BudgetRatio float64 `yaml:"budget_ratio" validate:"min=0"`
+	//BudgetMinPerSecond 调用量很少时每秒至少允许的重试次数,默认10
+	BudgetMinPerSecond int `yaml:"budget_min_per_second" validate:"min=0"`
+}
+
+func (config *RetryConfig) getPolicy(method string) *RetryPolicy {
+	if policy, ok := config.Methods[method]; ok {
+		return policy
+	}
+	return config.Default
+}
+
+//NewRetryInitializer 为每个 ClientConn 添加重试拦截器,每个 ClientConn 拥有自己的重试预算,
+// 重试时排除已经失败的地址,等待时间超过 ctx 的 deadline 时不再重试,只对 unary 调用生效
+func NewRetryInitializer(config *RetryConfig) InterceptorInitializer {
+	return func(serviceInfo base.ServiceInfo, unaryChain UnaryClientInterceptorChain, streamChain StreamClientInterceptorChain) base.Error {
+		ratio := config.BudgetRatio
+		if ratio <= 0 {
+			ratio = defaultRetryBudgetRatio
+		}
+		minPerSecond := config.BudgetMinPerSecond
+		if minPerSecond <= 0 {
+			minPerSecond = defaultRetryBudgetMinPerSecond
+		}
+		return unaryChain.AppendInterceptor(InterceptorNameRetry, newRetryInterceptor(config, newRetryBudget(ratio, minPerSecond)))
+	}
+}
+
+func newRetryInterceptor(config *RetryConfig, budget *retryBudget) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		budget.deposit()
+		policy := config.getPolicy(method)
+		if policy == nil || policy.getMaxAttempts() <= 1 {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		maxAttempts := policy.getMaxAttempts()
+		for attempt := 1; ; attempt++ {
+			p := &peer.Peer{}
+			callOpts := append(opts[:len(opts):len(opts)], grpc.Peer(p))
+			if attempt < maxAttempts {
+				//最后一次之前快速失败,连接不可用时尽快重试其他地址
+				callOpts = append(callOpts, grpc.FailFast(true))
+			}
+			err := invoker(ctx, method, req, reply, cc, callOpts...)
+			if err == nil || attempt >= maxAttempts || ctx.Err() != nil || !policy.retryable(err) {
+				return err
+			}
+			backoff := policy.backoff(attempt)
+			if deadline, ok := ctx.Deadline(); ok && deadline.Sub(time.Now()) <= backoff {
+				return err
+			}
+			if !budget.withdraw() {
+				logger.Warn("%s 重试预算已经用完,不再重试", method)
+				return err
+			}
+			if p.Addr != nil {
+				ctx = loadbalancer.WithExcludedAddrs(ctx, p.Addr.String())
+			}
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+//retryBudget 重试预算,每次调用存入 ratio 个令牌,每次重试取出一个令牌,另外每秒补充 minPerSecond 个令牌,
+// 令牌最多积累 retryBudgetMaxTokensSecond 秒的补充量,避免故障时重试放大流量
+type retryBudget struct {
+	mutex        *sync.Mutex
+	ratio        float64
+	minPerSecond float64
+	maxTokens    float64
+	tokens       float64
+	lastRefill   time.Time
+}
+
+func newRetryBudget(ratio float64, minPerSecond int) *retryBudget {
+	maxTokens := float64(minPerSecond * retryBudgetMaxTokensSecond)
+	return &retryBudget{
+		mutex:        new(sync.Mutex),
+		ratio:        ratio,
+		minPerSecond: float64(minPerSecond),
+		maxTokens:    maxTokens,
+		tokens:       maxTokens,
+		lastRefill:   time.Now(),
+	}
+}
+
+func (b *retryBudget) add(tokens float64) {
+	b.tokens += tokens
+	if b.tokens > b.maxTokens {
+		b.tokens = b.maxTokens
+	}
+}
+
+func (b *retryBudget) deposit() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.add(b.ratio)
+}
+
+func (b *retryBudget) withdraw() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	now := time.Now()
+	b.add(now.Sub(b.lastRefill).Seconds() * b.minPerSecond)
+	b.lastRefill = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,146 @@
+package grpcclient
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/base/grpcbase"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+type RetrySuite struct {
+}
+
+var _ = Suite(&RetrySuite{})
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+//retryTestErrors 映射为 Unavailable 的业务错误码,用于测试非幂等方法不重试服务端返回的 Unavailable
+var retryTestErrors = base.RegisterErrorNamespace(0xFF1, "retry_test", "重试测试", http.StatusServiceUnavailable, codes.Unavailable)
+
+var retryTestStockLocked = retryTestErrors.Code(0x1, "stock_locked", "库存被锁定", 0, codes.OK)
+
+func failingInvoker(calls *int, failures int, code codes.Code) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		*calls++
+		if *calls <= failures {
+			return grpc.Errorf(code, "failure %d", *calls)
+		}
+		return nil
+	}
+}
+
+func (t *RetrySuite) TestRetry(c *C) {
+	config := &RetryConfig{
+		Default: &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+		Methods: map[string]*RetryPolicy{
+			"/test.Service/Get": {MaxAttempts: 3, Idempotent: true, RetryableCodes: []codes.Code{codes.Internal}, InitialBackoff: time.Millisecond},
+		},
+	}
+	interceptor := newRetryInterceptor(config, newRetryBudget(0.1, 10))
+	calls := 0
+	err := interceptor(context.Background(), "/test.Service/Create", nil, nil, nil, failingInvoker(&calls, 2, codes.Unavailable))
+	c.Assert(err, IsNil)
+	c.Assert(calls, Equals, 3)
+	//非幂等的方法只重试 Unavailable
+	calls = 0
+	err = interceptor(context.Background(), "/test.Service/Create", nil, nil, nil, failingInvoker(&calls, 1, codes.Internal))
+	c.Assert(grpc.Code(err), Equals, codes.Internal)
+	c.Assert(calls, Equals, 1)
+	calls = 0
+	err = interceptor(context.Background(), "/test.Service/Get", nil, nil, nil, failingInvoker(&calls, 1, codes.Internal))
+	c.Assert(err, IsNil)
+	c.Assert(calls, Equals, 2)
+	calls = 0
+	err = interceptor(context.Background(), "/test.Service/Get", nil, nil, nil, failingInvoker(&calls, 5, codes.Unavailable))
+	c.Assert(grpc.Code(err), Equals, codes.Unavailable)
+	c.Assert(calls, Equals, 3)
+	//服务端限流的错误码从 Details 中解析,非幂等的方法也会重试
+	calls = 0
+	rateLimited := status.ErrorProto(grpcbase.ErrorToStatus(base.NewError(base.Error_System_RateLimited, "rate limit", "too many requests")))
+	err = interceptor(context.Background(), "/test.Service/Create", nil, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		calls++
+		if calls == 1 {
+			return rateLimited
+		}
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(calls, Equals, 2)
+	c.Assert(isNodeFailure(rateLimited), Equals, false)
+}
+
+func (t *RetrySuite) TestRetryUnavailableBusinessError(c *C) {
+	config := &RetryConfig{
+		Default: &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+		Methods: map[string]*RetryPolicy{
+			"/test.Service/Get": {MaxAttempts: 3, Idempotent: true, InitialBackoff: time.Millisecond},
+		},
+	}
+	interceptor := newRetryInterceptor(config, newRetryBudget(0.1, 10))
+	businessError := status.ErrorProto(grpcbase.ErrorToStatus(base.NewError(retryTestStockLocked, "stock", "stock locked")))
+	c.Assert(grpc.Code(businessError), Equals, codes.Unavailable)
+	invoker := func(calls *int) grpc.UnaryInvoker {
+		return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			*calls++
+			return businessError
+		}
+	}
+	//服务端已经处理过的请求,非幂等的方法不重试
+	calls := 0
+	err := interceptor(context.Background(), "/test.Service/Create", nil, nil, nil, invoker(&calls))
+	c.Assert(err, Equals, businessError)
+	c.Assert(calls, Equals, 1)
+	calls = 0
+	err = interceptor(context.Background(), "/test.Service/Get", nil, nil, nil, invoker(&calls))
+	c.Assert(err, Equals, businessError)
+	c.Assert(calls, Equals, 3)
+}
+
+func (t *RetrySuite) TestRetryBudget(c *C) {
+	budget := newRetryBudget(0.5, 1)
+	budget.tokens = 0
+	c.Assert(budget.withdraw(), Equals, false)
+	budget.deposit()
+	budget.deposit()
+	c.Assert(budget.withdraw(), Equals, true)
+	c.Assert(budget.withdraw(), Equals, false)
+}
+
+func (t *RetrySuite) TestRetryDeadline(c *C) {
+	config := &RetryConfig{Default: &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second}}
+	interceptor := newRetryInterceptor(config, newRetryBudget(0.1, 10))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	calls := 0
+	err := interceptor(ctx, "/test.Service/Create", nil, nil, nil, failingInvoker(&calls, 5, codes.Unavailable))
+	c.Assert(grpc.Code(err), Equals, codes.Unavailable)
+	c.Assert(calls, Equals, 1)
+}
+
+func (t *RetrySuite) TestRetryFastFail(c *C) {
+	config := &RetryConfig{Default: &RetryPolicy{MaxAttempts: 3, Idempotent: true, RetryableCodes: []codes.Code{codes.DeadlineExceeded}, InitialBackoff: time.Millisecond}}
+	interceptor := newRetryInterceptor(config, newRetryBudget(0.1, 10))
+	//熔断与超时时间不足都是本地直接拒绝的调用,即使映射为 Unavailable 或配置为可重试的状态码也不重试
+	for _, fastFail := range []error{
+		base.NewError(base.Error_System_CircuitOpen, "circuit breaker", "open"),
+		base.NewError(base.Error_System_DeadlineTooShort, "deadline", "too short"),
+		status.ErrorProto(grpcbase.ErrorToStatus(base.NewError(base.Error_System_CircuitOpen, "circuit breaker", "open"))),
+	} {
+		calls := 0
+		err := interceptor(context.Background(), "/test.Service/Get", nil, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			calls++
+			return fastFail
+		})
+		c.Assert(err, Equals, fastFail)
+		c.Assert(calls, Equals, 1)
+	}
+}
@@ -0,0 +1,62 @@
+package grpcclient
+
+import (
+	"io"
+	"net"
+
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/base/grpcbase"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+type StreamInterceptorSuite struct {
+}
+
+var _ = Suite(&StreamInterceptorSuite{})
+
+func (t *StreamInterceptorSuite) TestStreamBaseError(c *C) {
+	//服务端与 grpcboot 一样把 base.Error 转换为 grpc 状态,请求的 Service 为 fail 时返回错误
+	server := grpc.NewServer(grpc.UnknownServiceHandler(func(srv interface{}, stream grpc.ServerStream) error {
+		req := &grpc_health_v1.HealthCheckRequest{}
+		if err := stream.RecvMsg(req); err != nil {
+			return err
+		}
+		if req.Service == "fail" {
+			return status.ErrorProto(grpcbase.ErrorToStatus(base.NewError(base.Error_Message_NotFount, "user", "user not found")))
+		}
+		return stream.SendMsg(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING})
+	}))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	go server.Serve(listener)
+	defer server.Stop()
+	serviceInfo := base.NewSimpleServiceInfo("test", "1.0", "dev", "http", "", "")
+	clientConn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure(), grpc.WithStreamInterceptor(newStreamClientInterceptor(serviceInfo).Interceptor))
+	c.Assert(err, IsNil)
+	defer clientConn.Close()
+	newStream := func(service string) grpc.ClientStream {
+		stream, err := grpc.NewClientStream(context.Background(), &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}, clientConn, "/test.Service/Watch")
+		c.Assert(err, IsNil)
+		c.Assert(stream.SendMsg(&grpc_health_v1.HealthCheckRequest{Service: service}), IsNil)
+		c.Assert(stream.CloseSend(), IsNil)
+		return stream
+	}
+
+	err = newStream("fail").RecvMsg(&grpc_health_v1.HealthCheckResponse{})
+	baseErr, ok := err.(base.Error)
+	c.Assert(ok, Equals, true)
+	c.Assert(baseErr.GetCode(), Equals, int32(base.Error_Message_NotFount))
+	c.Assert(baseErr.GetScopes(), Equals, "user")
+	c.Assert(baseErr.Error(), Equals, "user not found")
+
+	//正常结束的 stream 仍然返回 io.EOF
+	stream := newStream("ok")
+	reply := &grpc_health_v1.HealthCheckResponse{}
+	c.Assert(stream.RecvMsg(reply), IsNil)
+	c.Assert(reply.Status, Equals, grpc_health_v1.HealthCheckResponse_SERVING)
+	c.Assert(stream.RecvMsg(reply), Equals, io.EOF)
+}
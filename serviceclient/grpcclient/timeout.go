@@ -0,0 +1,67 @@
+package grpcclient
+
+import (
+	"time"
+
+	"github.com/coffeehc/microserviceboot/base"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+//InterceptorNameTimeout 调用超时拦截器的名称
+const InterceptorNameTimeout = "timeout"
+
+//CallTimeoutConfig 调用的默认超时时间,优先级为 Methods > Services > Default,为0表示不设置超时
+type CallTimeoutConfig struct {
+	//Default 所有调用的默认超时时间
+	Default time.Duration `yaml:"default" validate:"min=0s"`
+	//Services 按服务配置的超时时间,key 为服务名
+	Services map[string]time.Duration `yaml:"services"`
+	//Methods 按方法配置的超时时间,key 为完整的方法名,如 /package.Service/Method
+	Methods map[string]time.Duration `yaml:"methods"`
+	//Deadline 上游 deadline 的传递配置
+	Deadline base.DeadlineConfig `yaml:"deadline"`
+}
+
+func (config *CallTimeoutConfig) getTimeout(serviceName string, method string) time.Duration {
+	if timeout, ok := config.Methods[method]; ok {
+		return timeout
+	}
+	if timeout, ok := config.Services[serviceName]; ok {
+		return timeout
+	}
+	return config.Default
+}
+
+//NewTimeoutInitializer 为每个 ClientConn 添加超时拦截器,调用的超时时间取默认超时时间与上游剩余时间减去余量中较小的值,
+// 剩余时间不足时直接返回 Error_System_DeadlineTooShort,stream 调用只检查剩余时间
+func NewTimeoutInitializer(config *CallTimeoutConfig) InterceptorInitializer {
+	return func(serviceInfo base.ServiceInfo, unaryChain UnaryClientInterceptorChain, streamChain StreamClientInterceptorChain) base.Error {
+		serviceName := serviceInfo.GetServiceName()
+		err := unaryChain.AppendInterceptor(InterceptorNameTimeout, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			remaining, ok, err := config.Deadline.Remaining(ctx)
+			if err != nil {
+				return err
+			}
+			timeout := config.getTimeout(serviceName, method)
+			if ok && (timeout <= 0 || remaining < timeout) {
+				timeout = remaining
+			}
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+			return invoker(ctx, method, req, reply, cc, opts...)
+		})
+		if err != nil {
+			return err
+		}
+		return streamChain.AppendInterceptor(InterceptorNameTimeout, func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			if _, _, err := config.Deadline.Remaining(ctx); err != nil {
+				return nil, err
+			}
+			return streamer(ctx, desc, cc, method, opts...)
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package grpcclient
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type TimeoutSuite struct {
+}
+
+var _ = Suite(&TimeoutSuite{})
+
+func (t *TimeoutSuite) TestGetTimeout(c *C) {
+	config := &CallTimeoutConfig{
+		Default:  time.Second,
+		Services: map[string]time.Duration{"user": time.Second * 2, "order": 0},
+		Methods:  map[string]time.Duration{"/user.User/Get": time.Second * 3, "/user.User/List": 0},
+	}
+	c.Assert(config.getTimeout("other", "/other.Other/Get"), Equals, time.Second)
+	c.Assert(config.getTimeout("user", "/user.User/Create"), Equals, time.Second*2)
+	c.Assert(config.getTimeout("user", "/user.User/Get"), Equals, time.Second*3)
+	//显式配置为0表示不设置超时,不会使用更低优先级的配置
+	c.Assert(config.getTimeout("user", "/user.User/List"), Equals, time.Duration(0))
+	c.Assert(config.getTimeout("order", "/order.Order/Get"), Equals, time.Duration(0))
+}
@@ -0,0 +1,80 @@
+package grpcclient
+
+import (
+	"io"
+
+	"github.com/coffeehc/microserviceboot/tracing"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+//InterceptorNameTracing 链路追踪拦截器的名称,每个 ClientConn 默认添加
+const InterceptorNameTracing = "tracing"
+
+//startClientSpan 创建 client span 并通过 metadata 传递 traceparent
+func startClientSpan(ctx context.Context, serviceName string, method string) (context.Context, *tracing.Span) {
+	spanCtx, span := tracing.StartSpan(ctx, method, tracing.SpanKindClient)
+	span.SetAttribute("rpc.system", "grpc")
+	span.SetAttribute("rpc.method", method)
+	span.SetAttribute("peer.service", serviceName)
+	sc := span.SpanContext()
+	md, ok := metadata.FromOutgoingContext(spanCtx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	md[tracing.HeaderTraceparent] = []string{sc.Traceparent()}
+	if sc.TraceState != "" {
+		md[tracing.HeaderTracestate] = []string{sc.TraceState}
+	}
+	return metadata.NewOutgoingContext(spanCtx, md), span
+}
+
+func newTracingUnaryInterceptor(serviceName string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := startClientSpan(ctx, serviceName, method)
+		defer span.End()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		span.SetError(err)
+		return err
+	}
+}
+
+func newTracingStreamInterceptor(serviceName string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, span := startClientSpan(ctx, serviceName, method)
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			span.SetError(err)
+			span.End()
+			return nil, err
+		}
+		return &tracingClientStream{ClientStream: stream, span: span, serverStreams: desc.ServerStreams}, nil
+	}
+}
+
+//tracingClientStream 在读到结束或者出错时结束 span
+type tracingClientStream struct {
+	grpc.ClientStream
+	span          *tracing.Span
+	serverStreams bool
+}
+
+func (s *tracingClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF:
+		s.span.SetError(nil)
+		s.span.End()
+	case err != nil:
+		s.span.SetError(err)
+		s.span.End()
+	case !s.serverStreams:
+		//服务端只返回一个消息
+		s.span.SetError(nil)
+		s.span.End()
+	}
+	return err
+}
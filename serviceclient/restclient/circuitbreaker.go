@@ -0,0 +1,91 @@
+package restclient
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/coffeehc/commons/https/client"
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/base/restbase"
+	"github.com/coffeehc/microserviceboot/serviceclient/circuitbreaker"
+)
+
+//WithCircuitBreaker 为 ServiceClient 的 HTTPClient 添加熔断器,熔断器的名称为服务名,
+// perEndpoint 为 true 时每个接口使用独立的熔断器,名称为 服务名+方法+BuildRequest 的 endpoint 模板,
+// 不是通过 BuildRequest 创建的请求共用 unknown 的熔断器,请求错误或者状态码大于等于500视为失败,
+// 解析出的 base.Error 按 restbase.HTTPStatus 判断
+func WithCircuitBreaker(serviceClient ServiceClient, config *circuitbreaker.Config, perEndpoint bool) ServiceClient {
+	return &circuitBreakerServiceClient{
+		ServiceClient: serviceClient,
+		client: &circuitBreakerHTTPClient{
+			HTTPClient:  serviceClient.GetHttpClient(),
+			serviceName: serviceClient.GetServiceName(),
+			config:      config,
+			perEndpoint: perEndpoint,
+		},
+	}
+}
+
+type circuitBreakerServiceClient struct {
+	ServiceClient
+	client client.HTTPClient
+}
+
+func (sc *circuitBreakerServiceClient) GetHttpClient() client.HTTPClient {
+	return sc.client
+}
+
+type circuitBreakerHTTPClient struct {
+	client.HTTPClient
+	serviceName string
+	config      *circuitbreaker.Config
+	perEndpoint bool
+}
+
+func (c *circuitBreakerHTTPClient) getBreaker(method string, endpoint string) *circuitbreaker.Breaker {
+	if c.perEndpoint {
+		return circuitbreaker.GetBreaker(c.serviceName+" "+method+" "+endpoint, c.config)
+	}
+	return circuitbreaker.GetBreaker(c.serviceName, c.config)
+}
+
+//call endpoint 使用 endpoint 模板而不是实际的路径,避免带参数的路径创建无限多的熔断器
+func (c *circuitBreakerHTTPClient) call(method string, endpoint string, invoke func() (client.HTTPResponse, error)) (client.HTTPResponse, error) {
+	done, err := c.getBreaker(method, endpoint).Allow()
+	if err != nil {
+		return nil, err
+	}
+	resp, invokeErr := invoke()
+	done(isFailure(resp, invokeErr))
+	return resp, invokeErr
+}
+
+//isFailure 错误码对应的 http 状态码小于500的 base.Error 是调用方的错误,不视为失败
+func isFailure(resp client.HTTPResponse, err error) bool {
+	if err != nil {
+		if baseErr, ok := err.(base.Error); ok {
+			return restbase.HTTPStatus(baseErr.GetCode()) >= http.StatusInternalServerError
+		}
+		return true
+	}
+	return resp.GetStatusCode() >= http.StatusInternalServerError
+}
+
+func (c *circuitBreakerHTTPClient) Get(url string) (client.HTTPResponse, error) {
+	return c.call(http.MethodGet, unknownEndpoint, func() (client.HTTPResponse, error) {
+		return c.HTTPClient.Get(url)
+	})
+}
+
+func (c *circuitBreakerHTTPClient) POST(url string, body io.Reader, contentType string) (client.HTTPResponse, error) {
+	return c.call(http.MethodPost, unknownEndpoint, func() (client.HTTPResponse, error) {
+		return c.HTTPClient.POST(url, body, contentType)
+	})
+}
+
+func (c *circuitBreakerHTTPClient) Do(req client.HTTPRequest, autoRedirect bool) (client.HTTPResponse, error) {
+	realRequest := req.GetRealRequest()
+	return c.call(realRequest.Method, getEndpoint(realRequest.Context()), func() (client.HTTPResponse, error) {
+		return c.HTTPClient.Do(req, autoRedirect)
+	})
+}
@@ -0,0 +1,30 @@
+package restclient
+
+import (
+	"context"
+
+	"github.com/coffeehc/commons/https/client"
+	"github.com/coffeehc/microserviceboot/base"
+)
+
+//WithDeadline 把 cxt 传递给请求,cxt 取消时请求中断,cxt 有 deadline 时通过 base.HeaderRequestTimeout
+// 把减去余量之后的剩余时间传递给下游,剩余时间不足时返回 Error_System_DeadlineTooShort,config 为 nil 时使用默认配置
+func WithDeadline(cxt context.Context, request client.HTTPRequest, config *base.DeadlineConfig) base.Error {
+	if config == nil {
+		config = &base.DeadlineConfig{}
+	}
+	timeout, ok, err := config.Remaining(cxt)
+	if err != nil {
+		return err
+	}
+	realRequest := request.GetRealRequest()
+	//保留 BuildRequest 设置的 endpoint
+	if endpoint := getEndpoint(realRequest.Context()); endpoint != unknownEndpoint {
+		cxt = withEndpoint(cxt, endpoint)
+	}
+	*realRequest = *realRequest.WithContext(cxt)
+	if ok {
+		request.SetHeader(base.HeaderRequestTimeout, base.FormatRequestTimeout(timeout))
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package restclient
 import (
 	"context"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/coffeehc/microserviceboot/loadbalancer"
@@ -14,6 +15,9 @@ type _BalanceDialer struct {
 	KeepAlive time.Duration
 	Cancel    <-chan struct{}
 	balancer  loadbalancer.Balancer
+	mutex     sync.RWMutex
+	//nodes 连接的远端地址对应的节点地址,用于上报连接上每个请求的结果
+	nodes map[string]string
 }
 
 func (d *_BalanceDialer) deadline(ctx context.Context, now time.Time) (earliest time.Time) {
@@ -50,18 +54,30 @@ func (d *_BalanceDialer) DialContext(ctx context.Context, network, address strin
 		}()
 		ctx = subCtx
 	}
-	addr, _, err := d.balancer.Get(ctx, loadbalancer.BalancerGetOptions{
+	addr, put, err := d.balancer.Get(ctx, loadbalancer.BalancerGetOptions{
 		BlockingWait: true,
 	})
 
 	if err != nil {
 		return nil, &net.OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: err}
 	}
+	if put != nil {
+		//http 的连接会被复用,put 只统计建立连接的过程,每个请求的结果与延迟由 outlierHTTPClient 上报
+		defer put()
+	}
 
 	c, err := net.Dial(network, addr.Addr)
 	if err != nil {
+		//建立连接成功不代表节点正常,不上报成功,以免清除请求失败的计数
+		d.reportResult(addr.Addr, true, 0)
 		return nil, err
 	}
+	d.mutex.Lock()
+	if d.nodes == nil {
+		d.nodes = make(map[string]string)
+	}
+	d.nodes[c.RemoteAddr().String()] = addr.Addr
+	d.mutex.Unlock()
 
 	if tc, ok := c.(*net.TCPConn); ok && d.KeepAlive > 0 {
 		tc.SetKeepAlive(true)
@@ -70,6 +86,24 @@ func (d *_BalanceDialer) DialContext(ctx context.Context, network, address strin
 	return c, nil
 }
 
+//node 返回连接对应的节点地址,连接不是由 DialContext 建立时返回 false
+func (d *_BalanceDialer) node(conn net.Conn) (string, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	addr, ok := d.nodes[conn.RemoteAddr().String()]
+	return addr, ok
+}
+
+//reportResult 向 Balancer 上报节点的调用结果,latency 大于0时同时上报延迟
+func (d *_BalanceDialer) reportResult(addr string, failed bool, latency time.Duration) {
+	if reporter, ok := d.balancer.(loadbalancer.ResultReporter); ok {
+		reporter.ReportResult(addr, failed)
+	}
+	if reporter, ok := d.balancer.(loadbalancer.LatencyReporter); ok && latency > 0 {
+		reporter.ReportLatency(addr, latency)
+	}
+}
+
 func minNonzeroTime(a, b time.Time) time.Time {
 	if a.IsZero() {
 		return b
@@ -0,0 +1,39 @@
+package restclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/coffeehc/commons/https/client"
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/base/restbase"
+)
+
+//maxErrorBodySize 解析错误响应时最多读取的字节数
+const maxErrorBodySize = 64 * 1024
+
+//DecodeErrorResponse 将状态码大于等于400的响应解析为 base.Error,响应内容是 restboot 返回的错误时保留原来的错误码与 scope,
+// 否则按状态码使用 restbase.ErrorCodeFromStatus 的错误码,状态码小于400时返回 nil,
+// 解析之后 Body 仍然可以从头读取,调用方需要关闭 Body
+func DecodeErrorResponse(resp client.HTTPResponse) base.Error {
+	status := resp.GetStatusCode()
+	if status < http.StatusBadRequest {
+		return nil
+	}
+	realResponse := resp.GetRealResponse()
+	body := realResponse.Body
+	data, _ := ioutil.ReadAll(io.LimitReader(body, maxErrorBodySize))
+	realResponse.Body = &readCloser{Reader: io.MultiReader(bytes.NewReader(data), body), Closer: body}
+	if err := base.ParseErrorFromJSON(data); err != nil && err.GetCode() != 0 {
+		return err
+	}
+	return base.NewError(restbase.ErrorCodeFromStatus(status), err_scope_rest_response, fmt.Sprintf("http status %d: %s", status, http.StatusText(status)))
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,71 @@
+package restclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/coffeehc/commons/https/client"
+	"github.com/coffeehc/microserviceboot/base"
+	. "gopkg.in/check.v1"
+)
+
+type ErrorsSuite struct {
+}
+
+var _ = Suite(&ErrorsSuite{})
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+//testResponse 只实现解析错误使用的方法
+type testResponse struct {
+	client.HTTPResponse
+	resp *http.Response
+}
+
+func (r *testResponse) GetStatusCode() int {
+	return r.resp.StatusCode
+}
+
+func (r *testResponse) GetRealResponse() *http.Response {
+	return r.resp
+}
+
+func newTestResponse(statusCode int, body string) *testResponse {
+	return &testResponse{resp: &http.Response{StatusCode: statusCode, Body: ioutil.NopCloser(strings.NewReader(body))}}
+}
+
+//testHTTPClient 返回固定的响应
+type testHTTPClient struct {
+	client.HTTPClient
+	resp client.HTTPResponse
+}
+
+func (c *testHTTPClient) Do(req client.HTTPRequest, autoRedirect bool) (client.HTTPResponse, error) {
+	return c.resp, nil
+}
+
+func (t *ErrorsSuite) TestDecodeErrorResponse(c *C) {
+	c.Assert(DecodeErrorResponse(newTestResponse(http.StatusOK, "ok")), IsNil)
+	body := string(base.ErrorToJson(base.NewError(base.Error_Message_NotFount, "user", "not found")))
+	resp := newTestResponse(http.StatusNotFound, body)
+	err := DecodeErrorResponse(resp)
+	c.Assert(err, NotNil)
+	c.Assert(err.GetCode(), Equals, int32(base.Error_Message_NotFount))
+	c.Assert(err.GetScopes(), Equals, "user")
+	//解析之后仍然可以读取原始的响应内容
+	data, _ := ioutil.ReadAll(resp.GetRealResponse().Body)
+	c.Assert(string(data), Equals, body)
+
+	resp = newTestResponse(http.StatusBadGateway, "bad gateway")
+	instrumented := &instrumentedHTTPClient{HTTPClient: &testHTTPClient{resp: resp}, serviceName: "test"}
+	req, _ := client.NewHTTPRequest(http.MethodGet, "http://test/path")
+	got, doErr := instrumented.Do(req, true)
+	c.Assert(doErr, NotNil)
+	c.Assert(got, Equals, client.HTTPResponse(resp))
+	data, _ = ioutil.ReadAll(got.GetRealResponse().Body)
+	c.Assert(string(data), Equals, "bad gateway")
+}
@@ -0,0 +1,98 @@
+package restclient
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/coffeehc/commons/https/client"
+	"github.com/coffeehc/microserviceboot/base/restbase"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+//unknownEndpoint 不是通过 BuildRequest 创建的请求使用的 endpoint label
+const unknownEndpoint = "unknown"
+
+var (
+	clientRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "microservice",
+		Subsystem: "http_client",
+		Name:      "requests_total",
+		Help:      "Total number of requests sent by rest clients.",
+	}, []string{"service", "method", "endpoint", "status_class"})
+	clientDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "microservice",
+		Subsystem: "http_client",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of requests sent by rest clients.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service", "method", "endpoint", "status_class"})
+)
+
+func init() {
+	prometheus.MustRegister(clientRequests, clientDuration)
+}
+
+type endpointKey struct{}
+
+func withEndpoint(cxt context.Context, endpoint string) context.Context {
+	return context.WithValue(cxt, endpointKey{}, endpoint)
+}
+
+func getEndpoint(cxt context.Context) string {
+	if endpoint, ok := cxt.Value(endpointKey{}).(string); ok {
+		return endpoint
+	}
+	return unknownEndpoint
+}
+
+//instrumentedHTTPClient 为每个请求记录监控数据,endpoint label 使用 BuildRequest 的路径模板,
+// 状态码大于等于400时同时返回 resp 与 DecodeErrorResponse 解析出的 base.Error
+type instrumentedHTTPClient struct {
+	client.HTTPClient
+	serviceName string
+}
+
+func (c *instrumentedHTTPClient) Get(url string) (client.HTTPResponse, error) {
+	req, err := client.NewHTTPRequest(http.MethodGet, url)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req, true)
+}
+
+func (c *instrumentedHTTPClient) POST(url string, body io.Reader, contentType string) (client.HTTPResponse, error) {
+	req, err := client.NewHTTPRequest(http.MethodPost, url)
+	if err != nil {
+		return nil, err
+	}
+	readerCloser, ok := body.(io.ReadCloser)
+	if !ok {
+		readerCloser = ioutil.NopCloser(body)
+	}
+	req.SetBodyStream(readerCloser)
+	req.SetContentType(contentType)
+	return c.Do(req, true)
+}
+
+func (c *instrumentedHTTPClient) Do(req client.HTTPRequest, autoRedirect bool) (client.HTTPResponse, error) {
+	realRequest := req.GetRealRequest()
+	start := time.Now()
+	resp, err := c.HTTPClient.Do(req, autoRedirect)
+	statusClass := "error"
+	defer func() {
+		endpoint := getEndpoint(realRequest.Context())
+		clientRequests.WithLabelValues(c.serviceName, realRequest.Method, endpoint, statusClass).Inc()
+		clientDuration.WithLabelValues(c.serviceName, realRequest.Method, endpoint, statusClass).Observe(time.Since(start).Seconds())
+	}()
+	if err != nil {
+		return resp, err
+	}
+	statusClass = restbase.StatusClass(resp.GetStatusCode())
+	if errResponse := DecodeErrorResponse(resp); errResponse != nil {
+		return resp, errResponse
+	}
+	return resp, nil
+}
@@ -0,0 +1,81 @@
+package restclient
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+
+	"github.com/coffeehc/commons/https/client"
+)
+
+//outlierHTTPClient 向 Balancer 上报每个请求的结果与延迟,连接失败,超时与5xx视为节点异常,
+// 返回5xx的连接不再复用,之后的请求重新通过 Balancer 选择节点,被摘除的节点不会再被选中
+type outlierHTTPClient struct {
+	client.HTTPClient
+	dialer *_BalanceDialer
+}
+
+func (c *outlierHTTPClient) Get(url string) (client.HTTPResponse, error) {
+	req, err := client.NewHTTPRequest(http.MethodGet, url)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req, true)
+}
+
+func (c *outlierHTTPClient) POST(url string, body io.Reader, contentType string) (client.HTTPResponse, error) {
+	req, err := client.NewHTTPRequest(http.MethodPost, url)
+	if err != nil {
+		return nil, err
+	}
+	readerCloser, ok := body.(io.ReadCloser)
+	if !ok {
+		readerCloser = ioutil.NopCloser(body)
+	}
+	req.SetBodyStream(readerCloser)
+	req.SetContentType(contentType)
+	return c.Do(req, true)
+}
+
+func (c *outlierHTTPClient) Do(req client.HTTPRequest, autoRedirect bool) (client.HTTPResponse, error) {
+	realRequest := req.GetRealRequest()
+	var conn net.Conn
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			conn = info.Conn
+		},
+	}
+	*realRequest = *realRequest.WithContext(httptrace.WithClientTrace(realRequest.Context(), trace))
+	start := time.Now()
+	resp, err := c.HTTPClient.Do(req, autoRedirect)
+	//没有拿到连接时建立连接的失败已经由 DialContext 上报,调用方取消的请求与节点无关
+	if conn == nil || realRequest.Context().Err() == context.Canceled {
+		return resp, err
+	}
+	addr, ok := c.dialer.node(conn)
+	if !ok {
+		return resp, err
+	}
+	failed := err != nil || resp.GetStatusCode() >= http.StatusInternalServerError
+	c.dialer.reportResult(addr, failed, time.Since(start))
+	if err == nil && failed {
+		realResponse := resp.GetRealResponse()
+		realResponse.Body = &discardConnBody{ReadCloser: realResponse.Body, conn: conn}
+	}
+	return resp, err
+}
+
+//discardConnBody 关闭 Body 时先关闭连接,连接不会放回连接池
+type discardConnBody struct {
+	io.ReadCloser
+	conn net.Conn
+}
+
+func (body *discardConnBody) Close() error {
+	body.conn.Close()
+	return body.ReadCloser.Close()
+}
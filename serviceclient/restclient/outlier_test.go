@@ -0,0 +1,56 @@
+package restclient
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/coffeehc/commons/https/client"
+	"github.com/coffeehc/microserviceboot/base"
+	"github.com/coffeehc/microserviceboot/loadbalancer"
+	. "gopkg.in/check.v1"
+)
+
+type OutlierSuite struct {
+}
+
+var _ = Suite(&OutlierSuite{})
+
+func (suite *OutlierSuite) TestEjectServerErrorNode(c *C) {
+	var badHits, goodHits int32
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&badHits, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer bad.Close()
+	//正常的节点不复用连接,每个请求都重新通过 Balancer 选择节点
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&goodHits, 1)
+		w.Header().Set("Connection", "close")
+	}))
+	defer good.Close()
+
+	balancer, err := loadbalancer.NewContextBalancer(loadbalancer.WithOutlierDetection(context.Background(), &loadbalancer.OutlierConfig{ConsecutiveErrors: 2}), nil)
+	c.Assert(err, IsNil)
+	defer balancer.Close()
+	for _, server := range []*httptest.Server{bad, good} {
+		addr := server.Listener.Addr().String()
+		c.Assert(balancer.Start(addr, loadbalancer.BalancerConfig{}), IsNil)
+		balancer.Up(loadbalancer.Address{Addr: addr})
+	}
+	serviceInfo := base.NewSimpleServiceInfo("test", "1.0", "dev", "http", "", "")
+	restClient := newHttpClient(context.Background(), serviceInfo, balancer, &client.HTTPClientOptions{Timeout: time.Second * 3, DialerKeepAlive: time.Minute})
+	httpClient := &outlierHTTPClient{HTTPClient: client.NewHTTPClient(restClient.options, restClient.transport), dialer: restClient.dialer}
+	for i := 0; i < 10; i++ {
+		resp, err := httpClient.Get("http://test.service/")
+		c.Assert(err, IsNil)
+		ioutil.ReadAll(resp.GetBody())
+		resp.GetBody().Close()
+	}
+	//连续两次5xx之后被摘除,返回5xx的连接不再复用,之后的请求都发送到正常的节点
+	c.Assert(atomic.LoadInt32(&badHits), Equals, int32(2))
+	c.Assert(atomic.LoadInt32(&goodHits), Equals, int32(8))
+}
@@ -12,6 +12,7 @@ import (
 type restClient struct {
 	options     *client.HTTPClientOptions
 	transport   *http.Transport
+	dialer      *_BalanceDialer
 	serviceInfo base.ServiceInfo
 }
 
@@ -22,13 +23,15 @@ func (rc *restClient) getTransport() *http.Transport {
 func newHttpClient(cxt context.Context, serviceInfo base.ServiceInfo, balancer loadbalancer.Balancer, defaultOption *client.HTTPClientOptions) *restClient {
 	_clientOptions := &restClient{}
 	_clientOptions.serviceInfo = serviceInfo
-	transport := defaultOption.NewTransport((&_BalanceDialer{
+	dialer := &_BalanceDialer{
 		Timeout:   defaultOption.GetTimeout(),
 		KeepAlive: defaultOption.GetDialerKeepAlive(),
 		balancer:  balancer,
-	}).DialContext)
+	}
+	transport := defaultOption.NewTransport(dialer.DialContext)
 
 	_clientOptions.transport = transport
+	_clientOptions.dialer = dialer
 	options := &client.HTTPClientOptions{
 		Timeout:                        defaultOption.GetTimeout(),
 		DialerTimeout:                  defaultOption.GetDialerTimeout(),
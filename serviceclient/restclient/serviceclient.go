@@ -3,11 +3,13 @@ package restclient
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/coffeehc/commons/https/client"
 	"github.com/coffeehc/microserviceboot/base"
 	"github.com/coffeehc/microserviceboot/base/restbase"
 	"github.com/coffeehc/microserviceboot/consultool"
+	"github.com/coffeehc/microserviceboot/discovery"
 	"github.com/coffeehc/microserviceboot/loadbalancer"
 	"github.com/hashicorp/consul/api"
 )
@@ -15,8 +17,11 @@ import (
 type ServiceClient interface {
 	GetServiceName() string
 	GetBaseUrl() string
+	//GetHttpClient 状态码大于等于400时同时返回响应与 DecodeErrorResponse 解析出的 base.Error
 	GetHttpClient() client.HTTPClient
 	BuildRequest(endpintMeta restbase.EndpointMeta, query string) (client.HTTPRequest, error)
+	//Close 关闭 balancer,以及使用 *discovery.Config 创建时内部创建的 Backend
+	Close()
 }
 
 func NewServiceClient(serviceInfo base.ServiceInfo, httpClientConfig *client.HTTPClientOptions, discoveryConfig interface{}) (ServiceClient, base.Error) {
@@ -29,33 +34,78 @@ func NewServiceClient(serviceInfo base.ServiceInfo, httpClientConfig *client.HTT
 	if httpClientConfig == nil {
 		httpClientConfig = &client.HTTPClientOptions{}
 	}
-	rootCxt := context.Background()
+	rootCxt, cancel := context.WithCancel(context.Background())
+	cleanFuncs := []func(){cancel}
+	closeAll := func() {
+		for i := len(cleanFuncs) - 1; i >= 0; i-- {
+			cleanFuncs[i]()
+		}
+	}
 	var balancer loadbalancer.Balancer
 	var baseURL string
 	var err base.Error
 	switch c := discoveryConfig.(type) {
 	case string: //host
 		if c == "" {
+			cancel()
 			return nil, base.NewError(base.Error_System, "rest client", "discoveryConfig is a addrs")
 		}
 		balancer, err = loadbalancer.NewAddrArrayBalancer([]string{c}, serviceInfo.GetScheme() == "https")
 		if err != nil {
+			cancel()
 			return nil, base.NewErrorWrapper(0, "rest client", err)
 		}
 		baseURL = fmt.Sprintf("%s://%s", serviceInfo.GetScheme(), c)
+	case *discovery.Config:
+		backend, backendErr := discovery.NewBackend(c)
+		if backendErr != nil {
+			cancel()
+			return nil, backendErr
+		}
+		//backend 由 ServiceClient 创建,随 ServiceClient 一起关闭
+		cleanFuncs = append(cleanFuncs, backend.Close)
+		balancer, err = backend.NewBalancer(rootCxt, serviceInfo)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		baseURL = fmt.Sprintf("%s://%s.%s.service", serviceInfo.GetScheme(), serviceInfo.GetServiceTag(), serviceInfo.GetServiceName())
+	case discovery.Backend:
+		balancer, err = c.NewBalancer(rootCxt, serviceInfo)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		baseURL = fmt.Sprintf("%s://%s.%s.service", serviceInfo.GetScheme(), serviceInfo.GetServiceTag(), serviceInfo.GetServiceName())
 	case *api.Client:
 		balancer, err = consultool.NewConsulBalancer(rootCxt, c, serviceInfo)
 		if err != nil {
+			cancel()
 			return nil, err
 		}
 		baseURL = fmt.Sprintf("%s://%s.%s.service", serviceInfo.GetScheme(), serviceInfo.GetServiceTag(), serviceInfo.GetServiceName())
+	default:
+		cancel()
+		return nil, base.NewError(base.Error_System, "rest client", fmt.Sprintf("不支持的 discoveryConfig 类型 %T", discoveryConfig))
 	}
+	cleanFuncs = append(cleanFuncs, func() { balancer.Close() })
 	restClient := newHttpClient(rootCxt, serviceInfo, balancer, httpClientConfig)
 	return &_ServiceClient{
 		_restClient: restClient,
-		client:      client.NewHTTPClient(restClient.options, restClient.transport),
+		client: &instrumentedHTTPClient{
+			HTTPClient: &tracingHTTPClient{
+				HTTPClient: &outlierHTTPClient{
+					HTTPClient: client.NewHTTPClient(restClient.options, restClient.transport),
+					dialer:     restClient.dialer,
+				},
+				serviceName: serviceInfo.GetServiceName(),
+			},
+			serviceName: serviceInfo.GetServiceName(),
+		},
 		serviceInfo: serviceInfo,
 		baseURL:     baseURL,
+		closeOnce:   new(sync.Once),
+		closeFunc:   closeAll,
 	}, nil
 }
 
@@ -64,6 +114,12 @@ type _ServiceClient struct {
 	client      client.HTTPClient
 	serviceInfo base.ServiceInfo
 	baseURL     string
+	closeOnce   *sync.Once
+	closeFunc   func()
+}
+
+func (sc *_ServiceClient) Close() {
+	sc.closeOnce.Do(sc.closeFunc)
 }
 
 func (sc *_ServiceClient) GetServiceName() string {
@@ -78,6 +134,13 @@ func (sc *_ServiceClient) GetHttpClient() client.HTTPClient {
 	return sc.client
 }
 
+//BuildRequest 创建请求,监控数据使用 endpintMeta.Path 作为 endpoint label
 func (sc *_ServiceClient) BuildRequest(endpintMeta restbase.EndpointMeta, query string) (client.HTTPRequest, error) {
-	return client.NewHTTPRequest(string(endpintMeta.Method), fmt.Sprintf("%s/%s?%s", sc.baseURL, endpintMeta.Path, query))
+	req, err := client.NewHTTPRequest(string(endpintMeta.Method), fmt.Sprintf("%s/%s?%s", sc.baseURL, endpintMeta.Path, query))
+	if err != nil {
+		return nil, err
+	}
+	realRequest := req.GetRealRequest()
+	*realRequest = *realRequest.WithContext(withEndpoint(realRequest.Context(), endpintMeta.Path))
+	return req, nil
 }
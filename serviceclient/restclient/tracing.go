@@ -0,0 +1,66 @@
+package restclient
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/coffeehc/commons/https/client"
+	"github.com/coffeehc/microserviceboot/tracing"
+)
+
+//tracingHTTPClient 为每个请求创建 client span 并通过 Header 传递 traceparent,
+// 请求的 context 中有 span 时(如使用 WithDeadline 传递了 cxt)作为它的子 span
+type tracingHTTPClient struct {
+	client.HTTPClient
+	serviceName string
+}
+
+func (c *tracingHTTPClient) Get(url string) (client.HTTPResponse, error) {
+	req, err := client.NewHTTPRequest(http.MethodGet, url)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req, true)
+}
+
+func (c *tracingHTTPClient) POST(url string, body io.Reader, contentType string) (client.HTTPResponse, error) {
+	req, err := client.NewHTTPRequest(http.MethodPost, url)
+	if err != nil {
+		return nil, err
+	}
+	readerCloser, ok := body.(io.ReadCloser)
+	if !ok {
+		readerCloser = ioutil.NopCloser(body)
+	}
+	req.SetBodyStream(readerCloser)
+	req.SetContentType(contentType)
+	return c.Do(req, true)
+}
+
+func (c *tracingHTTPClient) Do(req client.HTTPRequest, autoRedirect bool) (client.HTTPResponse, error) {
+	realRequest := req.GetRealRequest()
+	_, span := tracing.StartSpan(realRequest.Context(), realRequest.Method+" "+realRequest.URL.Path, tracing.SpanKindClient)
+	defer span.End()
+	span.SetAttribute("http.method", realRequest.Method)
+	span.SetAttribute("http.url", realRequest.URL.String())
+	span.SetAttribute("peer.service", c.serviceName)
+	sc := span.SpanContext()
+	req.SetHeader(tracing.HeaderTraceparent, sc.Traceparent())
+	if sc.TraceState != "" {
+		req.SetHeader(tracing.HeaderTracestate, sc.TraceState)
+	}
+	resp, err := c.HTTPClient.Do(req, autoRedirect)
+	if err != nil {
+		span.SetError(err)
+		return resp, err
+	}
+	span.SetAttribute("http.status_code", fmt.Sprint(resp.GetStatusCode()))
+	if resp.GetStatusCode() >= http.StatusInternalServerError {
+		span.SetError(fmt.Errorf("http status %d", resp.GetStatusCode()))
+	} else {
+		span.SetError(nil)
+	}
+	return resp, nil
+}
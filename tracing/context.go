@@ -0,0 +1,140 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	//HeaderTraceparent W3C trace-context 的 traceparent Header,grpc 的 metadata 同名
+	HeaderTraceparent = "traceparent"
+	//HeaderTracestate W3C trace-context 的 tracestate Header,原样传递
+	HeaderTracestate = "tracestate"
+
+	traceparentVersion = "00"
+	flagSampled        = 0x01
+)
+
+//TraceID 16字节的 trace id
+type TraceID [16]byte
+
+//SpanID 8字节的 span id
+type SpanID [8]byte
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+//IsValid 全0的 id 无效
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+//IsValid 全0的 id 无效
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+//SpanContext 跨进程传递的 span 信息
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string
+}
+
+//IsValid trace id 与 span id 都有效
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+//Traceparent 格式化为 traceparent Header 的值
+func (sc SpanContext) Traceparent() string {
+	flags := 0
+	if sc.Sampled {
+		flags = flagSampled
+	}
+	return fmt.Sprintf("%s-%s-%s-%02x", traceparentVersion, sc.TraceID, sc.SpanID, flags)
+}
+
+//ParseTraceparent 解析 traceparent Header,格式错误时 ok 为 false
+func ParseTraceparent(traceparent string, tracestate string) (sc SpanContext, ok bool) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	//版本00必须正好4段,更高的版本忽略后面的字段
+	if parts[0] == traceparentVersion && len(parts) != 4 {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags[0]&flagSampled == flagSampled
+	sc.TraceState = tracestate
+	return sc, true
+}
+
+type spanKey struct{}
+type remoteSpanContextKey struct{}
+
+//ContextWithSpan 把 span 放入 cxt,之后在 cxt 上创建的 span 作为它的子 span
+func ContextWithSpan(cxt context.Context, span *Span) context.Context {
+	return context.WithValue(cxt, spanKey{}, span)
+}
+
+//SpanFromContext 获取 cxt 中的 span,没有时返回 nil
+func SpanFromContext(cxt context.Context) *Span {
+	if cxt == nil {
+		return nil
+	}
+	span, _ := cxt.Value(spanKey{}).(*Span)
+	return span
+}
+
+//ContextWithRemoteSpanContext 把从请求中解析出的上游 SpanContext 放入 cxt
+func ContextWithRemoteSpanContext(cxt context.Context, sc SpanContext) context.Context {
+	return context.WithValue(cxt, remoteSpanContextKey{}, sc)
+}
+
+//SpanContextFromContext 获取 cxt 中当前的 SpanContext,优先使用本地的 span
+func SpanContextFromContext(cxt context.Context) (SpanContext, bool) {
+	if span := SpanFromContext(cxt); span != nil {
+		return span.SpanContext(), true
+	}
+	if cxt == nil {
+		return SpanContext{}, false
+	}
+	sc, ok := cxt.Value(remoteSpanContextKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
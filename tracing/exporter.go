@@ -0,0 +1,218 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/coffeehc/microserviceboot/base"
+)
+
+const (
+	//ExporterOTLP 使用 OTLP/HTTP JSON 导出
+	ExporterOTLP = "otlp"
+	//ExporterStdout 输出到标准输出,用于本地调试
+	ExporterStdout = "stdout"
+	//ExporterFile 写入文件,用于本地调试
+	ExporterFile = "file"
+
+	defaultOTLPEndpoint = "http://localhost:4318/v1/traces"
+	otlpScopeName       = "github.com/coffeehc/microserviceboot/tracing"
+)
+
+//Exporter span 导出器,由 Tracer 在单个 goroutine 中调用
+type Exporter interface {
+	Export(spans []*SpanData) error
+	Close() error
+}
+
+//ExporterBuilder 根据配置创建 Exporter
+type ExporterBuilder func(config *Config) (Exporter, base.Error)
+
+var (
+	exportersMutex = new(sync.Mutex)
+	exporters      = map[string]ExporterBuilder{
+		ExporterOTLP:   newOTLPExporter,
+		ExporterStdout: newStdoutExporter,
+		ExporterFile:   newFileExporter,
+	}
+)
+
+//RegisterExporter 注册 Exporter,name 用于 Config.Exporter,相同名称覆盖之前的注册
+func RegisterExporter(name string, builder ExporterBuilder) {
+	exportersMutex.Lock()
+	defer exportersMutex.Unlock()
+	exporters[name] = builder
+}
+
+func getExporterBuilder(name string) (ExporterBuilder, bool) {
+	exportersMutex.Lock()
+	defer exportersMutex.Unlock()
+	builder, ok := exporters[name]
+	return builder, ok
+}
+
+//writerExporter 每行输出一个 span 的 json
+type writerExporter struct {
+	writer io.Writer
+	closer io.Closer
+}
+
+//NewWriterExporter 创建输出到 writer 的 Exporter,每行一个 span 的 json
+func NewWriterExporter(writer io.Writer) Exporter {
+	return &writerExporter{writer: writer}
+}
+
+func newStdoutExporter(config *Config) (Exporter, base.Error) {
+	return NewWriterExporter(os.Stdout), nil
+}
+
+func newFileExporter(config *Config) (Exporter, base.Error) {
+	if config.File == "" {
+		return nil, base.NewError(base.Error_System, errScopeTracing, "file exporter 没有指定 file")
+	}
+	file, err := os.OpenFile(config.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, base.NewErrorWrapper(base.Error_System, errScopeTracing, err)
+	}
+	return &writerExporter{writer: file, closer: file}, nil
+}
+
+func (e *writerExporter) Export(spans []*SpanData) error {
+	encoder := json.NewEncoder(e.writer)
+	for _, span := range spans {
+		if err := encoder.Encode(span); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *writerExporter) Close() error {
+	if e.closer == nil {
+		return nil
+	}
+	return e.closer.Close()
+}
+
+//otlpExporter 使用 OTLP/HTTP 的 JSON 编码导出
+type otlpExporter struct {
+	endpoint string
+	client   *http.Client
+}
+
+func newOTLPExporter(config *Config) (Exporter, base.Error) {
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = defaultOTLPEndpoint
+	}
+	return &otlpExporter{endpoint: endpoint, client: &http.Client{Timeout: time.Second * 10}}, nil
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    StatusCode `json:"code,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []*otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpRequest struct {
+	ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+}
+
+func toOTLPAttributes(attributes map[string]string) []otlpKeyValue {
+	keyValues := make([]otlpKeyValue, 0, len(attributes))
+	for key, value := range attributes {
+		keyValues = append(keyValues, otlpKeyValue{Key: key, Value: otlpValue{StringValue: value}})
+	}
+	return keyValues
+}
+
+func (e *otlpExporter) Export(spans []*SpanData) error {
+	//按服务分组,每个服务一个 resource
+	resources := make(map[string]*otlpResourceSpans)
+	request := &otlpRequest{}
+	for _, span := range spans {
+		key := span.ServiceName + "/" + span.ServiceVersion
+		resource, ok := resources[key]
+		if !ok {
+			resource = &otlpResourceSpans{ScopeSpans: []*otlpScopeSpans{{}}}
+			resource.Resource.Attributes = toOTLPAttributes(map[string]string{
+				"service.name":    span.ServiceName,
+				"service.version": span.ServiceVersion,
+			})
+			resource.ScopeSpans[0].Scope.Name = otlpScopeName
+			resources[key] = resource
+			request.ResourceSpans = append(request.ResourceSpans, resource)
+		}
+		resource.ScopeSpans[0].Spans = append(resource.ScopeSpans[0].Spans, &otlpSpan{
+			TraceID:           span.TraceID,
+			SpanID:            span.SpanID,
+			ParentSpanID:      span.ParentSpanID,
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+			Attributes:        toOTLPAttributes(span.Attributes),
+			Status:            otlpStatus{Code: span.StatusCode, Message: span.StatusMessage},
+		})
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("otlp exporter 返回 %s", resp.Status)
+	}
+	return nil
+}
+
+func (e *otlpExporter) Close() error {
+	return nil
+}
@@ -0,0 +1,142 @@
+package tracing
+
+import (
+	"sync"
+	"time"
+)
+
+//SpanKind span 的类型,与 OTLP 的 SpanKind 取值一致
+type SpanKind int
+
+const (
+	//SpanKindInternal 进程内部的调用
+	SpanKindInternal SpanKind = 1
+	//SpanKindServer 处理远程调用
+	SpanKindServer SpanKind = 2
+	//SpanKindClient 发起远程调用
+	SpanKindClient SpanKind = 3
+)
+
+//StatusCode span 的状态,与 OTLP 的 StatusCode 取值一致
+type StatusCode int
+
+const (
+	//StatusUnset 没有设置状态
+	StatusUnset StatusCode = 0
+	//StatusOK 调用成功
+	StatusOK StatusCode = 1
+	//StatusError 调用失败
+	StatusError StatusCode = 2
+)
+
+//SpanData 结束的 span 的只读数据,用于导出
+type SpanData struct {
+	Name           string            `json:"name"`
+	Kind           SpanKind          `json:"kind"`
+	TraceID        string            `json:"trace_id"`
+	SpanID         string            `json:"span_id"`
+	ParentSpanID   string            `json:"parent_span_id,omitempty"`
+	ServiceName    string            `json:"service_name"`
+	ServiceVersion string            `json:"service_version"`
+	StartTime      time.Time         `json:"start_time"`
+	EndTime        time.Time         `json:"end_time"`
+	Attributes     map[string]string `json:"attributes,omitempty"`
+	StatusCode     StatusCode        `json:"status_code"`
+	StatusMessage  string            `json:"status_message,omitempty"`
+}
+
+//Span 一次调用的记录,可以并发使用,nil 的 Span 上的操作都是空操作
+type Span struct {
+	tracer      *Tracer
+	name        string
+	kind        SpanKind
+	spanContext SpanContext
+	parentID    SpanID
+	start       time.Time
+	mutex       *sync.Mutex
+	attributes  map[string]string
+	status      StatusCode
+	message     string
+	ended       bool
+}
+
+//SpanContext 传递给下游的 SpanContext
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.spanContext
+}
+
+//SetName 修改 span 的名称,用于创建 span 时还不知道名称的场景,如 http 请求匹配到路由之前
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.name = name
+}
+
+//SetAttribute 设置属性
+func (s *Span) SetAttribute(key string, value string) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.attributes[key] = value
+}
+
+//SetError 记录错误,err 为 nil 时设置为成功
+func (s *Span) SetError(err error) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err == nil {
+		s.status = StatusOK
+		s.message = ""
+		return
+	}
+	s.status = StatusError
+	s.message = err.Error()
+}
+
+//End 结束 span,只有第一次调用有效,采样的 span 交给 Tracer 导出
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	attributes := make(map[string]string, len(s.attributes))
+	for key, value := range s.attributes {
+		attributes[key] = value
+	}
+	data := &SpanData{
+		Name:           s.name,
+		Kind:           s.kind,
+		TraceID:        s.spanContext.TraceID.String(),
+		SpanID:         s.spanContext.SpanID.String(),
+		ServiceName:    s.tracer.serviceName,
+		ServiceVersion: s.tracer.serviceVersion,
+		StartTime:      s.start,
+		EndTime:        time.Now(),
+		Attributes:     attributes,
+		StatusCode:     s.status,
+		StatusMessage:  s.message,
+	}
+	s.mutex.Unlock()
+	if s.parentID.IsValid() {
+		data.ParentSpanID = s.parentID.String()
+	}
+	if s.spanContext.Sampled {
+		s.tracer.export(data)
+	}
+}
@@ -0,0 +1,201 @@
+package tracing
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/coffeehc/logger"
+	"github.com/coffeehc/microserviceboot/base"
+)
+
+const (
+	errScopeTracing      = "tracing"
+	defaultBatchSize     = 512
+	defaultQueueSize     = 2048
+	defaultFlushInterval = time.Second * 5
+)
+
+//Config 链路追踪配置
+type Config struct {
+	//Exporter 导出器名称,内置 otlp,stdout 与 file,也可以是 RegisterExporter 注册的名称,为空时不导出,只传递 trace context
+	Exporter string `yaml:"exporter"`
+	//Endpoint otlp 导出的地址,使用 OTLP/HTTP JSON,默认 http://localhost:4318/v1/traces
+	Endpoint string `yaml:"endpoint"`
+	//File file 导出的文件路径,每行一个 span 的 json
+	File string `yaml:"file"`
+	//SampleRatio 新 trace 的采样比例,默认1,有上游时跟随上游的采样结果
+	SampleRatio float64 `yaml:"sample_ratio" validate:"min=0,max=1"`
+	//BatchSize 每次导出的最大 span 数,默认512
+	BatchSize int `yaml:"batch_size" validate:"min=0"`
+	//QueueSize 等待导出的 span 数上限,超过时丢弃,默认2048
+	QueueSize int `yaml:"queue_size" validate:"min=0"`
+	//FlushInterval 导出的间隔,默认5s
+	FlushInterval time.Duration `yaml:"flush_interval" validate:"min=0s"`
+}
+
+//Tracer 创建 span 并批量导出,span 带有服务的名称与版本
+type Tracer struct {
+	serviceName    string
+	serviceVersion string
+	exporter       Exporter
+	sampleRatio    float64
+	batchSize      int
+	flushInterval  time.Duration
+	queue          chan *SpanData
+	closeOnce      *sync.Once
+	done           chan struct{}
+	stopped        chan struct{}
+}
+
+//NewTracer 创建 Tracer,config 为 nil 或者没有指定 Exporter 时不导出 span
+func NewTracer(serviceInfo base.ServiceInfo, config *Config) (*Tracer, base.Error) {
+	if config == nil {
+		config = &Config{}
+	}
+	tracer := &Tracer{
+		serviceName:    serviceInfo.GetServiceName(),
+		serviceVersion: serviceInfo.GetVersion(),
+		sampleRatio:    config.SampleRatio,
+		batchSize:      config.BatchSize,
+		flushInterval:  config.FlushInterval,
+		closeOnce:      new(sync.Once),
+	}
+	if config.Exporter == "" {
+		return tracer, nil
+	}
+	builder, ok := getExporterBuilder(config.Exporter)
+	if !ok {
+		return nil, base.NewError(base.Error_System, errScopeTracing, "不支持的 exporter:"+config.Exporter)
+	}
+	exporter, err := builder(config)
+	if err != nil {
+		return nil, err
+	}
+	if tracer.sampleRatio <= 0 {
+		tracer.sampleRatio = 1
+	}
+	if tracer.batchSize <= 0 {
+		tracer.batchSize = defaultBatchSize
+	}
+	if tracer.flushInterval <= 0 {
+		tracer.flushInterval = defaultFlushInterval
+	}
+	queueSize := config.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	tracer.exporter = exporter
+	tracer.queue = make(chan *SpanData, queueSize)
+	tracer.done = make(chan struct{})
+	tracer.stopped = make(chan struct{})
+	go tracer.loop()
+	return tracer, nil
+}
+
+//StartSpan 创建 span,cxt 中有 span 或者上游的 SpanContext 时作为子 span,返回的 cxt 包含新的 span
+func (t *Tracer) StartSpan(cxt context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	span := &Span{
+		tracer:     t,
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		mutex:      new(sync.Mutex),
+		attributes: make(map[string]string),
+	}
+	if parent, ok := SpanContextFromContext(cxt); ok {
+		span.spanContext = SpanContext{TraceID: parent.TraceID, Sampled: parent.Sampled, TraceState: parent.TraceState}
+		span.parentID = parent.SpanID
+	} else {
+		span.spanContext = SpanContext{TraceID: newTraceID(), Sampled: t.exporter != nil && rand.Float64() < t.sampleRatio}
+	}
+	span.spanContext.SpanID = newSpanID()
+	return ContextWithSpan(cxt, span), span
+}
+
+func (t *Tracer) export(data *SpanData) {
+	if t.exporter == nil {
+		return
+	}
+	select {
+	case t.queue <- data:
+	default:
+		logger.Warn("span 队列已满,丢弃 span %s", data.Name)
+	}
+}
+
+func (t *Tracer) loop() {
+	defer close(t.stopped)
+	ticker := time.NewTicker(t.flushInterval)
+	defer ticker.Stop()
+	batch := make([]*SpanData, 0, t.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.exporter.Export(batch); err != nil {
+			logger.Error("导出 span 失败,%s", err)
+		}
+		batch = make([]*SpanData, 0, t.batchSize)
+	}
+	for {
+		select {
+		case data := <-t.queue:
+			batch = append(batch, data)
+			if len(batch) >= t.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-t.done:
+			for {
+				select {
+				case data := <-t.queue:
+					batch = append(batch, data)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+//Close 导出剩余的 span 并关闭导出器
+func (t *Tracer) Close() {
+	if t.exporter == nil {
+		return
+	}
+	t.closeOnce.Do(func() {
+		close(t.done)
+		<-t.stopped
+		if err := t.exporter.Close(); err != nil {
+			logger.Error("关闭 span 导出器失败,%s", err)
+		}
+	})
+}
+
+var (
+	tracerMutex  = new(sync.RWMutex)
+	globalTracer = &Tracer{serviceName: "unknown", closeOnce: new(sync.Once)}
+)
+
+//SetTracer 设置全局的 Tracer,服务启动时根据配置设置,拦截器与客户端使用全局的 Tracer
+func SetTracer(tracer *Tracer) {
+	tracerMutex.Lock()
+	defer tracerMutex.Unlock()
+	globalTracer = tracer
+}
+
+//GetTracer 获取全局的 Tracer,没有设置时只传递 trace context,不导出 span
+func GetTracer() *Tracer {
+	tracerMutex.RLock()
+	defer tracerMutex.RUnlock()
+	return globalTracer
+}
+
+//StartSpan 使用全局的 Tracer 创建 span
+func StartSpan(cxt context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	return GetTracer().StartSpan(cxt, name, kind)
+}
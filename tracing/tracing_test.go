@@ -0,0 +1,70 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/coffeehc/microserviceboot/base"
+	. "gopkg.in/check.v1"
+)
+
+type TracingSuite struct {
+}
+
+var _ = Suite(&TracingSuite{})
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+func (t *TracingSuite) TestTraceparent(c *C) {
+	traceparent := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	sc, ok := ParseTraceparent(traceparent, "congo=t61rcWkgMzE")
+	c.Assert(ok, Equals, true)
+	c.Assert(sc.Sampled, Equals, true)
+	c.Assert(sc.TraceState, Equals, "congo=t61rcWkgMzE")
+	c.Assert(sc.Traceparent(), Equals, traceparent)
+	for _, invalid := range []string{
+		"",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331",
+		"00-00000000000000000000000000000000-b7ad6b7169203331-01",
+		"ff-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01-extra",
+	} {
+		_, ok = ParseTraceparent(invalid, "")
+		c.Assert(ok, Equals, false, Commentf("%s", invalid))
+	}
+}
+
+func (t *TracingSuite) TestTracerExport(c *C) {
+	buffer := new(bytes.Buffer)
+	RegisterExporter("test", func(config *Config) (Exporter, base.Error) {
+		return NewWriterExporter(buffer), nil
+	})
+	tracer, err := NewTracer(&base.SimpleServiceInfo{ServiceName: "demo", Version: "1.0"}, &Config{Exporter: "test"})
+	c.Assert(err, IsNil)
+	remote, _ := ParseTraceparent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", "")
+	cxt, server := tracer.StartSpan(ContextWithRemoteSpanContext(context.Background(), remote), "server", SpanKindServer)
+	_, client := tracer.StartSpan(cxt, "client", SpanKindClient)
+	client.SetAttribute("peer.service", "other")
+	client.End()
+	server.End()
+	tracer.Close()
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	c.Assert(lines, HasLen, 2)
+	spans := make([]*SpanData, 2)
+	for i, line := range lines {
+		spans[i] = &SpanData{}
+		c.Assert(json.Unmarshal([]byte(line), spans[i]), IsNil)
+		c.Assert(spans[i].TraceID, Equals, remote.TraceID.String())
+		c.Assert(spans[i].ServiceName, Equals, "demo")
+		c.Assert(spans[i].ServiceVersion, Equals, "1.0")
+	}
+	c.Assert(spans[0].ParentSpanID, Equals, spans[1].SpanID)
+	c.Assert(spans[1].ParentSpanID, Equals, remote.SpanID.String())
+	c.Assert(spans[0].Attributes["peer.service"], Equals, "other")
+}